@@ -0,0 +1,234 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+
+	"poppins/config"
+	"poppins/domain"
+	"poppins/repository"
+	"poppins/storage"
+)
+
+// cliActor — кем подписываются действия, выполненные из командной строки.
+func cliActor() string {
+	if u := os.Getenv("USER"); u != "" {
+		return "cli:" + u
+	}
+	return "cli"
+}
+
+// userCommand выполняет «poppins user ban|unban …».
+func userCommand(cfg *config.Config, args []string) int {
+	const usage = `usage: poppins user ban [-shadow] [-duration 72h] -reason text <telegram_id>
+       poppins user unban [-reason text] <telegram_id>`
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, usage)
+		return 2
+	}
+	fs := flag.NewFlagSet("user "+args[0], flag.ContinueOnError)
+	reason := fs.String("reason", "", "reason shown to the user and kept for audit")
+	switch args[0] {
+	case "ban":
+		shadow := fs.Bool("shadow", false, "shadow-ban: the user's ads are visible only to them")
+		duration := fs.Duration("duration", 0, "how long the sanction lasts; 0 means until lifted")
+		if err := fs.Parse(args[1:]); err != nil || fs.NArg() != 1 {
+			fmt.Fprintln(os.Stderr, usage)
+			return 2
+		}
+		if *reason == "" || *duration < 0 {
+			fmt.Fprintln(os.Stderr, "ban: -reason is required and -duration must not be negative")
+			return 2
+		}
+		s := &domain.Sanction{TelegramID: fs.Arg(0), Kind: domain.SanctionBan, Reason: *reason, CreatedBy: cliActor()}
+		if *shadow {
+			s.Kind = domain.SanctionShadowBan
+		}
+		if *duration > 0 {
+			until := time.Now().Add(*duration)
+			s.ExpiresAt = &until
+		}
+		return withDB(cfg, func(repos *cliRepos) error {
+			if err := repos.Sanctions.Create(s); err != nil {
+				return err
+			}
+			log.Printf("sanction %d (%s) on %s by %s: %s", s.ID, s.Kind, s.TelegramID, s.CreatedBy, s.Reason)
+			return nil
+		})
+	case "unban":
+		if err := fs.Parse(args[1:]); err != nil || fs.NArg() != 1 {
+			fmt.Fprintln(os.Stderr, usage)
+			return 2
+		}
+		telegramID := fs.Arg(0)
+		return withDB(cfg, func(repos *cliRepos) error {
+			list, err := repos.Sanctions.ListByTelegram(telegramID)
+			if err != nil {
+				return err
+			}
+			lifted := 0
+			for _, s := range list {
+				if !s.Active {
+					continue
+				}
+				s, err := repos.Sanctions.Lift(telegramID, s.ID, cliActor(), *reason)
+				if err != nil && !errors.Is(err, repository.ErrNotFound) {
+					return err
+				}
+				if err == nil {
+					log.Printf("sanction %d (%s) on %s lifted by %s", s.ID, s.Kind, s.TelegramID, s.LiftedBy)
+					lifted++
+				}
+			}
+			if lifted == 0 {
+				fmt.Println("no active sanctions")
+			}
+			return nil
+		})
+	}
+	fmt.Fprintln(os.Stderr, usage)
+	return 2
+}
+
+// adCommand выполняет «poppins ad archive <id>».
+func adCommand(cfg *config.Config, args []string) int {
+	const usage = `usage: poppins ad archive <id>`
+	if len(args) != 2 || args[0] != "archive" {
+		fmt.Fprintln(os.Stderr, usage)
+		return 2
+	}
+	id, err := strconv.ParseInt(args[1], 10, 64)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "invalid ad id: "+args[1])
+		return 2
+	}
+	return withDB(cfg, func(repos *cliRepos) error {
+		ad, err := repos.Ads.Archive(id, domain.Actor{Admin: true})
+		if err != nil {
+			return err
+		}
+		log.Printf("ad %d of %s archived by %s", ad.ID, ad.TelegramID, cliActor())
+		return nil
+	})
+}
+
+// storageCommand выполняет «poppins storage gc»: ставит на удаление фото, на
+// которые не ссылается ни одно объявление, и разбирает очередь удаления.
+func storageCommand(cfg *config.Config, args []string) int {
+	const usage = `usage: poppins storage gc [-min-age 24h] [-dry-run]`
+	if len(args) == 0 || args[0] != "gc" {
+		fmt.Fprintln(os.Stderr, usage)
+		return 2
+	}
+	fs := flag.NewFlagSet("storage gc", flag.ContinueOnError)
+	// Свежее фото может ещё не попасть в объявление: загрузка идёт раньше
+	// записи в базу
+	minAge := fs.Duration("min-age", 24*time.Hour, "skip photos uploaded more recently than this")
+	dryRun := fs.Bool("dry-run", false, "only list orphaned photos")
+	if err := fs.Parse(args[1:]); err != nil || fs.NArg() != 0 {
+		fmt.Fprintln(os.Stderr, usage)
+		return 2
+	}
+	client, err := newMinIO(cfg)
+	if err != nil {
+		log.Print(err)
+		return 1
+	}
+	return withDB(cfg, func(repos *cliRepos) error {
+		orphans, err := orphanPhotos(client, cfg.MinIOBucket, repos.Storage, time.Now().Add(-*minAge))
+		if err != nil {
+			return err
+		}
+		for _, obj := range orphans {
+			fmt.Println(obj)
+		}
+		if *dryRun {
+			fmt.Printf("%d orphaned photos\n", len(orphans))
+			return nil
+		}
+		if err := repos.Storage.Enqueue(domain.StoragePhoto, orphans); err != nil {
+			return err
+		}
+		n, err := storage.NewCleaner(repos.Storage, client, storageBuckets(cfg)).Drain()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%d orphaned photos queued, %d queued objects processed\n", len(orphans), n)
+		return nil
+	})
+}
+
+// orphanPhotos перебирает фото объявлений в бакете, загруженные до before,
+// и возвращает те, на которые нет ссылок.
+func orphanPhotos(client *minio.Client, bucket string, repo *repository.StorageRepo, before time.Time) ([]string, error) {
+	const batchSize = 1000
+	var orphans, batch []string
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		found, err := repo.OrphanPhotos(batch)
+		orphans = append(orphans, found...)
+		batch = batch[:0]
+		return err
+	}
+	for obj := range client.ListObjects(context.Background(), bucket, minio.ListObjectsOptions{Prefix: "ads/", Recursive: true}) {
+		if obj.Err != nil {
+			return nil, fmt.Errorf("list %s: %w", bucket, obj.Err)
+		}
+		if obj.LastModified.After(before) || strings.HasSuffix(obj.Key, "/") {
+			continue
+		}
+		if batch = append(batch, obj.Key); len(batch) == batchSize {
+			if err := flush(); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+	return orphans, nil
+}
+
+// cliRepos — репозитории, нужные административным командам.
+type cliRepos struct {
+	Users     *repository.UserRepo
+	Ads       *repository.AdRepo
+	Sanctions *repository.SanctionRepo
+	Storage   *repository.StorageRepo
+}
+
+// withDB подключается к базе, выполняет fn и переводит её ошибку в код выхода.
+func withDB(cfg *config.Config, fn func(repos *cliRepos) error) int {
+	db, err := openDB(cfg)
+	if err != nil {
+		log.Print(err)
+		return 1
+	}
+	defer db.Close()
+	err = fn(&cliRepos{
+		Users:     repository.NewUserRepo(db, cfg.ErasureSecret),
+		Ads:       repository.NewAdRepo(db, cfg.SearchOrganicPerPromoted),
+		Sanctions: repository.NewSanctionRepo(db),
+		Storage:   repository.NewStorageRepo(db),
+	})
+	if errors.Is(err, repository.ErrNotFound) {
+		log.Print("not found")
+		return 1
+	}
+	if err != nil {
+		log.Print(err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,101 @@
+// Package auth реализует аутентификацию сервисов по API-ключам со scope'ами.
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+)
+
+// Scope'ы API-ключей.
+const (
+	// ScopeRead — только чтение каталога и профилей (аналитические скрипты).
+	ScopeRead = "read"
+	// ScopeBot — бэкенд бота: действует от имени пользователей Telegram.
+	ScopeBot = "bot"
+	// ScopeModerator — модераторы: работа с очередями модерации и жалобами.
+	ScopeModerator = "moderator"
+	// ScopeAdmin — полный доступ, включая управление ключами.
+	ScopeAdmin = "admin"
+)
+
+// KnownScopes — все допустимые scope'ы.
+var KnownScopes = []string{ScopeRead, ScopeBot, ScopeModerator, ScopeAdmin}
+
+// ValidScope сообщает, известен ли scope.
+func ValidScope(scope string) bool {
+	for _, s := range KnownScopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// keyPrefix отличает наши ключи от прочих секретов в логах и конфигах.
+const keyPrefix = "pk_"
+
+// GenerateKey создаёт новый ключ. Открытый текст отдаётся клиенту один раз,
+// в БД хранится только hash.
+func GenerateKey() (plain, hash string, err error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", err
+	}
+	plain = keyPrefix + base64.RawURLEncoding.EncodeToString(buf)
+	return plain, HashKey(plain), nil
+}
+
+// HashKey возвращает SHA-256 ключа в hex. Ключи случайные и длинные,
+// поэтому медленный KDF не нужен.
+func HashKey(plain string) string {
+	sum := sha256.Sum256([]byte(plain))
+	return hex.EncodeToString(sum[:])
+}
+
+// DisplayPrefix — первые символы ключа, по которым его можно узнать в списке.
+func DisplayPrefix(plain string) string {
+	if len(plain) > 10 {
+		return plain[:10]
+	}
+	return plain
+}
+
+// Principal — аутентифицированный вызывающий сервис.
+type Principal struct {
+	KeyID  int64
+	Name   string
+	Scopes []string
+}
+
+// Has сообщает, разрешён ли principal'у scope. admin разрешает всё,
+// bot и moderator включают read.
+func (p *Principal) Has(scope string) bool {
+	if p == nil {
+		return false
+	}
+	for _, s := range p.Scopes {
+		switch {
+		case s == scope, s == ScopeAdmin:
+			return true
+		case scope == ScopeRead && (s == ScopeBot || s == ScopeModerator):
+			return true
+		}
+	}
+	return false
+}
+
+type ctxKey struct{}
+
+// WithPrincipal кладёт principal в контекст запроса.
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, ctxKey{}, p)
+}
+
+// FromContext достаёт principal из контекста; nil, если запрос не аутентифицирован.
+func FromContext(ctx context.Context) *Principal {
+	p, _ := ctx.Value(ctxKey{}).(*Principal)
+	return p
+}
@@ -0,0 +1,99 @@
+package auth
+
+import (
+	"crypto/subtle"
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"poppins/repository"
+)
+
+// Authenticator проверяет API-ключи запросов.
+type Authenticator struct {
+	Keys *repository.APIKeyRepo
+
+	// BootstrapKeyHash — hash ключа администратора из окружения, чтобы выпустить
+	// первые ключи через API. Пустая строка отключает bootstrap-ключ.
+	BootstrapKeyHash string
+
+	// Disabled пропускает запросы без ключа с правами бота и модератора
+	// (только для локальной разработки).
+	Disabled bool
+}
+
+func NewAuthenticator(keys *repository.APIKeyRepo, bootstrapKey string, disabled bool) *Authenticator {
+	a := &Authenticator{Keys: keys, Disabled: disabled}
+	if bootstrapKey != "" {
+		a.BootstrapKeyHash = HashKey(bootstrapKey)
+	}
+	return a
+}
+
+// keyFromRequest достаёт ключ из "Authorization: Bearer ..." или X-API-Key.
+func keyFromRequest(r *http.Request) string {
+	if h := r.Header.Get("Authorization"); strings.HasPrefix(h, "Bearer ") {
+		return strings.TrimSpace(strings.TrimPrefix(h, "Bearer "))
+	}
+	return r.Header.Get("X-API-Key")
+}
+
+// authenticate возвращает principal для ключа или nil, если ключ не подошёл.
+func (a *Authenticator) authenticate(plain string) (*Principal, error) {
+	hash := HashKey(plain)
+	if a.BootstrapKeyHash != "" &&
+		subtle.ConstantTimeCompare([]byte(hash), []byte(a.BootstrapKeyHash)) == 1 {
+		return &Principal{Name: "bootstrap", Scopes: []string{ScopeAdmin}}, nil
+	}
+	key, err := a.Keys.GetActiveByHash(hash)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &Principal{KeyID: key.ID, Name: key.Name, Scopes: key.Scopes}, nil
+}
+
+// Require пропускает запрос, только если его ключ даёт scope.
+func (a *Authenticator) Require(scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			plain := keyFromRequest(r)
+			if plain == "" && a.Disabled {
+				p := &Principal{Name: "anonymous", Scopes: []string{ScopeBot, ScopeModerator}}
+				next.ServeHTTP(w, r.WithContext(WithPrincipal(r.Context(), p)))
+				return
+			}
+			if plain == "" {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="poppins"`)
+				http.Error(w, "missing api key", http.StatusUnauthorized)
+				return
+			}
+
+			p, err := a.authenticate(plain)
+			if err != nil {
+				log.Printf("authenticate api key: %v", err)
+				http.Error(w, "internal server error", http.StatusInternalServerError)
+				return
+			}
+			if p == nil {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="poppins", error="invalid_token"`)
+				http.Error(w, "invalid api key", http.StatusUnauthorized)
+				return
+			}
+			if !p.Has(scope) {
+				http.Error(w, "api key lacks scope "+scope, http.StatusForbidden)
+				return
+			}
+			if p.KeyID != 0 {
+				if err := a.Keys.TouchLastUsed(p.KeyID); err != nil {
+					log.Printf("touch api key %d: %v", p.KeyID, err)
+				}
+			}
+			next.ServeHTTP(w, r.WithContext(WithPrincipal(r.Context(), p)))
+		})
+	}
+}
@@ -0,0 +1,158 @@
+// Package catalog выгружает объявления в форматах площадок-агрегаторов:
+// Яндекс YML и XML-фид в формате Авито.
+package catalog
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// YMLCategory — категория дерева YML.
+type YMLCategory struct {
+	ID       int    `json:"id"`
+	ParentID int    `json:"parent_id,omitempty"`
+	Name     string `json:"name"`
+}
+
+// YMLTaxonomy — дерево категорий YML и сопоставление с нашими категориями.
+type YMLTaxonomy struct {
+	Categories []YMLCategory `json:"categories"`
+	// Map — наша категория → ID категории YML.
+	Map map[string]int `json:"map"`
+	// Default — категория объявлений без сопоставления.
+	Default int `json:"default"`
+}
+
+// AvitoCategory — категория и вид товара Авито.
+type AvitoCategory struct {
+	Category  string `json:"category"`
+	GoodsType string `json:"goods_type,omitempty"`
+}
+
+// AvitoTaxonomy — сопоставление наших категорий с категориями Авито.
+type AvitoTaxonomy struct {
+	Map map[string]AvitoCategory `json:"map"`
+	// Default — категория объявлений без сопоставления; если не задана,
+	// такие объявления в фид не попадают: Авито отклоняет фид с неверными
+	// категориями.
+	Default *AvitoCategory `json:"default,omitempty"`
+}
+
+// Taxonomy — сопоставление категорий для всех форматов. Наши категории
+// сравниваются без учёта регистра.
+type Taxonomy struct {
+	YML   YMLTaxonomy   `json:"yml"`
+	Avito AvitoTaxonomy `json:"avito"`
+}
+
+// DefaultTaxonomy используется, если файл сопоставления не задан.
+func DefaultTaxonomy() *Taxonomy {
+	return &Taxonomy{
+		YML: YMLTaxonomy{
+			Categories: []YMLCategory{
+				{ID: 1, Name: "Электроника"},
+				{ID: 2, ParentID: 1, Name: "Телефоны"},
+				{ID: 3, ParentID: 1, Name: "Компьютеры"},
+				{ID: 4, Name: "Одежда и обувь"},
+				{ID: 5, Name: "Дом и сад"},
+				{ID: 6, ParentID: 5, Name: "Мебель"},
+				{ID: 7, Name: "Детские товары"},
+				{ID: 8, Name: "Спорт и отдых"},
+				{ID: 9, Name: "Авто"},
+				{ID: 99, Name: "Разное"},
+			},
+			Map: map[string]int{
+				"электроника": 1, "телефоны": 2, "компьютеры": 3, "одежда": 4, "обувь": 4,
+				"дом": 5, "мебель": 6, "детские товары": 7, "спорт": 8, "авто": 9, "автозапчасти": 9,
+			},
+			Default: 99,
+		},
+		Avito: AvitoTaxonomy{
+			Map: map[string]AvitoCategory{
+				"телефоны":       {Category: "Телефоны", GoodsType: "Мобильные телефоны"},
+				"компьютеры":     {Category: "Настольные компьютеры"},
+				"электроника":    {Category: "Аудио и видео"},
+				"одежда":         {Category: "Одежда, обувь, аксессуары"},
+				"обувь":          {Category: "Одежда, обувь, аксессуары"},
+				"мебель":         {Category: "Мебель и интерьер"},
+				"дом":            {Category: "Мебель и интерьер"},
+				"детские товары": {Category: "Товары для детей и игрушки"},
+				"спорт":          {Category: "Спорт и отдых"},
+				"автозапчасти":   {Category: "Запчасти и аксессуары"},
+			},
+		},
+	}
+}
+
+// LoadTaxonomy читает сопоставление из JSON-файла; пустой путь —
+// сопоставление по умолчанию.
+func LoadTaxonomy(path string) (*Taxonomy, error) {
+	if path == "" {
+		return DefaultTaxonomy().normalize()
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read taxonomy: %w", err)
+	}
+	t := &Taxonomy{}
+	if err := json.Unmarshal(data, t); err != nil {
+		return nil, fmt.Errorf("parse taxonomy %s: %w", path, err)
+	}
+	return t.normalize()
+}
+
+// normalize приводит ключи к нижнему регистру и проверяет, что все
+// сопоставления YML ссылаются на существующие категории.
+func (t *Taxonomy) normalize() (*Taxonomy, error) {
+	ids := map[int]bool{}
+	for _, c := range t.YML.Categories {
+		ids[c.ID] = true
+	}
+	for _, c := range t.YML.Categories {
+		if c.ParentID != 0 && !ids[c.ParentID] {
+			return nil, fmt.Errorf("yml category %d: unknown parent %d", c.ID, c.ParentID)
+		}
+	}
+	if !ids[t.YML.Default] {
+		return nil, fmt.Errorf("yml default category %d is not defined", t.YML.Default)
+	}
+	ymlMap := make(map[string]int, len(t.YML.Map))
+	for k, id := range t.YML.Map {
+		if !ids[id] {
+			return nil, fmt.Errorf("yml category for %q: unknown id %d", k, id)
+		}
+		ymlMap[strings.ToLower(k)] = id
+	}
+	t.YML.Map = ymlMap
+
+	avitoMap := make(map[string]AvitoCategory, len(t.Avito.Map))
+	for k, c := range t.Avito.Map {
+		if c.Category == "" {
+			return nil, fmt.Errorf("avito category for %q is empty", k)
+		}
+		avitoMap[strings.ToLower(k)] = c
+	}
+	t.Avito.Map = avitoMap
+	return t, nil
+}
+
+// YMLCategoryID возвращает категорию YML для нашей категории.
+func (t *Taxonomy) YMLCategoryID(category string) int {
+	if id, ok := t.YML.Map[strings.ToLower(category)]; ok {
+		return id
+	}
+	return t.YML.Default
+}
+
+// AvitoCategory возвращает категорию Авито; false — объявление не выгружается.
+func (t *Taxonomy) AvitoCategory(category string) (AvitoCategory, bool) {
+	if c, ok := t.Avito.Map[strings.ToLower(category)]; ok {
+		return c, true
+	}
+	if t.Avito.Default != nil {
+		return *t.Avito.Default, true
+	}
+	return AvitoCategory{}, false
+}
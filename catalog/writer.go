@@ -0,0 +1,204 @@
+package catalog
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"poppins/domain"
+	"strconv"
+	"time"
+)
+
+// Shop — сведения о площадке для заголовка фида.
+type Shop struct {
+	Name     string
+	Company  string
+	URL      string
+	Currency string
+	// AdURL — шаблон ссылки на страницу объявления с %d на месте ID.
+	AdURL string
+	// PhotoBase — адрес, к которому дописывается путь фото объявления.
+	PhotoBase string
+}
+
+// Source передаёт fn объявления фида по одному.
+type Source func(fn func(*domain.Advertisement) error) error
+
+// flushEvery — через сколько записей сбрасывать буфер кодировщика клиенту.
+const flushEvery = 100
+
+type ymlOffer struct {
+	XMLName     xml.Name `xml:"offer"`
+	ID          int64    `xml:"id,attr"`
+	Available   bool     `xml:"available,attr"`
+	Name        string   `xml:"name"`
+	URL         string   `xml:"url"`
+	Price       int64    `xml:"price"`
+	CurrencyID  string   `xml:"currencyId"`
+	CategoryID  int      `xml:"categoryId"`
+	Picture     string   `xml:"picture,omitempty"`
+	Description string   `xml:"description,omitempty"`
+}
+
+type ymlCategory struct {
+	XMLName  xml.Name `xml:"category"`
+	ID       int      `xml:"id,attr"`
+	ParentID int      `xml:"parentId,attr,omitempty"`
+	Name     string   `xml:",chardata"`
+}
+
+// WriteYML пишет фид в формате Яндекс YML, читая объявления из src по одному.
+func WriteYML(w io.Writer, shop Shop, t *Taxonomy, src Source) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	catalog := start("yml_catalog", xml.Attr{Name: xml.Name{Local: "date"}, Value: time.Now().Format(time.RFC3339)})
+	s := start("shop")
+	tokens := []xml.Token{catalog, s}
+	for _, f := range [][2]string{{"name", shop.Name}, {"company", shop.Company}, {"url", shop.URL}} {
+		tokens = append(tokens, start(f[0]), xml.CharData(f[1]), xml.EndElement{Name: xml.Name{Local: f[0]}})
+	}
+	for _, tok := range tokens {
+		if err := enc.EncodeToken(tok); err != nil {
+			return err
+		}
+	}
+	currencies := struct {
+		XMLName  xml.Name `xml:"currencies"`
+		Currency struct {
+			ID   string `xml:"id,attr"`
+			Rate string `xml:"rate,attr"`
+		} `xml:"currency"`
+	}{}
+	currencies.Currency.ID, currencies.Currency.Rate = shop.Currency, "1"
+	if err := enc.Encode(currencies); err != nil {
+		return err
+	}
+	if err := enc.EncodeToken(start("categories")); err != nil {
+		return err
+	}
+	for _, c := range t.YML.Categories {
+		if err := enc.Encode(ymlCategory{ID: c.ID, ParentID: c.ParentID, Name: c.Name}); err != nil {
+			return err
+		}
+	}
+	if err := enc.EncodeToken(end("categories")); err != nil {
+		return err
+	}
+
+	if err := enc.EncodeToken(start("offers")); err != nil {
+		return err
+	}
+	n := 0
+	err := src(func(ad *domain.Advertisement) error {
+		o := ymlOffer{
+			ID:          ad.ID,
+			Available:   true,
+			Name:        ad.Title,
+			URL:         fmt.Sprintf(shop.AdURL, ad.ID),
+			Price:       ad.Price,
+			CurrencyID:  shop.Currency,
+			CategoryID:  t.YMLCategoryID(ad.Category),
+			Description: ad.Description,
+		}
+		if ad.PhotosUrls != "" {
+			o.Picture = shop.PhotoBase + ad.PhotosUrls
+		}
+		return encode(enc, o, &n)
+	})
+	if err != nil {
+		return err
+	}
+	for _, name := range []string{"offers", "shop", "yml_catalog"} {
+		if err := enc.EncodeToken(end(name)); err != nil {
+			return err
+		}
+	}
+	return enc.Flush()
+}
+
+type avitoImage struct {
+	URL string `xml:"url,attr"`
+}
+
+type avitoAd struct {
+	XMLName     xml.Name     `xml:"Ad"`
+	ID          string       `xml:"Id"`
+	DateBegin   string       `xml:"DateBegin"`
+	Category    string       `xml:"Category"`
+	GoodsType   string       `xml:"GoodsType,omitempty"`
+	AdType      string       `xml:"AdType"`
+	Condition   string       `xml:"Condition"`
+	Title       string       `xml:"Title"`
+	Description string       `xml:"Description"`
+	Price       int64        `xml:"Price"`
+	Address     string       `xml:"Address,omitempty"`
+	Images      []avitoImage `xml:"Images>Image,omitempty"`
+}
+
+// WriteAvito пишет фид в формате автозагрузки Авито (formatVersion 3).
+// Объявления категорий без сопоставления пропускаются.
+func WriteAvito(w io.Writer, shop Shop, t *Taxonomy, src Source) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	root := start("Ads",
+		xml.Attr{Name: xml.Name{Local: "formatVersion"}, Value: "3"},
+		xml.Attr{Name: xml.Name{Local: "target"}, Value: "Avito.ru"})
+	if err := enc.EncodeToken(root); err != nil {
+		return err
+	}
+	n := 0
+	err := src(func(ad *domain.Advertisement) error {
+		c, ok := t.AvitoCategory(ad.Category)
+		if !ok {
+			return nil
+		}
+		a := avitoAd{
+			ID:          strconv.FormatInt(ad.ID, 10),
+			DateBegin:   ad.CreatedAt.Format("2006-01-02"),
+			Category:    c.Category,
+			GoodsType:   c.GoodsType,
+			AdType:      "Продаю своё",
+			Condition:   "Б/у",
+			Title:       ad.Title,
+			Description: ad.Description,
+			Price:       ad.Price,
+			Address:     ad.Address,
+		}
+		if ad.PhotosUrls != "" {
+			a.Images = []avitoImage{{URL: shop.PhotoBase + ad.PhotosUrls}}
+		}
+		return encode(enc, a, &n)
+	})
+	if err != nil {
+		return err
+	}
+	if err := enc.EncodeToken(end("Ads")); err != nil {
+		return err
+	}
+	return enc.Flush()
+}
+
+// encode пишет запись и время от времени отдаёт накопленное клиенту.
+func encode(enc *xml.Encoder, v interface{}, n *int) error {
+	if err := enc.Encode(v); err != nil {
+		return err
+	}
+	if *n++; *n%flushEvery == 0 {
+		return enc.Flush()
+	}
+	return nil
+}
+
+func start(name string, attrs ...xml.Attr) xml.StartElement {
+	return xml.StartElement{Name: xml.Name{Local: name}, Attr: attrs}
+}
+
+func end(name string) xml.EndElement {
+	return xml.EndElement{Name: xml.Name{Local: name}}
+}
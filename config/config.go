@@ -3,6 +3,9 @@ package config
 import (
 	"log"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -17,6 +20,119 @@ type Config struct {
 	MinIOSecretKey string
 	MinIOUseSSL    bool
 	MinIOBucket    string
+
+	// AdminTelegramIDs — пользователи, которым разрешено изменять чужие объявления.
+	AdminTelegramIDs []string
+
+	// BootstrapAPIKey — ключ со scope admin, принимаемый без записи в БД,
+	// чтобы выпустить первые ключи.
+	BootstrapAPIKey string
+	// AuthDisabled разрешает запросы без ключа (только для локальной разработки).
+	AuthDisabled bool
+
+	// TelegramBotToken — токен бота для уведомлений пользователей.
+	// Без него уведомления только пишутся в лог.
+	TelegramBotToken string
+	// OutboxMaxAttempts — после скольких неудачных попыток уведомление
+	// считается недоставленным.
+	OutboxMaxAttempts int
+	// OutboxPollInterval — как часто проверяется очередь уведомлений.
+	OutboxPollInterval time.Duration
+	// OutboxRetention — сколько хранятся доставленные уведомления.
+	OutboxRetention time.Duration
+
+	// PremoderationCategories — категории, объявления в которых публикуются
+	// только после одобрения модератором; "*" — все категории.
+	PremoderationCategories []string
+	// ModerationClaimTTL — сколько задача модерации закреплена за модератором.
+	ModerationClaimTTL time.Duration
+	// ContentRulesFile — JSON с правилами проверки текста объявлений;
+	// пустой путь — встроенные правила.
+	ContentRulesFile string
+
+	// DuplicatePolicy — что делать с повторной публикацией: off, block, merge или flag.
+	DuplicatePolicy string
+	// DuplicateWindow — за какой период искать оригинал.
+	DuplicateWindow time.Duration
+
+	// ReportsHideThreshold — после скольких жалоб от разных пользователей
+	// объявление скрывается до решения модератора; 0 — не скрывать.
+	ReportsHideThreshold int
+
+	// Лимиты частоты запросов вида "10/1h" (10 запросов в час с равномерным
+	// восстановлением) по пользователю и по IP; "off" — без ограничения.
+	RateLimitCreate    string
+	RateLimitCreateIP  string
+	RateLimitEdit      string
+	RateLimitEditIP    string
+	RateLimitSearch    string
+	RateLimitSearchIP  string
+	RateLimitContact   string
+	RateLimitContactIP string
+	// RateLimitTrustedIPs не ограничиваются по IP — например, сервер бота.
+	RateLimitTrustedIPs []string
+	// RateLimitTrustForwarded берёт IP клиента из X-Forwarded-For.
+	RateLimitTrustForwarded bool
+
+	// IdempotencyTTL — сколько хранится ответ на запрос с Idempotency-Key.
+	IdempotencyTTL time.Duration
+
+	// SearchOrganicPerPromoted — сколько обычных объявлений в поиске идёт
+	// между продвигаемыми; 0 — продвигаемые не поднимаются.
+	SearchOrganicPerPromoted int
+
+	// PaymentsProvider — платёжный провайдер: telegram, fake или пусто,
+	// если оплата отключена.
+	PaymentsProvider string
+	// TelegramPaymentsProviderToken — токен провайдера из BotFather.
+	TelegramPaymentsProviderToken string
+	// PaymentsWebhookSecret — секрет, которым провайдер подписывает уведомления;
+	// обязателен, если задан PAYMENTS_PROVIDER.
+	PaymentsWebhookSecret string
+	// PaymentsCurrency — валюта счетов.
+	PaymentsCurrency string
+
+	// ErasureSecret — ключ HMAC, которым хешируется telegram_id удалённых
+	// аккаунтов в журнале удалений и истории санкций; обязателен.
+	ErasureSecret string
+
+	// WebhookMaxAttempts — после скольких неудач доставка события подписчику
+	// прекращается до ручного повтора.
+	WebhookMaxAttempts int
+	// WebhookTimeout — таймаут запроса к подписчику.
+	WebhookTimeout time.Duration
+	// EventsRetention — сколько хранятся события и журнал их доставки.
+	EventsRetention time.Duration
+	// StreamReplayLimit — сколько пропущенных событий клиент потока может
+	// догнать по Last-Event-ID.
+	StreamReplayLimit int
+
+	// PublicBaseURL — внешний адрес API для абсолютных ссылок в Atom-лентах,
+	// например https://api.example.com; пустой — адрес берётся из запроса.
+	PublicBaseURL string
+
+	// ExportBucket — закрытый бакет для архивов выгрузок персональных данных.
+	ExportBucket string
+	// ExportTTL — сколько хранится архив выгрузки и действует ссылка на него.
+	ExportTTL time.Duration
+
+	// ImportBucket — закрытый бакет для файлов импорта объявлений.
+	ImportBucket string
+	// ImportMaxRows — сколько объявлений можно загрузить одним файлом.
+	ImportMaxRows int
+
+	// CatalogShopName и CatalogCompany — название площадки и компании в
+	// заголовке YML-фида.
+	CatalogShopName string
+	CatalogCompany  string
+	// CatalogCurrency — валюта цен в фидах.
+	CatalogCurrency string
+	// CatalogAdURL — шаблон ссылки на страницу объявления с %d на месте ID;
+	// пустой — ссылка на объявление в API.
+	CatalogAdURL string
+	// CatalogTaxonomyFile — JSON с сопоставлением категорий для YML и Авито;
+	// пустой путь — встроенное сопоставление.
+	CatalogTaxonomyFile string
 }
 
 func LoadConfig() *Config {
@@ -32,5 +148,111 @@ func LoadConfig() *Config {
 		MinIOSecretKey: os.Getenv("MINIO_SECRET_ACCESS_KEY"),
 		MinIOUseSSL:    os.Getenv("MINIO_USE_SSL") == "true",
 		MinIOBucket:    os.Getenv("MINIO_BUCKET"),
+
+		AdminTelegramIDs: splitList(os.Getenv("ADMIN_TELEGRAM_IDS")),
+		BootstrapAPIKey:  os.Getenv("ADMIN_API_KEY"),
+		AuthDisabled:     os.Getenv("AUTH_DISABLED") == "true",
+
+		TelegramBotToken:   os.Getenv("TELEGRAM_BOT_TOKEN"),
+		OutboxMaxAttempts:  intEnv("OUTBOX_MAX_ATTEMPTS", 10),
+		OutboxPollInterval: durationEnv("OUTBOX_POLL_INTERVAL", 2*time.Second),
+		OutboxRetention:    durationEnv("OUTBOX_RETENTION", 7*24*time.Hour),
+
+		PremoderationCategories: splitList(os.Getenv("PREMODERATION_CATEGORIES")),
+		ModerationClaimTTL:      durationEnv("MODERATION_CLAIM_TTL", 30*time.Minute),
+		ContentRulesFile:        os.Getenv("CONTENT_RULES_FILE"),
+
+		DuplicatePolicy: stringEnv("DUPLICATE_POLICY", "flag"),
+		DuplicateWindow: durationEnv("DUPLICATE_WINDOW", 30*24*time.Hour),
+
+		ReportsHideThreshold: intEnv("REPORTS_HIDE_THRESHOLD", 3),
+
+		RateLimitCreate:         stringEnv("RATE_LIMIT_CREATE", "10/1h"),
+		RateLimitCreateIP:       stringEnv("RATE_LIMIT_CREATE_IP", "30/1h"),
+		RateLimitEdit:           stringEnv("RATE_LIMIT_EDIT", "60/1h"),
+		RateLimitEditIP:         stringEnv("RATE_LIMIT_EDIT_IP", "200/1h"),
+		RateLimitSearch:         stringEnv("RATE_LIMIT_SEARCH", "60/1m"),
+		RateLimitSearchIP:       stringEnv("RATE_LIMIT_SEARCH_IP", "120/1m"),
+		RateLimitContact:        stringEnv("RATE_LIMIT_CONTACT", "20/1h"),
+		RateLimitContactIP:      stringEnv("RATE_LIMIT_CONTACT_IP", "60/1h"),
+		RateLimitTrustedIPs:     splitList(os.Getenv("RATE_LIMIT_TRUSTED_IPS")),
+		RateLimitTrustForwarded: os.Getenv("RATE_LIMIT_TRUST_FORWARDED") == "true",
+
+		IdempotencyTTL: durationEnv("IDEMPOTENCY_TTL", 24*time.Hour),
+
+		SearchOrganicPerPromoted: intEnv("SEARCH_ORGANIC_PER_PROMOTED", 4),
+
+		PaymentsProvider:              os.Getenv("PAYMENTS_PROVIDER"),
+		TelegramPaymentsProviderToken: os.Getenv("TELEGRAM_PAYMENTS_PROVIDER_TOKEN"),
+		PaymentsWebhookSecret:         os.Getenv("PAYMENTS_WEBHOOK_SECRET"),
+		PaymentsCurrency:              stringEnv("PAYMENTS_CURRENCY", "RUB"),
+
+		ErasureSecret: os.Getenv("ERASURE_SECRET"),
+
+		WebhookMaxAttempts: intEnv("WEBHOOK_MAX_ATTEMPTS", 12),
+		WebhookTimeout:     durationEnv("WEBHOOK_TIMEOUT", 10*time.Second),
+		EventsRetention:    durationEnv("EVENTS_RETENTION", 30*24*time.Hour),
+		StreamReplayLimit:  intEnv("STREAM_REPLAY_LIMIT", 1000),
+
+		PublicBaseURL: os.Getenv("PUBLIC_BASE_URL"),
+
+		ExportBucket: stringEnv("EXPORT_BUCKET", "exports"),
+		ExportTTL:    durationEnv("EXPORT_TTL", 72*time.Hour),
+
+		ImportBucket:  stringEnv("IMPORT_BUCKET", "imports"),
+		ImportMaxRows: intEnv("IMPORT_MAX_ROWS", 500),
+
+		CatalogShopName:     stringEnv("CATALOG_SHOP_NAME", "Poppins"),
+		CatalogCompany:      stringEnv("CATALOG_COMPANY", "Poppins"),
+		CatalogCurrency:     stringEnv("CATALOG_CURRENCY", "RUB"),
+		CatalogAdURL:        os.Getenv("CATALOG_AD_URL"),
+		CatalogTaxonomyFile: os.Getenv("CATALOG_TAXONOMY_FILE"),
+	}
+}
+
+// stringEnv читает строку; при пустом значении — def.
+func stringEnv(name, def string) string {
+	if v := os.Getenv(name); v != "" {
+		return v
+	}
+	return def
+}
+
+// intEnv читает целое число; при пустом или неверном значении — def.
+func intEnv(name string, def int) int {
+	v := os.Getenv(name)
+	if v == "" {
+		return def
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		log.Printf("invalid %s=%q, using %d", name, v, def)
+		return def
+	}
+	return n
+}
+
+// durationEnv читает длительность вида "30m"; при пустом или неверном значении — def.
+func durationEnv(name string, def time.Duration) time.Duration {
+	v := os.Getenv(name)
+	if v == "" {
+		return def
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		log.Printf("invalid %s=%q, using %s", name, v, def)
+		return def
+	}
+	return d
+}
+
+// splitList разбирает список значений через запятую, отбрасывая пустые.
+func splitList(v string) []string {
+	var out []string
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
 	}
+	return out
 }
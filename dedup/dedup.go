@@ -0,0 +1,166 @@
+// Package dedup находит повторно опубликованные объявления по перцептивному
+// хешу фото и отпечатку текста.
+package dedup
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Пороги расстояния Хэмминга, при которых объявления считаются дубликатами.
+const (
+	// PhotoThreshold — dHash переживает пересжатие, ресайз и мелкие правки.
+	PhotoThreshold = 6
+	// TextThreshold — simhash совпадает почти полностью при перестановке слов
+	// и правке нескольких символов.
+	TextThreshold = 3
+)
+
+// Режимы обработки найденного дубликата.
+const (
+	ModeOff   = "off"
+	ModeBlock = "block"
+	ModeMerge = "merge"
+	ModeFlag  = "flag"
+)
+
+// Policy — что делать с дубликатом и как далеко в прошлое искать оригинал.
+type Policy struct {
+	Mode   string
+	Window time.Duration
+}
+
+// ValidMode сообщает, известен ли режим.
+func ValidMode(mode string) bool {
+	switch mode {
+	case ModeOff, ModeBlock, ModeMerge, ModeFlag:
+		return true
+	}
+	return false
+}
+
+// MaxImagePixels — больше пикселей ImageHash не декодирует: маленький файл
+// может объявить огромные размеры, и декодер выделит под них гигабайты.
+const MaxImagePixels = 64 << 20
+
+// ErrImageTooLarge — изображение больше MaxImagePixels, хеш не считается.
+var ErrImageTooLarge = errors.New("image is too large to hash")
+
+// ImageHash считает разностный хеш (dHash) изображения: картинка сжимается
+// до 9×8 оттенков серого, и каждый бит показывает, ярче ли пиксель соседа справа.
+func ImageHash(r io.Reader) (uint64, error) {
+	// Размеры читаются из заголовка, прочитанное затем отдаётся декодеру
+	var head bytes.Buffer
+	cfg, _, err := image.DecodeConfig(io.TeeReader(r, &head))
+	if err != nil {
+		return 0, fmt.Errorf("decode image: %w", err)
+	}
+	if int64(cfg.Width)*int64(cfg.Height) > MaxImagePixels {
+		return 0, fmt.Errorf("%w: %d×%d", ErrImageTooLarge, cfg.Width, cfg.Height)
+	}
+	img, _, err := image.Decode(io.MultiReader(&head, r))
+	if err != nil {
+		return 0, fmt.Errorf("decode image: %w", err)
+	}
+	const w, h = 9, 8
+	var gray [h][w]float64
+	b := img.Bounds()
+	for y := 0; y < h; y++ {
+		y0 := b.Min.Y + y*b.Dy()/h
+		y1 := b.Min.Y + (y+1)*b.Dy()/h
+		for x := 0; x < w; x++ {
+			x0 := b.Min.X + x*b.Dx()/w
+			x1 := b.Min.X + (x+1)*b.Dx()/w
+			gray[y][x] = averageLuma(img, x0, y0, x1, y1)
+		}
+	}
+
+	var hash uint64
+	for y := 0; y < h; y++ {
+		for x := 0; x < w-1; x++ {
+			hash <<= 1
+			if gray[y][x] > gray[y][x+1] {
+				hash |= 1
+			}
+		}
+	}
+	return hash, nil
+}
+
+// averageLuma — средняя яркость прямоугольника; большие картинки
+// прореживаются, чтобы не обходить миллионы пикселей.
+func averageLuma(img image.Image, x0, y0, x1, y1 int) float64 {
+	if x1 <= x0 {
+		x1 = x0 + 1
+	}
+	if y1 <= y0 {
+		y1 = y0 + 1
+	}
+	step := 1
+	if n := (x1 - x0) * (y1 - y0); n > 4096 {
+		step = 1 + (x1-x0)/64
+	}
+	var sum float64
+	var count int
+	for y := y0; y < y1; y += step {
+		for x := x0; x < x1; x += step {
+			r, g, b, _ := img.At(x, y).RGBA()
+			sum += 0.299*float64(r) + 0.587*float64(g) + 0.114*float64(b)
+			count++
+		}
+	}
+	return sum / float64(count)
+}
+
+// TextHash считает simhash заголовка и описания по парам соседних слов.
+// Регистр, пунктуация, ё/е и порядок абзацев на результат почти не влияют.
+func TextHash(title, description string) uint64 {
+	words := strings.FieldsFunc(strings.ToLower(title+" "+description), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for i, w := range words {
+		words[i] = strings.ReplaceAll(w, "ё", "е")
+	}
+	if len(words) == 0 {
+		return 0
+	}
+
+	shingles := words
+	if len(words) > 1 {
+		shingles = make([]string, 0, len(words)-1)
+		for i := 0; i+1 < len(words); i++ {
+			shingles = append(shingles, words[i]+" "+words[i+1])
+		}
+	}
+
+	var v [64]int
+	for _, s := range shingles {
+		h := fnv.New64a()
+		h.Write([]byte(s))
+		sum := h.Sum64()
+		for i := 0; i < 64; i++ {
+			if sum&(1<<uint(i)) != 0 {
+				v[i]++
+			} else {
+				v[i]--
+			}
+		}
+	}
+	var hash uint64
+	for i := 0; i < 64; i++ {
+		if v[i] > 0 {
+			hash |= 1 << uint(i)
+		}
+	}
+	return hash
+}
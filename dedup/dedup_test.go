@@ -0,0 +1,102 @@
+package dedup
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/png"
+	"math/bits"
+	"testing"
+)
+
+// distance — число различающихся бит, как в AdRepo.FindDuplicate.
+func distance(a, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
+
+func TestTextHashDuplicates(t *testing.T) {
+	const title = "Велосипед горный Stels"
+	const description = "Рама 18 дюймов, 21 скорость. Катался один сезон, всё работает. Самовывоз от метро."
+	tests := []struct {
+		name               string
+		title, description string
+		wantDuplicate      bool
+	}{
+		{"same text", title, description, true},
+		{"case and punctuation", "ВЕЛОСИПЕД ГОРНЫЙ STELS!", "рама 18 дюймов 21 скорость; катался один сезон — всё работает, самовывоз от метро", true},
+		{"ё and е", title, "Рама 18 дюймов, 21 скорость. Катался один сезон, все работает. Самовывоз от метро.", true},
+		{"other ad", "Диван угловой", "Раскладной, механизм дельфин. Обивка без пятен, доставка за ваш счёт.", false},
+	}
+	base := TextHash(title, description)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := distance(base, TextHash(tt.title, tt.description))
+			if got := d <= TextThreshold; got != tt.wantDuplicate {
+				t.Errorf("distance %d, duplicate = %v, want %v", d, got, tt.wantDuplicate)
+			}
+		})
+	}
+}
+
+func TestImageHashDuplicates(t *testing.T) {
+	gradient := func(w, h int, invert bool) []byte {
+		img := image.NewGray(image.Rect(0, 0, w, h))
+		for y := 0; y < h; y++ {
+			for x := 0; x < w; x++ {
+				v := uint8((x*7 + y*3) * 255 / (w*7 + h*3))
+				if invert {
+					v = 255 - v
+				}
+				img.SetGray(x, y, color.Gray{Y: v})
+			}
+		}
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, img); err != nil {
+			t.Fatal(err)
+		}
+		return buf.Bytes()
+	}
+	hash := func(data []byte) uint64 {
+		h, err := ImageHash(bytes.NewReader(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return h
+	}
+
+	base := hash(gradient(640, 480, false))
+	tests := []struct {
+		name          string
+		image         []byte
+		wantDuplicate bool
+	}{
+		{"same image", gradient(640, 480, false), true},
+		{"resized", gradient(320, 240, false), true},
+		{"inverted", gradient(640, 480, true), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := distance(base, hash(tt.image))
+			if got := d <= PhotoThreshold; got != tt.wantDuplicate {
+				t.Errorf("distance %d, duplicate = %v, want %v", d, got, tt.wantDuplicate)
+			}
+		})
+	}
+}
+
+func TestImageHashTooLarge(t *testing.T) {
+	var buf bytes.Buffer
+	if err := gif.Encode(&buf, image.NewPaletted(image.Rect(0, 0, 2, 2), color.Palette{color.Black, color.White}), nil); err != nil {
+		t.Fatal(err)
+	}
+	// Логический экран GIF объявляет 40000×40000, сам файл — несколько байт
+	data := buf.Bytes()
+	binary.LittleEndian.PutUint16(data[6:], 40000)
+	binary.LittleEndian.PutUint16(data[8:], 40000)
+	if _, err := ImageHash(bytes.NewReader(data)); !errors.Is(err, ErrImageTooLarge) {
+		t.Fatalf("error = %v, want ErrImageTooLarge", err)
+	}
+}
@@ -0,0 +1,12 @@
+package domain
+
+// Actor — тот, от чьего имени выполняется запрос.
+type Actor struct {
+	TelegramID string
+	Admin      bool
+}
+
+// Owns сообщает, может ли actor изменять ресурс пользователя ownerTelegramID.
+func (a Actor) Owns(ownerTelegramID string) bool {
+	return a.Admin || (a.TelegramID != "" && a.TelegramID == ownerTelegramID)
+}
@@ -2,18 +2,73 @@ package domain
 
 import "time"
 
+// Статусы публикации объявления.
+const (
+	// AdStatusActive — объявление видно в поиске.
+	AdStatusActive = "active"
+	// AdStatusPending — объявление ждёт решения модератора.
+	AdStatusPending = "pending"
+	// AdStatusRejected — модератор отклонил объявление.
+	AdStatusRejected = "rejected"
+	// AdStatusHidden — объявление скрыто по жалобам до решения модератора.
+	AdStatusHidden = "hidden"
+)
+
 type Advertisement struct {
-	ID          int64     `json:"id"`
-	UserID      int64     `json:"user_id"`
-	TelegramID  string    `json:"telegram_id"`
-	UserName    string    `json:"user_name"`
-	UserPhone   string    `json:"user_phone"`
-	Title       string    `json:"title"`
-	Description string    `json:"description"`
-	Price       int64     `json:"price"`
-	PhotosUrls  string    `json:"photos_urls"`
-	Address     string    `json:"address"`
-	Archived    bool      `json:"archived"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID               int64     `json:"id"`
+	UserID           int64     `json:"user_id"`
+	TelegramID       string    `json:"telegram_id,omitempty"`
+	UserName         string    `json:"user_name"`
+	UserPhone        string    `json:"user_phone"`
+	Title            string    `json:"title"`
+	Description      string    `json:"description"`
+	Price            int64     `json:"price"`
+	PhotosUrls       string    `json:"photos_urls"`
+	Address          string    `json:"address"`
+	Category         string    `json:"category"`
+	Status           string    `json:"status"`
+	ModerationReason string    `json:"moderation_reason,omitempty"`
+	RiskScore        int       `json:"risk_score"`
+	DuplicateOf      *int64    `json:"duplicate_of,omitempty"`
+	PhotoHash        *int64    `json:"-"`
+	TextHash         *int64    `json:"-"`
+	Archived         bool      `json:"archived"`
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
+	// ContactReveals — сколько раз открывали контакт продавца; видно только владельцу.
+	ContactReveals *int64 `json:"contact_reveals,omitempty"`
+	// Promotions — действующие пакеты продвижения (top, highlight, pinned).
+	Promotions []string `json:"promotions,omitempty"`
+	// Promoted — объявление стоит в выдаче на рекламной позиции.
+	Promoted bool `json:"promoted,omitempty"`
+}
+
+// HasPromotion сообщает, действует ли у объявления пакет pkg.
+func (a *Advertisement) HasPromotion(pkg string) bool {
+	for _, p := range a.Promotions {
+		if p == pkg {
+			return true
+		}
+	}
+	return false
+}
+
+// MaskContact скрывает контакты продавца от всех, кроме владельца: телефон
+// заменяется маской, telegram_id не отдаётся, иначе по нему можно написать
+// продавцу в обход preferred_contact=chat. Полный контакт выдаёт только
+// POST /ads/{id}/contact.
+func (a *Advertisement) MaskContact() {
+	a.TelegramID = ""
+	a.UserPhone = MaskPhone(a.UserPhone)
+}
+
+// DuplicateMatch — ранее опубликованное объявление, похожее на новое.
+type DuplicateMatch struct {
+	AdID int64 `json:"ad_id"`
+	// TelegramID — владелец оригинала; наружу не отдаётся, автору нового
+	// объявления достаточно SameSeller.
+	TelegramID    string `json:"-"`
+	SameSeller    bool   `json:"same_seller"`
+	PhotoDistance *int   `json:"photo_distance,omitempty"`
+	TextDistance  int    `json:"text_distance"`
 }
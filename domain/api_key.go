@@ -0,0 +1,15 @@
+package domain
+
+import "time"
+
+// APIKey — ключ доступа внешнего сервиса (бота, модераторов, аналитики).
+// Сам ключ не хранится, только его hash.
+type APIKey struct {
+	ID         int64      `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
@@ -0,0 +1,63 @@
+package domain
+
+import "unicode"
+
+// Способы связи с продавцом (users.preferred_contact).
+const (
+	// ContactPhone — звонок по телефону; также используется, если способ не указан.
+	ContactPhone = "phone"
+	// ContactTelegram — написать продавцу в Telegram напрямую.
+	ContactTelegram = "telegram"
+	// ContactChat — только анонимная переписка через бота, телефон не выдаётся.
+	ContactChat = "chat"
+)
+
+// Contact — контакт продавца, выданный покупателю по объявлению.
+type Contact struct {
+	AdID       int64  `json:"ad_id"`
+	Name       string `json:"name"`
+	Method     string `json:"method"`
+	Phone      string `json:"phone,omitempty"`
+	TelegramID string `json:"telegram_id,omitempty"`
+}
+
+// NewContact выбирает, что раскрыть покупателю, по предпочтению продавца.
+func NewContact(adID int64, name, phone, telegramID, preferred string) *Contact {
+	c := &Contact{AdID: adID, Name: name, Method: preferred}
+	switch preferred {
+	case ContactTelegram:
+		c.TelegramID = telegramID
+	case ContactChat:
+	default:
+		c.Method = ContactPhone
+		c.Phone = phone
+	}
+	return c
+}
+
+// MaskPhone оставляет первую и две последние цифры номера, остальные
+// заменяет звёздочками, сохраняя форматирование: "+7 (912) 345-67-89" →
+// "+7 (***) ***-**-89".
+func MaskPhone(phone string) string {
+	digits := 0
+	for _, r := range phone {
+		if unicode.IsDigit(r) {
+			digits++
+		}
+	}
+	if digits == 0 {
+		return phone
+	}
+	out := []rune(phone)
+	seen := 0
+	for i, r := range out {
+		if !unicode.IsDigit(r) {
+			continue
+		}
+		seen++
+		if seen > 1 && seen <= digits-2 {
+			out[i] = '*'
+		}
+	}
+	return string(out)
+}
@@ -0,0 +1,34 @@
+package domain
+
+import "time"
+
+// Роли участника переписки.
+const (
+	RoleBuyer  = "buyer"
+	RoleSeller = "seller"
+)
+
+// Conversation — переписка покупателя с продавцом по объявлению, как её
+// видит один из участников. Telegram ID собеседника не раскрывается.
+type Conversation struct {
+	ID            int64     `json:"id"`
+	AdID          int64     `json:"ad_id"`
+	AdTitle       string    `json:"ad_title"`
+	Role          string    `json:"role"`
+	Unread        int       `json:"unread"`
+	BlockedByMe   bool      `json:"blocked_by_me"`
+	BlockedByPeer bool      `json:"blocked_by_peer"`
+	LastMessage   *Message  `json:"last_message,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+	LastMessageAt time.Time `json:"last_message_at"`
+}
+
+// Message — сообщение в переписке. ReadAt — когда собеседник его прочитал.
+type Message struct {
+	ID             int64      `json:"id"`
+	ConversationID int64      `json:"conversation_id"`
+	FromMe         bool       `json:"from_me"`
+	Text           string     `json:"text"`
+	CreatedAt      time.Time  `json:"created_at"`
+	ReadAt         *time.Time `json:"read_at,omitempty"`
+}
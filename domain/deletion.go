@@ -0,0 +1,29 @@
+package domain
+
+import "time"
+
+// DeletionReceipt — квитанция об удалении аккаунта: что именно удалено.
+// Персональных данных в квитанции нет, поэтому она хранится и после удаления.
+type DeletionReceipt struct {
+	ID        int64     `json:"id"`
+	DeletedAt time.Time `json:"deleted_at"`
+	// Ads — удалённые объявления, включая архивные.
+	Ads int64 `json:"ads"`
+	// Photos — фото объявлений, поставленные на удаление из хранилища.
+	Photos        int64 `json:"photos"`
+	Favorites     int64 `json:"favorites"`
+	Conversations int64 `json:"conversations"`
+	Messages      int64 `json:"messages"`
+	Reviews       int64 `json:"reviews"`
+	Orders        int64 `json:"orders"`
+	// Exports — архивы выгрузок данных, поставленные на удаление из хранилища.
+	Exports       int64 `json:"exports"`
+	Notifications int64 `json:"notifications"`
+}
+
+// Виды объектов хранилища, удаляемых в фоне.
+const (
+	StoragePhoto  = "photo"
+	StorageExport = "export"
+	StorageImport = "import"
+)
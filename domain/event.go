@@ -0,0 +1,97 @@
+package domain
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// EventVersion — версия схемы data в событиях. Несовместимые изменения
+// схемы выпускаются с новой версией.
+const EventVersion = 1
+
+// Типы событий для подписчиков
+const (
+	EventAdCreated   = "ad.created"
+	EventAdUpdated   = "ad.updated"
+	EventAdArchived  = "ad.archived"
+	EventAdDeleted   = "ad.deleted"
+	EventUserCreated = "user.created"
+	EventUserUpdated = "user.updated"
+	EventUserDeleted = "user.deleted"
+)
+
+// EventTypes — все типы событий; "*" в подписке означает все.
+var EventTypes = []string{
+	EventAdCreated, EventAdUpdated, EventAdArchived, EventAdDeleted,
+	EventUserCreated, EventUserUpdated, EventUserDeleted,
+}
+
+// ValidEventType сообщает, известен ли тип события.
+func ValidEventType(t string) bool {
+	for _, et := range EventTypes {
+		if et == t {
+			return true
+		}
+	}
+	return false
+}
+
+// Event — доменное событие в том виде, в каком его получают подписчики.
+type Event struct {
+	ID        int64           `json:"id"`
+	Type      string          `json:"type"`
+	Version   int             `json:"version"`
+	CreatedAt time.Time       `json:"created_at"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// AdEventData — data событий ad.* версии 1.
+type AdEventData struct {
+	ID          int64     `json:"id"`
+	TelegramID  string    `json:"telegram_id"`
+	Title       string    `json:"title"`
+	Description string    `json:"description"`
+	Price       int64     `json:"price"`
+	Category    string    `json:"category"`
+	Address     string    `json:"address"`
+	PhotosUrls  string    `json:"photos_urls"`
+	Status      string    `json:"status"`
+	Archived    bool      `json:"archived"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+func NewAdEventData(ad *Advertisement) AdEventData {
+	return AdEventData{
+		ID:          ad.ID,
+		TelegramID:  ad.TelegramID,
+		Title:       ad.Title,
+		Description: ad.Description,
+		Price:       ad.Price,
+		Category:    ad.Category,
+		Address:     ad.Address,
+		PhotosUrls:  ad.PhotosUrls,
+		Status:      ad.Status,
+		Archived:    ad.Archived,
+		CreatedAt:   ad.CreatedAt,
+		UpdatedAt:   ad.UpdatedAt,
+	}
+}
+
+// UserEventData — data событий user.* версии 1. Телефон подписчикам не
+// передаётся.
+type UserEventData struct {
+	TelegramID       string    `json:"telegram_id"`
+	Name             string    `json:"name"`
+	PreferredContact string    `json:"preferred_contact"`
+	CreatedAt        time.Time `json:"created_at"`
+}
+
+func NewUserEventData(u *User) UserEventData {
+	return UserEventData{
+		TelegramID:       u.TelegramID,
+		Name:             u.Name,
+		PreferredContact: u.PreferredContact,
+		CreatedAt:        u.CreatedAt,
+	}
+}
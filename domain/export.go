@@ -0,0 +1,51 @@
+package domain
+
+import "time"
+
+// Статусы выгрузки персональных данных.
+const (
+	// ExportPending — выгрузка ждёт обработчика (в том числе после сбоя).
+	ExportPending = "pending"
+	// ExportRunning — архив собирается.
+	ExportRunning = "running"
+	// ExportReady — архив готов к скачиванию до ExpiresAt.
+	ExportReady = "ready"
+	// ExportFailed — собрать архив не удалось.
+	ExportFailed = "failed"
+	// ExportExpired — срок хранения архива истёк, файл удалён.
+	ExportExpired = "expired"
+)
+
+// DataExport — выгрузка всех данных пользователя одним ZIP-архивом.
+type DataExport struct {
+	ID         int64      `json:"id"`
+	TelegramID string     `json:"telegram_id"`
+	Status     string     `json:"status"`
+	Attempts   int        `json:"-"`
+	Error      string     `json:"error,omitempty"`
+	Size       int64      `json:"size,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	// DownloadURL — подписанная ссылка на архив; действует до ExpiresAt.
+	DownloadURL string `json:"download_url,omitempty"`
+	// ObjectName — имя архива в хранилище выгрузок.
+	ObjectName string `json:"-"`
+}
+
+// ExportConversation — переписка пользователя в выгрузке.
+type ExportConversation struct {
+	ID        int64      `json:"id"`
+	AdID      int64      `json:"ad_id"`
+	AdTitle   string     `json:"ad_title"`
+	Role      string     `json:"role"`
+	CreatedAt time.Time  `json:"created_at"`
+	Messages  []*Message `json:"messages"`
+}
+
+// ExportFavorite — объявление в избранном пользователя в выгрузке.
+type ExportFavorite struct {
+	AdID    int64     `json:"ad_id"`
+	AdTitle string    `json:"ad_title"`
+	AddedAt time.Time `json:"added_at"`
+}
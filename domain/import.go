@@ -0,0 +1,58 @@
+package domain
+
+import "time"
+
+// Статусы импорта объявлений.
+const (
+	// ImportPending — файл принят и ждёт обработчика.
+	ImportPending = "pending"
+	// ImportRunning — объявления создаются.
+	ImportRunning = "running"
+	// ImportDone — все строки обработаны; ошибки отдельных строк — в Rows.
+	ImportDone = "done"
+	// ImportFailed — файл не удалось обработать целиком.
+	ImportFailed = "failed"
+)
+
+// Форматы файла импорта.
+const (
+	ImportCSV  = "csv"
+	ImportJSON = "json"
+	// ImportZIP — архив с ads.csv или ads.json и файлами фото.
+	ImportZIP = "zip"
+)
+
+// Результаты строк импорта.
+const (
+	ImportRowCreated = "created"
+	ImportRowFailed  = "failed"
+)
+
+// ImportJob — загрузка пачки объявлений из файла.
+type ImportJob struct {
+	ID         int64      `json:"id"`
+	TelegramID string     `json:"telegram_id"`
+	Status     string     `json:"status"`
+	Format     string     `json:"format"`
+	Filename   string     `json:"filename"`
+	Total      int        `json:"total"`
+	Created    int        `json:"created"`
+	Failed     int        `json:"failed"`
+	Error      string     `json:"error,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	// Rows — результаты обработанных строк; только в ответе на запрос импорта.
+	Rows     []*ImportRowResult `json:"rows,omitempty"`
+	Attempts int                `json:"-"`
+	// SourceObject — имя загруженного файла в хранилище импортов.
+	SourceObject string `json:"-"`
+}
+
+// ImportRowResult — итог одной строки файла. Line — номер строки CSV
+// (с заголовком) или номер элемента JSON-массива с единицы.
+type ImportRowResult struct {
+	Line   int    `json:"line"`
+	Status string `json:"status"`
+	AdID   *int64 `json:"ad_id,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
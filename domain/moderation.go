@@ -0,0 +1,50 @@
+package domain
+
+import "time"
+
+// Статусы задачи модерации.
+const (
+	ModerationPending  = "pending"
+	ModerationClaimed  = "claimed"
+	ModerationApproved = "approved"
+	ModerationRejected = "rejected"
+)
+
+// Причины попадания объявления в очередь.
+const (
+	ModerationTriggerCreated = "created"
+	ModerationTriggerEdited  = "edited"
+	// ModerationTriggerRules — объявление отправили на проверку автоматические правила.
+	ModerationTriggerRules = "rules"
+	// ModerationTriggerDuplicate — объявление похоже на уже опубликованное.
+	ModerationTriggerDuplicate = "duplicate"
+)
+
+// RejectionReasons — коды причин отклонения и их текст для продавца.
+var RejectionReasons = map[string]string{
+	"spam":            "спам или повторная публикация",
+	"prohibited_item": "товар запрещён к продаже",
+	"wrong_category":  "неверная категория",
+	"misleading":      "недостоверное описание или цена",
+	"offensive":       "оскорбительное содержание",
+	"contacts_in_ad":  "контакты в тексте объявления",
+	"reported":        "жалобы покупателей подтвердились",
+	"other":           "нарушение правил площадки",
+}
+
+// ModerationTask — объявление в очереди премодерации.
+type ModerationTask struct {
+	ID         int64          `json:"id"`
+	AdID       int64          `json:"ad_id"`
+	Status     string         `json:"status"`
+	Trigger    string         `json:"trigger"`
+	Notes      string         `json:"notes,omitempty"`
+	ClaimedBy  string         `json:"claimed_by,omitempty"`
+	ClaimedAt  *time.Time     `json:"claimed_at,omitempty"`
+	ReasonCode string         `json:"reason_code,omitempty"`
+	Comment    string         `json:"comment,omitempty"`
+	ResolvedBy string         `json:"resolved_by,omitempty"`
+	ResolvedAt *time.Time     `json:"resolved_at,omitempty"`
+	CreatedAt  time.Time      `json:"created_at"`
+	Ad         *Advertisement `json:"ad,omitempty"`
+}
@@ -0,0 +1,138 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+)
+
+// Статусы уведомлений в outbox
+const (
+	// NotificationPending — ждёт отправки или повторной попытки.
+	NotificationPending = "pending"
+	NotificationSent    = "sent"
+	// NotificationDead — доставка прекращена: исчерпаны попытки, сообщение
+	// отклонено Telegram или пользователь заблокировал бота.
+	NotificationDead = "dead"
+)
+
+// Виды уведомлений
+const (
+	NotifyModeration = "moderation"
+	NotifyReport     = "report"
+	NotifySanction   = "sanction"
+	NotifyMessage    = "message"
+	NotifyReview     = "review"
+	NotifyOrder      = "order"
+	NotifyExport     = "export"
+	NotifyImport     = "import"
+)
+
+// Notification — сообщение пользователю, которое бот доставит из outbox.
+type Notification struct {
+	ID            int64      `json:"id"`
+	TelegramID    string     `json:"telegram_id"`
+	Kind          string     `json:"kind"`
+	Text          string     `json:"text"`
+	Status        string     `json:"status"`
+	Attempts      int        `json:"attempts"`
+	NextAttemptAt time.Time  `json:"next_attempt_at"`
+	LastError     string     `json:"last_error,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	SentAt        *time.Time `json:"sent_at,omitempty"`
+}
+
+// ModerationOutcomeText — сообщение продавцу о решении модератора.
+func ModerationOutcomeText(t *ModerationTask) string {
+	if t.Status == ModerationApproved {
+		return fmt.Sprintf("Ваше объявление «%s» прошло проверку и опубликовано.", t.Ad.Title)
+	}
+	text := fmt.Sprintf("Ваше объявление «%s» отклонено: %s.", t.Ad.Title, RejectionReasons[t.ReasonCode])
+	if t.Comment != "" {
+		text += "\nКомментарий модератора: " + t.Comment
+	}
+	return text
+}
+
+// ReportResolvedText — благодарность пожаловавшемуся.
+func ReportResolvedText(adTitle string) string {
+	return fmt.Sprintf("Ваша жалоба на объявление «%s» рассмотрена. Спасибо!", adTitle)
+}
+
+// ReportOutcomeText — сообщение продавцу о снятии объявления или
+// предупреждении; пустая строка, если продавцу сообщать нечего.
+func ReportOutcomeText(adTitle, outcome, comment string) string {
+	var text string
+	switch outcome {
+	case ReportAdRemoved:
+		text = fmt.Sprintf("Ваше объявление «%s» снято с публикации по жалобам покупателей.", adTitle)
+	case ReportSellerWarned:
+		text = fmt.Sprintf("На ваше объявление «%s» поступили жалобы. Пожалуйста, соблюдайте правила площадки.", adTitle)
+	default:
+		return ""
+	}
+	if comment != "" {
+		text += "\nКомментарий модератора: " + comment
+	}
+	return text
+}
+
+// BanText — сообщение о блокировке аккаунта.
+func BanText(s *Sanction) string {
+	if s.ExpiresAt != nil {
+		return fmt.Sprintf("Ваш аккаунт заблокирован до %s: %s", s.ExpiresAt.Format("02.01.2006 15:04"), s.Reason)
+	}
+	return "Ваш аккаунт заблокирован: " + s.Reason
+}
+
+// UnbanText — сообщение о снятии блокировки.
+const UnbanText = "Блокировка вашего аккаунта снята."
+
+// RelayText — сообщение собеседнику от имени бота. Номер переписки нужен
+// боту, чтобы направить ответ обратно.
+func RelayText(adTitle string, m *Message) string {
+	return fmt.Sprintf("Сообщение по объявлению «%s» (переписка #%d):\n\n%s", adTitle, m.ConversationID, m.Text)
+}
+
+// NewReviewText — сообщение продавцу о новом отзыве.
+func NewReviewText(rv *Review) string {
+	text := fmt.Sprintf("Новый отзыв (%d из 5) по объявлению «%s»", rv.Rating, rv.AdTitle)
+	if rv.Text != "" {
+		text += ":\n\n" + rv.Text
+	}
+	return text
+}
+
+// ReviewReplyText — сообщение покупателю об ответе продавца на отзыв.
+func ReviewReplyText(rv *Review) string {
+	return fmt.Sprintf("Продавец ответил на ваш отзыв по объявлению «%s»:\n\n%s", rv.AdTitle, rv.Reply)
+}
+
+// OrderStatusText — сообщение покупателю об оплате или возврате заказа;
+// пустая строка для остальных статусов.
+func OrderStatusText(o *Order) string {
+	switch o.Status {
+	case OrderPaid:
+		return fmt.Sprintf("Оплата заказа №%d получена: «%s» подключено.", o.ID, o.Title)
+	case OrderRefunded:
+		return fmt.Sprintf("Оплата заказа №%d «%s» возвращена.", o.ID, o.Title)
+	}
+	return ""
+}
+
+// ExportReadyText — сообщение о готовности выгрузки данных.
+func ExportReadyText(e *DataExport) string {
+	return fmt.Sprintf("Архив с вашими данными готов. Скачать его можно до %s.",
+		e.ExpiresAt.Format("02.01.2006 15:04 MST"))
+}
+
+// ImportDoneText — сообщение об окончании импорта объявлений.
+func ImportDoneText(j *ImportJob) string {
+	if j.Status == ImportFailed {
+		return fmt.Sprintf("Импорт объявлений из файла «%s» не удался: %s", j.Filename, j.Error)
+	}
+	text := fmt.Sprintf("Импорт объявлений из файла «%s» завершён: создано %d", j.Filename, j.Created)
+	if j.Failed > 0 {
+		text += fmt.Sprintf(", с ошибками %d — подробности в отчёте об импорте №%d", j.Failed, j.ID)
+	}
+	return text + "."
+}
@@ -0,0 +1,62 @@
+package domain
+
+import "time"
+
+// Статусы заказа.
+const (
+	// OrderCreated — заказ создан, счёт ещё не выставлен.
+	OrderCreated = "created"
+	// OrderPending — счёт выставлен, ждём оплату.
+	OrderPending = "pending"
+	// OrderPaid — оплачен, услуга подключена.
+	OrderPaid = "paid"
+	// OrderFailed — оплата не прошла.
+	OrderFailed = "failed"
+	// OrderCancelled — заказ отменён до оплаты.
+	OrderCancelled = "cancelled"
+	// OrderRefunded — деньги возвращены, услуга отключена.
+	OrderRefunded = "refunded"
+)
+
+// OrderTransitions — допустимые переходы статусов заказа. Переход в текущий
+// статус допускается всегда и ничего не меняет: провайдеры повторяют
+// уведомления.
+var OrderTransitions = map[string][]string{
+	OrderCreated: {OrderPending, OrderPaid, OrderFailed, OrderCancelled},
+	OrderPending: {OrderPaid, OrderFailed, OrderCancelled},
+	OrderFailed:  {OrderPending, OrderPaid},
+	OrderPaid:    {OrderRefunded},
+}
+
+// CanTransition сообщает, можно ли перевести заказ из from в to.
+func CanTransition(from, to string) bool {
+	for _, s := range OrderTransitions[from] {
+		if s == to {
+			return true
+		}
+	}
+	return false
+}
+
+// Order — заказ платной услуги. Amount — в копейках (минимальных единицах валюты).
+type Order struct {
+	ID               int64      `json:"id"`
+	TelegramID       string     `json:"telegram_id"`
+	AdID             *int64     `json:"ad_id,omitempty"`
+	Product          string     `json:"product"`
+	Title            string     `json:"title"`
+	Amount           int64      `json:"amount"`
+	Currency         string     `json:"currency"`
+	Provider         string     `json:"provider"`
+	Status           string     `json:"status"`
+	ProviderChargeID string     `json:"provider_charge_id,omitempty"`
+	CreatedAt        time.Time  `json:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at"`
+	PaidAt           *time.Time `json:"paid_at,omitempty"`
+	RefundedAt       *time.Time `json:"refunded_at,omitempty"`
+}
+
+// PromotionProduct — код товара «пакет продвижения pkg».
+func PromotionProduct(pkg string) string {
+	return "promotion:" + pkg
+}
@@ -0,0 +1,40 @@
+package domain
+
+import "testing"
+
+func TestCanTransition(t *testing.T) {
+	tests := []struct {
+		from, to string
+		want     bool
+	}{
+		{OrderCreated, OrderPending, true},
+		{OrderCreated, OrderPaid, true},
+		{OrderCreated, OrderFailed, true},
+		{OrderCreated, OrderCancelled, true},
+		{OrderCreated, OrderRefunded, false},
+		{OrderPending, OrderPaid, true},
+		{OrderPending, OrderFailed, true},
+		{OrderPending, OrderCancelled, true},
+		{OrderPending, OrderCreated, false},
+		{OrderPending, OrderRefunded, false},
+		{OrderFailed, OrderPending, true},
+		{OrderFailed, OrderPaid, true},
+		{OrderFailed, OrderCancelled, false},
+		{OrderPaid, OrderRefunded, true},
+		{OrderPaid, OrderCancelled, false},
+		{OrderPaid, OrderFailed, false},
+		{OrderPaid, OrderPending, false},
+		// Конечные статусы
+		{OrderCancelled, OrderPending, false},
+		{OrderCancelled, OrderPaid, false},
+		{OrderRefunded, OrderPaid, false},
+		// Повтор текущего статуса обрабатывает OrderRepo.Transition
+		{OrderPaid, OrderPaid, false},
+		{"unknown", OrderPaid, false},
+	}
+	for _, tt := range tests {
+		if got := CanTransition(tt.from, tt.to); got != tt.want {
+			t.Errorf("CanTransition(%q, %q) = %v, want %v", tt.from, tt.to, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package domain
+
+import "time"
+
+// Пакеты продвижения.
+const (
+	// PromotionTop — объявление показывается в продвигаемых позициях поиска.
+	PromotionTop = "top"
+	// PromotionHighlight — объявление выделяется в выдаче, позиция не меняется.
+	PromotionHighlight = "highlight"
+	// PromotionPinned — объявление закреплено над выдачей своей категории.
+	PromotionPinned = "pinned"
+)
+
+// PromotionPackage — пакет продвижения из каталога. Price — в рублях.
+type PromotionPackage struct {
+	Code     string        `json:"code"`
+	Title    string        `json:"title"`
+	Duration time.Duration `json:"-"`
+	Days     int           `json:"days"`
+	Price    int64         `json:"price"`
+}
+
+// PromotionPackages — каталог пакетов продвижения.
+var PromotionPackages = map[string]PromotionPackage{
+	PromotionTop:       {Code: PromotionTop, Title: "Поднятие в поиске", Duration: 7 * 24 * time.Hour, Days: 7, Price: 199},
+	PromotionHighlight: {Code: PromotionHighlight, Title: "Выделение цветом", Duration: 7 * 24 * time.Hour, Days: 7, Price: 99},
+	PromotionPinned:    {Code: PromotionPinned, Title: "Закрепление в категории", Duration: 3 * 24 * time.Hour, Days: 3, Price: 299},
+}
+
+// Promotion — продвижение объявления пакетом Package на период [StartsAt, EndsAt).
+type Promotion struct {
+	ID          int64      `json:"id"`
+	AdID        int64      `json:"ad_id"`
+	Package     string     `json:"package"`
+	StartsAt    time.Time  `json:"starts_at"`
+	EndsAt      time.Time  `json:"ends_at"`
+	CreatedBy   string     `json:"created_by"`
+	CreatedAt   time.Time  `json:"created_at"`
+	CancelledAt *time.Time `json:"cancelled_at,omitempty"`
+	Active      bool       `json:"active"`
+}
@@ -0,0 +1,53 @@
+package domain
+
+import "time"
+
+// ReportReasons — категории жалоб на объявление.
+var ReportReasons = map[string]string{
+	"scam":       "мошенничество",
+	"prohibited": "запрещённый товар",
+	"spam":       "спам или дубликат",
+	"wrong_info": "недостоверная информация",
+	"offensive":  "оскорбительное содержание",
+	"sold":       "товар уже продан",
+	"other":      "другое",
+}
+
+// Статусы жалобы.
+const (
+	ReportOpen     = "open"
+	ReportResolved = "resolved"
+)
+
+// Решения модератора по жалобам.
+const (
+	// ReportDismissed — нарушений нет, скрытое объявление возвращается в поиск.
+	ReportDismissed = "dismissed"
+	// ReportAdRemoved — объявление снимается с публикации.
+	ReportAdRemoved = "ad_removed"
+	// ReportSellerWarned — продавец предупреждён, объявление остаётся.
+	ReportSellerWarned = "seller_warned"
+)
+
+// ReportOutcomes — допустимые решения по жалобам.
+var ReportOutcomes = map[string]bool{
+	ReportDismissed:    true,
+	ReportAdRemoved:    true,
+	ReportSellerWarned: true,
+}
+
+// Report — жалоба покупателя на объявление.
+type Report struct {
+	ID                 int64          `json:"id"`
+	AdID               int64          `json:"ad_id"`
+	ReporterTelegramID string         `json:"reporter_telegram_id"`
+	Reason             string         `json:"reason"`
+	Text               string         `json:"text"`
+	Status             string         `json:"status"`
+	Outcome            string         `json:"outcome,omitempty"`
+	Comment            string         `json:"comment,omitempty"`
+	ResolvedBy         string         `json:"resolved_by,omitempty"`
+	ResolvedAt         *time.Time     `json:"resolved_at,omitempty"`
+	CreatedAt          time.Time      `json:"created_at"`
+	Ad                 *Advertisement `json:"ad,omitempty"`
+}
@@ -0,0 +1,33 @@
+package domain
+
+import "time"
+
+// Статусы отзыва.
+const (
+	// ReviewPublished — отзыв виден и учитывается в рейтинге продавца.
+	ReviewPublished = "published"
+	// ReviewPending — сработали правила проверки текста, отзыв ждёт модератора.
+	ReviewPending = "pending"
+	// ReviewHidden — отзыв скрыт модератором.
+	ReviewHidden = "hidden"
+)
+
+// Review — отзыв покупателя о продавце по объявлению.
+type Review struct {
+	ID               int64      `json:"id"`
+	AdID             *int64     `json:"ad_id,omitempty"`
+	AdTitle          string     `json:"ad_title"`
+	SellerTelegramID string     `json:"seller_telegram_id"`
+	BuyerName        string     `json:"buyer_name"`
+	Rating           int        `json:"rating"`
+	Text             string     `json:"text"`
+	Reply            string     `json:"reply,omitempty"`
+	RepliedAt        *time.Time `json:"replied_at,omitempty"`
+	Status           string     `json:"status"`
+	FlaggedAt        *time.Time `json:"flagged_at,omitempty"`
+	FlagReason       string     `json:"flag_reason,omitempty"`
+	ModerationNote   string     `json:"moderation_note,omitempty"`
+	CreatedAt        time.Time  `json:"created_at"`
+	// BuyerTelegramID нужен для уведомлений и не отдаётся клиентам.
+	BuyerTelegramID string `json:"-"`
+}
@@ -0,0 +1,29 @@
+package domain
+
+import "time"
+
+// Виды санкций против пользователя.
+const (
+	// SanctionBan — пользователь не может ничего публиковать и изменять,
+	// его объявления скрыты.
+	SanctionBan = "ban"
+	// SanctionShadowBan — пользователь ничего не замечает, но его объявления
+	// видит только он сам.
+	SanctionShadowBan = "shadow_ban"
+)
+
+// Sanction — бан или теневой бан. Записи не удаляются: снятие санкции
+// фиксируется в LiftedAt/LiftedBy, так что история остаётся для аудита.
+type Sanction struct {
+	ID         int64      `json:"id"`
+	TelegramID string     `json:"telegram_id"`
+	Kind       string     `json:"kind"`
+	Reason     string     `json:"reason"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	CreatedBy  string     `json:"created_by"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LiftedAt   *time.Time `json:"lifted_at,omitempty"`
+	LiftedBy   string     `json:"lifted_by,omitempty"`
+	LiftReason string     `json:"lift_reason,omitempty"`
+	Active     bool       `json:"active"`
+}
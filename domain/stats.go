@@ -0,0 +1,33 @@
+package domain
+
+// DailyStat — показатели объявления (или всех объявлений продавца) за день.
+// Favorites — сколько раз объявление добавили в избранное за день.
+type DailyStat struct {
+	Day            string `json:"day"`
+	Views          int64  `json:"views"`
+	Favorites      int64  `json:"favorites"`
+	ContactReveals int64  `json:"contact_reveals"`
+}
+
+// AdStats — показатели одного объявления за период.
+type AdStats struct {
+	AdID           int64  `json:"ad_id"`
+	Title          string `json:"title"`
+	Status         string `json:"status"`
+	Archived       bool   `json:"archived"`
+	Views          int64  `json:"views"`
+	Favorites      int64  `json:"favorites"`
+	ContactReveals int64  `json:"contact_reveals"`
+	// InFavorites — сколько пользователей держат объявление в избранном сейчас.
+	InFavorites int64       `json:"in_favorites"`
+	Daily       []DailyStat `json:"daily"`
+}
+
+// SellerStats — показатели всех объявлений продавца за период [From, To].
+type SellerStats struct {
+	From  string      `json:"from"`
+	To    string      `json:"to"`
+	Total DailyStat   `json:"total"`
+	Daily []DailyStat `json:"daily"`
+	Ads   []*AdStats  `json:"ads"`
+}
@@ -9,5 +9,7 @@ type User struct {
 	Phone            string    `json:"phone"`
 	PreferredContact string    `json:"preferred_contact"`
 	AdsCount         int64     `json:"ads_count"`
+	Rating           float64   `json:"rating"`
+	ReviewsCount     int64     `json:"reviews_count"`
 	CreatedAt        time.Time `json:"created_at"`
 }
@@ -0,0 +1,53 @@
+package domain
+
+import "time"
+
+// Статусы доставки события подписчику
+const (
+	WebhookPending   = "pending"
+	WebhookDelivered = "delivered"
+	// WebhookFailed — попытки исчерпаны; доставку можно повторить вручную.
+	WebhookFailed = "failed"
+)
+
+// WebhookSubscription — подписка внешнего сервиса на события.
+type WebhookSubscription struct {
+	ID  int64  `json:"id"`
+	URL string `json:"url"`
+	// Events — типы событий; "*" — все.
+	Events      []string  `json:"events"`
+	Description string    `json:"description"`
+	Active      bool      `json:"active"`
+	Secret      string    `json:"-"`
+	CreatedBy   string    `json:"created_by"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// WebhookDelivery — доставка одного события одному подписчику.
+type WebhookDelivery struct {
+	ID             int64      `json:"id"`
+	SubscriptionID int64      `json:"subscription_id"`
+	EventID        int64      `json:"event_id"`
+	EventType      string     `json:"event_type"`
+	Status         string     `json:"status"`
+	Attempts       int        `json:"attempts"`
+	NextAttemptAt  time.Time  `json:"next_attempt_at"`
+	LastStatusCode int        `json:"last_status_code,omitempty"`
+	LastError      string     `json:"last_error,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	DeliveredAt    *time.Time `json:"delivered_at,omitempty"`
+	// AttemptLog заполняется только при запросе одной доставки.
+	AttemptLog []*WebhookAttempt `json:"attempt_log,omitempty"`
+}
+
+// WebhookAttempt — запись журнала о попытке доставки.
+type WebhookAttempt struct {
+	ID         int64     `json:"id"`
+	StartedAt  time.Time `json:"started_at"`
+	DurationMs int64     `json:"duration_ms"`
+	StatusCode int       `json:"status_code,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	// ResponseBody — начало ответа подписчика.
+	ResponseBody string `json:"response_body,omitempty"`
+}
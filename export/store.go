@@ -0,0 +1,65 @@
+// Package export собирает выгрузки персональных данных пользователей.
+package export
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"poppins/domain"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+)
+
+// maxLinkTTL — предельный срок подписанной ссылки S3.
+const maxLinkTTL = 7 * 24 * time.Hour
+
+// Store хранит архивы выгрузок в отдельном закрытом бакете: скачать архив
+// можно только по подписанной ссылке.
+type Store struct {
+	Client *minio.Client
+	Bucket string
+}
+
+func NewStore(client *minio.Client, bucket string) *Store {
+	return &Store{Client: client, Bucket: bucket}
+}
+
+// Ensure создаёт бакет выгрузок, если его нет. Публичную политику бакет не получает.
+func (s *Store) Ensure(ctx context.Context) error {
+	exists, err := s.Client.BucketExists(ctx, s.Bucket)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return nil
+	}
+	return s.Client.MakeBucket(ctx, s.Bucket, minio.MakeBucketOptions{})
+}
+
+func (s *Store) put(ctx context.Context, objectName string, r io.Reader, size int64) error {
+	_, err := s.Client.PutObject(ctx, s.Bucket, objectName, r, size,
+		minio.PutObjectOptions{ContentType: "application/zip"})
+	return err
+}
+
+func (s *Store) remove(ctx context.Context, objectName string) error {
+	return s.Client.RemoveObject(ctx, s.Bucket, objectName, minio.RemoveObjectOptions{})
+}
+
+// DownloadURL возвращает подписанную ссылку на архив готовой выгрузки,
+// действующую до e.ExpiresAt.
+func (s *Store) DownloadURL(ctx context.Context, e *domain.DataExport) (string, error) {
+	ttl := min(time.Until(*e.ExpiresAt), maxLinkTTL)
+	if ttl < time.Second {
+		return "", fmt.Errorf("export %d has expired", e.ID)
+	}
+	params := url.Values{}
+	params.Set("response-content-disposition", fmt.Sprintf(`attachment; filename="export-%d.zip"`, e.ID))
+	u, err := s.Client.PresignedGetObject(ctx, s.Bucket, e.ObjectName, ttl, params)
+	if err != nil {
+		return "", err
+	}
+	return u.String(), nil
+}
@@ -0,0 +1,242 @@
+package export
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path"
+	"poppins/domain"
+	"poppins/repository"
+	"strings"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+)
+
+// Worker собирает выгрузки из очереди: профиль, объявления с фотографиями,
+// избранное, переписки и отзывы складываются в ZIP и кладутся в Store.
+// Неудачная сборка повторяется до MaxAttempts раз.
+type Worker struct {
+	Repo  *repository.ExportRepo
+	Users *repository.UserRepo
+	Store *Store
+	// Photos и PhotoBucket — хранилище фотографий объявлений.
+	Photos      *minio.Client
+	PhotoBucket string
+	// TTL — сколько архив хранится и действует ссылка на него.
+	TTL         time.Duration
+	MaxAttempts int
+	// Lease — сколько выгрузка закреплена за обработчиком во время сборки.
+	Lease time.Duration
+}
+
+func NewWorker(repo *repository.ExportRepo, users *repository.UserRepo, store *Store,
+	photos *minio.Client, photoBucket string, ttl time.Duration) *Worker {
+	return &Worker{
+		Repo:        repo,
+		Users:       users,
+		Store:       store,
+		Photos:      photos,
+		PhotoBucket: photoBucket,
+		TTL:         ttl,
+		MaxAttempts: 3,
+		Lease:       30 * time.Minute,
+	}
+}
+
+// Run проверяет очередь каждые every; пока в ней есть выгрузки, берёт их без ожидания.
+func (w *Worker) Run(every time.Duration) {
+	for {
+		e, err := w.Repo.Claim(w.Lease)
+		if err != nil {
+			log.Printf("export: %v", err)
+		}
+		if e == nil {
+			time.Sleep(every)
+			continue
+		}
+		w.process(e)
+	}
+}
+
+func (w *Worker) process(e *domain.DataExport) {
+	objectName := fmt.Sprintf("%s/export-%d.zip", e.TelegramID, e.ID)
+	size, err := w.build(e.TelegramID, objectName)
+	switch {
+	case err == nil:
+		err = w.Repo.Complete(e.ID, objectName, size, time.Now().Add(w.TTL))
+		if errors.Is(err, repository.ErrNotFound) {
+			// Пользователь удалил аккаунт, пока собирался архив
+			err = w.Store.remove(context.Background(), objectName)
+		}
+	case e.Attempts >= w.MaxAttempts:
+		log.Printf("export %d failed after %d attempts: %v", e.ID, e.Attempts, err)
+		err = w.Repo.Fail(e.ID, err.Error())
+	default:
+		log.Printf("export %d: %v", e.ID, err)
+		err = w.Repo.Retry(e.ID, time.Now().Add(time.Duration(e.Attempts)*time.Minute), err.Error())
+	}
+	if err != nil {
+		log.Printf("export: update export %d: %v", e.ID, err)
+	}
+}
+
+// build собирает архив во временном файле и загружает его в Store.
+func (w *Worker) build(telegramID, objectName string) (int64, error) {
+	f, err := os.CreateTemp("", "export-*.zip")
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	zw := zip.NewWriter(f)
+	if err := w.write(zw, telegramID); err != nil {
+		return 0, err
+	}
+	if err := zw.Close(); err != nil {
+		return 0, err
+	}
+	size, err := f.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return 0, err
+	}
+	if err := w.Store.put(context.Background(), objectName, f, size); err != nil {
+		return 0, fmt.Errorf("upload archive: %w", err)
+	}
+	return size, nil
+}
+
+func (w *Worker) write(zw *zip.Writer, telegramID string) error {
+	user, err := w.Users.GetByID(telegramID)
+	if err != nil {
+		return fmt.Errorf("load profile: %w", err)
+	}
+	if err := writeJSON(zw, "profile.json", user); err != nil {
+		return err
+	}
+
+	ads, err := w.Repo.Ads(telegramID)
+	if err != nil {
+		return err
+	}
+	if err := writeJSON(zw, "ads.json", nonNil(ads)); err != nil {
+		return err
+	}
+	for _, ad := range ads {
+		if err := w.writePhoto(zw, ad); err != nil {
+			return err
+		}
+	}
+
+	favorites, err := w.Repo.Favorites(telegramID)
+	if err != nil {
+		return err
+	}
+	if err := writeJSON(zw, "favorites.json", nonNil(favorites)); err != nil {
+		return err
+	}
+	conversations, err := w.Repo.Conversations(telegramID)
+	if err != nil {
+		return err
+	}
+	if err := writeJSON(zw, "messages.json", nonNil(conversations)); err != nil {
+		return err
+	}
+	written, err := w.Repo.ReviewsWritten(telegramID)
+	if err != nil {
+		return err
+	}
+	if err := writeJSON(zw, "reviews_written.json", nonNil(written)); err != nil {
+		return err
+	}
+	received, err := w.Repo.ReviewsReceived(telegramID)
+	if err != nil {
+		return err
+	}
+	return writeJSON(zw, "reviews_received.json", nonNil(received))
+}
+
+// writePhoto кладёт фото объявления в photos/<id объявления><расширение>.
+// Фото, которого уже нет в хранилище, пропускается.
+func (w *Worker) writePhoto(zw *zip.Writer, ad *domain.Advertisement) error {
+	if ad.PhotosUrls == "" {
+		return nil
+	}
+	ctx := context.Background()
+	objectName := strings.TrimPrefix(ad.PhotosUrls, "/ads/")
+	obj, err := w.Photos.GetObject(ctx, w.PhotoBucket, objectName, minio.GetObjectOptions{})
+	if err != nil {
+		return fmt.Errorf("get photo %s: %w", objectName, err)
+	}
+	defer obj.Close()
+	if _, err := obj.Stat(); err != nil {
+		if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+			log.Printf("export: photo %s of ad %d is missing", objectName, ad.ID)
+			return nil
+		}
+		return fmt.Errorf("stat photo %s: %w", objectName, err)
+	}
+	// Фото уже сжаты, повторное сжатие только тратит время
+	fw, err := zw.CreateHeader(&zip.FileHeader{
+		Name:     fmt.Sprintf("photos/%d%s", ad.ID, path.Ext(objectName)),
+		Method:   zip.Store,
+		Modified: ad.UpdatedAt,
+	})
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(fw, obj); err != nil {
+		return fmt.Errorf("copy photo %s: %w", objectName, err)
+	}
+	return nil
+}
+
+func writeJSON(zw *zip.Writer, name string, v interface{}) error {
+	fw, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(fw)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		return fmt.Errorf("write %s: %w", name, err)
+	}
+	return nil
+}
+
+// nonNil заменяет nil на пустой срез, чтобы в архиве был [], а не null.
+func nonNil[T any](s []T) []T {
+	if s == nil {
+		return []T{}
+	}
+	return s
+}
+
+// PurgeLoop периодически удаляет архивы выгрузок с истёкшим сроком хранения.
+func (w *Worker) PurgeLoop(every time.Duration) {
+	for range time.Tick(every) {
+		list, err := w.Repo.ListExpired(100)
+		if err != nil {
+			log.Printf("purge exports: %v", err)
+			continue
+		}
+		for _, e := range list {
+			if err := w.Store.remove(context.Background(), e.ObjectName); err != nil {
+				log.Printf("purge export %d: %v", e.ID, err)
+				continue
+			}
+			if err := w.Repo.MarkExpired(e.ID); err != nil {
+				log.Printf("purge export %d: %v", e.ID, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,89 @@
+// Package feed строит Atom-ленты (RFC 4287).
+package feed
+
+import (
+	"crypto/sha1"
+	"encoding/xml"
+	"fmt"
+	"time"
+)
+
+// ContentType — MIME-тип Atom-ленты.
+const ContentType = "application/atom+xml; charset=utf-8"
+
+const atomNS = "http://www.w3.org/2005/Atom"
+
+type Feed struct {
+	XMLName  xml.Name `xml:"feed"`
+	NS       string   `xml:"xmlns,attr"`
+	ID       string   `xml:"id"`
+	Title    string   `xml:"title"`
+	Subtitle string   `xml:"subtitle,omitempty"`
+	Updated  Time     `xml:"updated"`
+	Links    []Link   `xml:"link"`
+	Author   *Person  `xml:"author,omitempty"`
+	Entries  []*Entry `xml:"entry"`
+}
+
+type Entry struct {
+	ID         string     `xml:"id"`
+	Title      string     `xml:"title"`
+	Updated    Time       `xml:"updated"`
+	Published  Time       `xml:"published"`
+	Author     *Person    `xml:"author,omitempty"`
+	Links      []Link     `xml:"link"`
+	Categories []Category `xml:"category,omitempty"`
+	Content    *Text      `xml:"content,omitempty"`
+}
+
+type Link struct {
+	Rel    string `xml:"rel,attr,omitempty"`
+	Type   string `xml:"type,attr,omitempty"`
+	Href   string `xml:"href,attr"`
+	Length int64  `xml:"length,attr,omitempty"`
+}
+
+type Person struct {
+	Name string `xml:"name"`
+}
+
+type Category struct {
+	Term string `xml:"term,attr"`
+}
+
+type Text struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",chardata"`
+}
+
+// Time выводится в формате RFC 3339, как требует Atom.
+type Time time.Time
+
+func (t Time) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return e.EncodeElement(time.Time(t).UTC().Format(time.RFC3339), start)
+}
+
+// Marshal возвращает документ ленты с XML-заголовком.
+func (f *Feed) Marshal() ([]byte, error) {
+	f.NS = atomNS
+	body, err := xml.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), body...), nil
+}
+
+// namespace — пространство имён UUID для идентификаторов лент и записей.
+var namespace = [16]byte{0x6f, 0x1c, 0x2d, 0x0e, 0x8a, 0x51, 0x4b, 0x7e, 0x9d, 0x33, 0x5c, 0x12, 0xa4, 0x40, 0x07, 0xe9}
+
+// ID возвращает постоянный идентификатор urn:uuid (UUID версии 5) для name:
+// одно и то же имя всегда даёт тот же ID, независимо от адреса сервера.
+func ID(name string) string {
+	h := sha1.New()
+	h.Write(namespace[:])
+	h.Write([]byte(name))
+	u := h.Sum(nil)[:16]
+	u[6] = u[6]&0x0f | 0x50
+	u[8] = u[8]&0x3f | 0x80
+	return fmt.Sprintf("urn:uuid:%x-%x-%x-%x-%x", u[0:4], u[4:6], u[6:8], u[8:10], u[10:16])
+}
@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"poppins/auth"
+	"poppins/domain"
+	"poppins/repository"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// Admins — множество telegram_id администраторов.
+type Admins map[string]bool
+
+// NewAdmins строит множество администраторов из списка telegram_id.
+func NewAdmins(ids []string) Admins {
+	a := make(Admins, len(ids))
+	for _, id := range ids {
+		a[id] = true
+	}
+	return a
+}
+
+// actorFromRequest определяет, от чьего имени выполняется запрос.
+// Telegram ID передаётся query-параметром telegram_id, как и в GET /ads/{id}.
+// Ключ со scope admin действует как администратор и без telegram_id.
+func actorFromRequest(r *http.Request, admins Admins) (domain.Actor, bool) {
+	telegramID := r.URL.Query().Get("telegram_id")
+	admin := admins[telegramID] || auth.FromContext(r.Context()).Has(auth.ScopeAdmin)
+	if telegramID == "" && !admin {
+		return domain.Actor{}, false
+	}
+	return domain.Actor{TelegramID: telegramID, Admin: admin}, true
+}
+
+// viewerFromRequest — telegram_id смотрящего для чтения каталога. Своим
+// telegram_id может назваться только ключ, действующий от имени
+// пользователей (bot, moderator, admin); ключ read видит каталог как
+// анонимный посетитель, иначе он получил бы телефоны и скрытые объявления
+// любого продавца.
+func viewerFromRequest(r *http.Request) string {
+	p := auth.FromContext(r.Context())
+	if !p.Has(auth.ScopeBot) && !p.Has(auth.ScopeModerator) {
+		return ""
+	}
+	return r.URL.Query().Get("telegram_id")
+}
+
+// principalName — имя API-ключа запроса, пустое для неаутентифицированных.
+func principalName(r *http.Request) string {
+	if p := auth.FromContext(r.Context()); p != nil {
+		return p.Name
+	}
+	return ""
+}
+
+// parseAdID достаёт ID объявления из пути.
+func parseAdID(r *http.Request) (int64, error) {
+	return strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+}
+
+// writeMutationError переводит ошибки MutateOwned в HTTP-статусы.
+func writeMutationError(w http.ResponseWriter, op string, err error) {
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		http.Error(w, "ad not found", http.StatusNotFound)
+	case errors.Is(err, repository.ErrForbidden):
+		http.Error(w, "access denied", http.StatusForbidden)
+	default:
+		log.Printf("%s error: %v", op, err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+	}
+}
+
+// denyBanned отвечает 403 и возвращает true, если у пользователя telegramID
+// действует бан. Теневой бан намеренно не проверяется: пользователь не должен
+// о нём узнать.
+func denyBanned(w http.ResponseWriter, sanctions *repository.SanctionRepo, telegramID string) bool {
+	ban, err := sanctions.ActiveBan(telegramID)
+	if err != nil {
+		log.Printf("ActiveBan error: %v", err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return true
+	}
+	if ban == nil {
+		return false
+	}
+	msg := "account is banned: " + ban.Reason
+	if ban.ExpiresAt != nil {
+		msg = fmt.Sprintf("account is banned until %s: %s", ban.ExpiresAt.Format("2006-01-02 15:04"), ban.Reason)
+	}
+	http.Error(w, msg, http.StatusForbidden)
+	return true
+}
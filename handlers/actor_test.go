@@ -0,0 +1,32 @@
+package handlers
+
+import (
+	"net/http/httptest"
+	"poppins/auth"
+	"testing"
+)
+
+func TestViewerFromRequest(t *testing.T) {
+	tests := []struct {
+		name   string
+		scopes []string
+		want   string
+	}{
+		{"no key", nil, ""},
+		{"read", []string{auth.ScopeRead}, ""},
+		{"bot", []string{auth.ScopeBot}, "42"},
+		{"moderator", []string{auth.ScopeModerator}, "42"},
+		{"admin", []string{auth.ScopeAdmin}, "42"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/ads/1?telegram_id=42", nil)
+			if tt.scopes != nil {
+				r = r.WithContext(auth.WithPrincipal(r.Context(), &auth.Principal{Name: "test", Scopes: tt.scopes}))
+			}
+			if got := viewerFromRequest(r); got != tt.want {
+				t.Errorf("viewerFromRequest = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -6,12 +6,18 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"log"
+	"mime/multipart"
 	"net/http"
 	"path/filepath"
+	"poppins/dedup"
 	"poppins/domain"
+	"poppins/moderation"
 	"poppins/repository"
+	"poppins/rules"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gorilla/mux"
@@ -22,10 +28,121 @@ type AdHandler struct {
 	Repo        *repository.AdRepo
 	MinioClient *minio.Client
 	Bucket      string
+	Admins      Admins
+	Policy      moderation.Policy
+	Rules       *rules.Engine
+	Duplicates  dedup.Policy
+	Sanctions   *repository.SanctionRepo
+	Stats       *repository.StatsRepo
 }
 
-func NewAdHandler(repo *repository.AdRepo, mc *minio.Client, bucket string) *AdHandler {
-	return &AdHandler{Repo: repo, MinioClient: mc, Bucket: bucket}
+func NewAdHandler(
+	repo *repository.AdRepo,
+	mc *minio.Client,
+	bucket string,
+	admins Admins,
+	policy moderation.Policy,
+	engine *rules.Engine,
+	duplicates dedup.Policy,
+	sanctions *repository.SanctionRepo,
+	stats *repository.StatsRepo,
+) *AdHandler {
+	return &AdHandler{
+		Repo:        repo,
+		MinioClient: mc,
+		Bucket:      bucket,
+		Admins:      admins,
+		Policy:      policy,
+		Rules:       engine,
+		Duplicates:  duplicates,
+		Sanctions:   sanctions,
+		Stats:       stats,
+	}
+}
+
+// rulesViolation — объявление отклонено автоматическими правилами.
+type rulesViolation struct {
+	Result rules.Result
+}
+
+func (e *rulesViolation) Error() string {
+	return "ad violates content rules: " + e.Result.Summary()
+}
+
+// writeRulesViolation отвечает 422 со списком сработавших правил.
+func writeRulesViolation(w http.ResponseWriter, v *rulesViolation) {
+	w.WriteHeader(http.StatusUnprocessableEntity)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"error": "ad violates content rules",
+		"hits":  v.Result.Hits,
+	})
+}
+
+// uploadPhoto кладёт фото пользователя telegramID в бакет и возвращает имя объекта.
+func (h *AdHandler) uploadPhoto(telegramID string, file multipart.File, fh *multipart.FileHeader) (string, error) {
+	objectName := fmt.Sprintf("ads/%s_%d%s",
+		telegramID, time.Now().UnixNano(), filepath.Ext(fh.Filename),
+	)
+	_, err := h.MinioClient.PutObject(
+		context.Background(),
+		h.Bucket,
+		objectName,
+		file,
+		fh.Size,
+		minio.PutObjectOptions{ContentType: fh.Header.Get("Content-Type")},
+	)
+	return objectName, err
+}
+
+// removePhoto удаляет фото объявления из бакета, если его загрузил сам владелец.
+func (h *AdHandler) removePhoto(ad *domain.Advertisement, url string) {
+	if url == "" || !photoOwnedBy(url, ad.TelegramID) {
+		return
+	}
+	if err := h.MinioClient.RemoveObject(
+		context.Background(), h.Bucket, photoObjectName(url), minio.RemoveObjectOptions{},
+	); err != nil {
+		log.Printf("remove photo of ad %d: %v", ad.ID, err)
+	}
+}
+
+// writeDuplicate отвечает 409 со ссылкой на оригинал.
+func writeDuplicate(w http.ResponseWriter, m *domain.DuplicateMatch) {
+	w.WriteHeader(http.StatusConflict)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"error":        "duplicate ad",
+		"duplicate_of": m.AdID,
+		"original_url": fmt.Sprintf("/ads/%d", m.AdID),
+		"match":        m,
+	})
+}
+
+// review решает по результату правил и политике премодерации, нужна ли
+// объявлению проверка модератором. nil — публикуется сразу.
+func (h *AdHandler) review(verdict rules.Result, category, trigger string) *domain.ModerationTask {
+	switch {
+	case verdict.Flag:
+		return &domain.ModerationTask{Trigger: domain.ModerationTriggerRules, Notes: verdict.Summary()}
+	case h.Policy.Requires(category):
+		return &domain.ModerationTask{Trigger: trigger}
+	}
+	return nil
+}
+
+// photoURL строит публичный URL фото по имени объекта в бакете.
+func photoURL(objectName string) string {
+	return "/ads/" + objectName
+}
+
+// photoObjectName — обратное преобразование к photoURL.
+func photoObjectName(url string) string {
+	return strings.TrimPrefix(url, "/ads/")
+}
+
+// photoOwnedBy сообщает, загружено ли фото пользователем telegramID:
+// Create кладёт объекты под именем ads/<telegram_id>_<nanos><ext>.
+func photoOwnedBy(url, telegramID string) bool {
+	return strings.HasPrefix(photoObjectName(url), "ads/"+telegramID+"_")
 }
 
 // Create создаёт новое объявление с загрузкой фотографий.
@@ -38,16 +155,23 @@ func NewAdHandler(repo *repository.AdRepo, mc *minio.Client, bucket string) *AdH
 // @Param        description  formData  string  true  "Описание объявления"
 // @Param        price        formData  int     true  "Цена объявления"
 // @Param        address      formData  string  true  "Адрес размещения объявления"
+// @Param        category     formData  string  false "Категория объявления"
 // @Param        photos       formData  []file  true  "Файлы фотографий объявления" collectionFormat(multi)
+// @Param        Idempotency-Key  header  string  false "Ключ идемпотентности: повтор с тем же ключом вернёт сохранённый ответ"
 // @Success      201          {object}  domain.Advertisement
+// @Success      200          {object}  domain.Advertisement  "Повтор слит в существующее объявление"
 // @Failure      400          {object}  map[string]string
+// @Failure      409          {object}  map[string]interface{}
+// @Failure      422          {object}  map[string]interface{}
 // @Failure      500          {object}  map[string]string
+// @Security     ApiKeyAuth
 // @Router       /ads [post]
 // handlers/ad.go
 func (h *AdHandler) Create(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
 	// Ограничим размер формы до 20 МБ
+	r.Body = http.MaxBytesReader(w, r.Body, 20<<20)
 	if err := r.ParseMultipartForm(20 << 20); err != nil {
 		http.Error(w, "cannot parse form: "+err.Error(), http.StatusBadRequest)
 		return
@@ -55,6 +179,9 @@ func (h *AdHandler) Create(w http.ResponseWriter, r *http.Request) {
 
 	// Читаем telegram_id
 	telegramID := r.FormValue("telegram_id")
+	if denyBanned(w, h.Sanctions, telegramID) {
+		return
+	}
 
 	title := r.FormValue("title")
 	description := r.FormValue("description")
@@ -64,6 +191,25 @@ func (h *AdHandler) Create(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	address := r.FormValue("address")
+	category := r.FormValue("category")
+
+	// Собираем объявление
+	ad := &domain.Advertisement{
+		TelegramID:  telegramID,
+		Title:       title,
+		Description: description,
+		Price:       int64(price),
+		Address:     address,
+		Category:    category,
+	}
+
+	// Проверяем текст до загрузки фото, чтобы не оставлять в хранилище сирот
+	verdict := h.Rules.Check(ad)
+	if verdict.Reject {
+		writeRulesViolation(w, &rulesViolation{Result: verdict})
+		return
+	}
+	ad.RiskScore = verdict.Score
 
 	// Обрабатываем одно фото
 	file, fh, err := r.FormFile("photo")
@@ -73,34 +219,60 @@ func (h *AdHandler) Create(w http.ResponseWriter, r *http.Request) {
 	}
 	defer file.Close()
 
-	objectName := fmt.Sprintf("ads/%s_%d%s",
-		telegramID, time.Now().UnixNano(), filepath.Ext(fh.Filename),
-	)
-	_, err = h.MinioClient.PutObject(
-		context.Background(),
-		h.Bucket,
-		objectName,
-		file,
-		fh.Size,
-		minio.PutObjectOptions{ContentType: fh.Header.Get("Content-Type")},
-	)
+	// Отпечатки для поиска повторных публикаций. Фото в неизвестном формате
+	// просто не участвует в сравнении.
+	textHash := int64(dedup.TextHash(title, description))
+	ad.TextHash = &textHash
+	if ph, err := dedup.ImageHash(file); err == nil {
+		photoHash := int64(ph)
+		ad.PhotoHash = &photoHash
+	} else {
+		log.Printf("photo hash for %s: %v", telegramID, err)
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		http.Error(w, "cannot read photo: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	review := h.review(verdict, category, domain.ModerationTriggerCreated)
+	var dup *domain.DuplicateMatch
+	if h.Duplicates.Mode != dedup.ModeOff {
+		dup, err = h.Repo.FindDuplicate(ad, time.Now().Add(-h.Duplicates.Window), dedup.PhotoThreshold, dedup.TextThreshold)
+		if err != nil {
+			log.Printf("FindDuplicate error: %v", err)
+			http.Error(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+	if dup != nil && h.Duplicates.Mode == dedup.ModeBlock {
+		writeDuplicate(w, dup)
+		return
+	}
+
+	objectName, err := h.uploadPhoto(telegramID, file, fh)
 	if err != nil {
 		http.Error(w, "upload error: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
-	photoURL := fmt.Sprintf("/ads/%s", objectName)
+	ad.PhotosUrls = photoURL(objectName)
 
-	// Собираем объявление
-	ad := &domain.Advertisement{
-		TelegramID:  telegramID,
-		Title:       title,
-		Description: description,
-		Price:       int64(price),
-		PhotosUrls:  photoURL,
-		Address:     address,
+	if dup != nil {
+		// Свой повтор сливаем в оригинал без поднятия в выдаче,
+		// чужой (или любой в режиме flag) отправляем модератору со ссылкой.
+		if h.Duplicates.Mode == dedup.ModeMerge && dup.SameSeller {
+			h.mergeInto(w, dup.AdID, ad, verdict)
+			return
+		}
+		ad.DuplicateOf = &dup.AdID
+		notes := fmt.Sprintf("похоже на объявление /ads/%d", dup.AdID)
+		if review != nil && review.Notes != "" {
+			notes = review.Notes + "; " + notes
+		}
+		review = &domain.ModerationTask{Trigger: domain.ModerationTriggerDuplicate, Notes: notes}
 	}
 
-	if err := h.Repo.Create(ad); err != nil {
+	// Подозрительные объявления и категории с премодерацией сначала попадают в очередь
+	if err := h.Repo.Create(ad, review); err != nil {
 		http.Error(w, "cannot save ad: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -109,16 +281,52 @@ func (h *AdHandler) Create(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(ad)
 }
 
-// Get возвращает объявление по его ID, но только если оно
-// принадлежит пользователю с данным telegram_id.
+// mergeInto переносит содержимое повторной публикации в оригинал originalID
+// того же продавца. Дата создания оригинала не меняется, так что повтор
+// не поднимает его в выдаче.
+func (h *AdHandler) mergeInto(w http.ResponseWriter, originalID int64, ad *domain.Advertisement, verdict rules.Result) {
+	var oldPhoto string
+	merged, err := h.Repo.MutateOwned(originalID, domain.Actor{TelegramID: ad.TelegramID}, domain.EventAdUpdated, func(tx *sql.Tx, cur *domain.Advertisement) error {
+		oldPhoto = cur.PhotosUrls
+		if ad.Category == "" {
+			ad.Category = cur.Category
+		}
+		if err := h.Repo.UpdateTx(tx, cur, ad); err != nil {
+			return err
+		}
+		if review := h.review(verdict, cur.Category, domain.ModerationTriggerEdited); review != nil {
+			return h.Repo.SubmitForModerationTx(tx, cur, review.Trigger, review.Notes)
+		}
+		return nil
+	})
+	if err != nil {
+		h.removePhoto(ad, ad.PhotosUrls)
+		writeMutationError(w, "merge duplicate", err)
+		return
+	}
+	if oldPhoto != merged.PhotosUrls {
+		h.removePhoto(merged, oldPhoto)
+	}
+
+	w.Header().Set("Location", fmt.Sprintf("/ads/%d", merged.ID))
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(merged)
+}
+
+// Get возвращает объявление по его ID. Владелец видит своё объявление
+// в любом статусе вместе с числом раскрытий контакта, остальные — только
+// опубликованное, с замаскированным телефоном; такой просмотр учитывается
+// в статистике (один раз в сутки на пользователя). telegram_id ключа read
+// не делает его ни владельцем, ни зрителем для статистики.
 // @Summary      Получить объявление
-// @Description  Возвращает детали объявления по переданному идентификатору и telegram_id.
+// @Description  Возвращает детали объявления по переданному идентификатору для пользователя telegram_id.
 // @Tags         ads
 // @Param        id            path      int  true  "ID объявления"
-// @Param        telegram_id   query     int  true  "Telegram ID пользователя"
+// @Param        telegram_id   query     int  true  "Telegram ID смотрящего; учитывается только для ключей bot, moderator и admin"
 // @Success      200  {object}  domain.Advertisement
 // @Failure      400  {object}  map[string]string
 // @Failure      404  {object}  map[string]string
+// @Security     ApiKeyAuth
 // @Router       /ads/{id} [get]
 func (h *AdHandler) Get(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
@@ -138,34 +346,53 @@ func (h *AdHandler) Get(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// 3) Запрашиваем объявление с проверкой принадлежности
-	ad, err := h.Repo.GetByIDAndTelegram(adID, telegramID)
+	// 3) Запрашиваем объявление с учётом того, кто смотрит
+	viewer := viewerFromRequest(r)
+	ad, err := h.Repo.GetForViewer(adID, viewer)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			http.Error(w, "ad not found or access denied", http.StatusNotFound)
+			http.Error(w, "ad not found", http.StatusNotFound)
 		} else {
-			log.Printf("GetByIDAndTelegram error: %v", err)
+			log.Printf("GetForViewer error: %v", err)
 			http.Error(w, "internal server error", http.StatusInternalServerError)
 		}
 		return
 	}
 
-	// 4) Отдаём JSON
+	// 4) Владельцу показываем, сколько раз открывали его контакт;
+	//    чужой просмотр учитываем в статистике
+	if viewer != "" && ad.TelegramID == viewer {
+		if err := h.Repo.FillContactReveals([]*domain.Advertisement{ad}); err != nil {
+			log.Printf("FillContactReveals error: %v", err)
+		}
+	} else {
+		ad.MaskContact()
+		if viewer != "" {
+			if err := h.Stats.RecordView(ad.ID, viewer); err != nil {
+				log.Printf("RecordView error: %v", err)
+			}
+		}
+	}
+
+	// 5) Отдаём JSON
 	w.WriteHeader(http.StatusOK)
 	if err := json.NewEncoder(w).Encode(ad); err != nil {
 		log.Printf("JSON encode error: %v", err)
 	}
 }
 
-// ListByTelegram возвращает все активные объявления пользователя по его telegram_id.
+// ListByTelegram возвращает все неархивные объявления пользователя по его telegram_id.
+// Объявления на модерации и отклонённые видит только сам владелец.
 // @Summary      Список объявлений пользователя
 // @Description  Возвращает массив объявлений, принадлежащих пользователю с переданным telegram_id.
 // @Tags         ads
-// @Param        telegram_id   query     int  true  "Telegram ID пользователя"
+// @Param        telegramId    path      int  true   "Telegram ID пользователя"
+// @Param        telegram_id   query     int  false  "Telegram ID смотрящего; учитывается только для ключей bot, moderator и admin"
 // @Success      200  {array}   domain.Advertisement
 // @Failure      400  {object}  map[string]string
 // @Failure      500  {object}  map[string]string
-// @Router       /ads [get]
+// @Security     ApiKeyAuth
+// @Router       /users/{telegramId}/ads [get]
 func (h *AdHandler) ListByTelegram(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
@@ -178,7 +405,8 @@ func (h *AdHandler) ListByTelegram(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// 2) Запрашиваем объявления в репозитории
-	ads, err := h.Repo.GetByTelegramID(telegramID)
+	viewer := viewerFromRequest(r)
+	ads, err := h.Repo.GetByTelegramID(telegramID, viewer)
 	if err != nil {
 		http.Error(w, "cannot fetch ads: "+err.Error(), http.StatusInternalServerError)
 		return
@@ -189,102 +417,260 @@ func (h *AdHandler) ListByTelegram(w http.ResponseWriter, r *http.Request) {
 		ads = []*domain.Advertisement{}
 	}
 
-	// 4) Сериализуем в JSON
+	// 4) Владелец видит свой телефон и число раскрытий, остальные — маску
+	if viewer != "" && viewer == telegramID {
+		if err := h.Repo.FillContactReveals(ads); err != nil {
+			log.Printf("FillContactReveals error: %v", err)
+		}
+	} else {
+		for _, ad := range ads {
+			ad.MaskContact()
+		}
+	}
+
+	// 5) Сериализуем в JSON
 	w.WriteHeader(http.StatusOK)
 	if err := json.NewEncoder(w).Encode(ads); err != nil {
 		log.Printf("JSON encode error: %v", err)
 	}
 }
 
+// parseAdFilter разбирает параметры поиска объявлений из query.
+func parseAdFilter(r *http.Request) (repository.AdFilter, error) {
+	q := r.URL.Query()
+	f := repository.AdFilter{Keyword: q.Get("search"), Category: q.Get("category"), Viewer: viewerFromRequest(r)}
+	if mp := q.Get("max_price"); mp != "" {
+		p, err := strconv.ParseInt(mp, 10, 64)
+		if err != nil {
+			return f, errors.New("invalid max_price")
+		}
+		f.MaxPrice = p
+	}
+	return f, nil
+}
+
 // Search обрабатывает поиск объявлений.
 // @Summary      Поиск объявлений
-// @Description  Ищет объявления по ключевому слову в заголовке и/или по максимальной цене.
+// @Description  Ищет опубликованные объявления по ключевому слову в заголовке, максимальной цене и категории. Продвигаемые объявления чередуются с обычными и помечены promoted; закреплённые в категории идут первыми.
 // @Tags         ads
 // @Param        search     query     string  false  "Ключевое слово для поиска"
 // @Param        max_price  query     int     false  "Максимальная цена"
+// @Param        category   query     string  false  "Категория"
+// @Param        telegram_id query    string  false  "Telegram ID смотрящего; учитывается только для ключей bot, moderator и admin"
 // @Success      200        {array}   domain.Advertisement
 // @Failure      400        {object}  map[string]string
 // @Failure      500        {object}  map[string]string
+// @Security     ApiKeyAuth
 // @Router       /ads [get]
 func (h *AdHandler) Search(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
-	q := r.URL.Query()
-	keyword := q.Get("search")
-	var maxPrice int64
-	if mp := q.Get("max_price"); mp != "" {
-		if p, err := strconv.ParseInt(mp, 10, 64); err == nil {
-			maxPrice = p
-		} else {
-			http.Error(w, "invalid max_price", http.StatusBadRequest)
-			return
-		}
+	f, err := parseAdFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
-	ads, err := h.Repo.Search(keyword, maxPrice)
+	ads, err := h.Repo.Search(f)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	for _, ad := range ads {
+		ad.MaskContact()
+	}
 	json.NewEncoder(w).Encode(ads)
 }
 
+// RevealContact выдаёт контакт продавца покупателю.
+// @Summary      Показать контакт продавца
+// @Description  Возвращает контакт по предпочтению продавца: phone — телефон, telegram — Telegram ID, chat — только переписка через бота. Каждое раскрытие записывается в журнал; частота ограничена.
+// @Tags         ads
+// @Produce      json
+// @Param        id           path      int     true  "ID объявления"
+// @Param        telegram_id  query     string  true  "Telegram ID покупателя"
+// @Success      200  {object}  domain.Contact
+// @Failure      400  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      429  {object}  map[string]string
+// @Security     ApiKeyAuth
+// @Router       /ads/{id}/contact [post]
+func (h *AdHandler) RevealContact(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	adID, err := parseAdID(r)
+	if err != nil {
+		http.Error(w, "invalid ad id: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	telegramID := r.URL.Query().Get("telegram_id")
+	if telegramID == "" {
+		http.Error(w, "missing telegram_id", http.StatusBadRequest)
+		return
+	}
+	if denyBanned(w, h.Sanctions, telegramID) {
+		return
+	}
+
+	contact, err := h.Repo.RevealContact(adID, telegramID, principalName(r))
+	if errors.Is(err, repository.ErrNotFound) {
+		http.Error(w, "ad or user not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("RevealContact error: %v", err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(contact)
+}
+
 // Update изменяет существующее объявление.
 // @Summary      Обновить объявление
-// @Description  Обновляет поля объявления по его ID.
+// @Description  Обновляет поля объявления по его ID. Доступно только владельцу или администратору.
 // @Tags         ads
 // @Accept       json
-// @Param        id   path      int                    true  "ID объявления"
-// @Param        ad   body      domain.Advertisement   true  "Объект объявления"
+// @Param        id           path      int                    true  "ID объявления"
+// @Param        telegram_id  query     string                 true  "Telegram ID владельца"
+// @Param        ad           body      domain.Advertisement   true  "Объект объявления"
 // @Success      200  {object}  domain.Advertisement
 // @Failure      400  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      422  {object}  map[string]interface{}
 // @Failure      500  {object}  map[string]string
+// @Security     ApiKeyAuth
 // @Router       /ads/{id} [put]
 func (h *AdHandler) Update(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
+	id, err := parseAdID(r)
+	if err != nil {
+		http.Error(w, "invalid ad id: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	actor, ok := actorFromRequest(r, h.Admins)
+	if !ok {
+		http.Error(w, "missing telegram_id", http.StatusBadRequest)
+		return
+	}
+	if !actor.Admin && denyBanned(w, h.Sanctions, actor.TelegramID) {
+		return
+	}
 	var ad domain.Advertisement
 	if err := json.NewDecoder(r.Body).Decode(&ad); err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	id, _ := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
 	ad.ID = id
-	if err := h.Repo.Update(&ad); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+
+	updated, err := h.Repo.MutateOwned(id, actor, domain.EventAdUpdated, func(tx *sql.Tx, cur *domain.Advertisement) error {
+		// Подменить фото можно только на файл, загруженный самим владельцем.
+		if ad.PhotosUrls != "" && ad.PhotosUrls != cur.PhotosUrls && !photoOwnedBy(ad.PhotosUrls, cur.TelegramID) {
+			return repository.ErrForbidden
+		}
+		if ad.Category == "" {
+			ad.Category = cur.Category
+		}
+		verdict := h.Rules.Check(&ad)
+		if verdict.Reject {
+			return &rulesViolation{Result: verdict}
+		}
+		ad.RiskScore = verdict.Score
+		textHash := int64(dedup.TextHash(ad.Title, ad.Description))
+		ad.TextHash = &textHash
+
+		if err := h.Repo.UpdateTx(tx, cur, &ad); err != nil {
+			return err
+		}
+		// Правка уходит на повторную проверку по правилам и в категориях
+		// с премодерацией, а отклонённое объявление — всегда.
+		review := h.review(verdict, cur.Category, domain.ModerationTriggerEdited)
+		if review == nil && cur.Status == domain.AdStatusRejected {
+			review = &domain.ModerationTask{Trigger: domain.ModerationTriggerEdited}
+		}
+		if review != nil {
+			return h.Repo.SubmitForModerationTx(tx, cur, review.Trigger, review.Notes)
+		}
+		return nil
+	})
+	var violation *rulesViolation
+	if errors.As(err, &violation) {
+		writeRulesViolation(w, violation)
 		return
 	}
-	json.NewEncoder(w).Encode(ad)
+	if err != nil {
+		writeMutationError(w, "Update", err)
+		return
+	}
+	json.NewEncoder(w).Encode(updated)
 }
 
-// Delete удаляет объявление по ID.
+// Delete удаляет объявление по ID вместе с его фото.
 // @Summary      Удалить объявление
-// @Description  Удаляет объявление из БД по его идентификатору.
+// @Description  Удаляет объявление из БД и фото из хранилища. Доступно только владельцу или администратору.
 // @Tags         ads
-// @Param        id   path      int  true  "ID объявления"
+// @Param        id           path      int     true  "ID объявления"
+// @Param        telegram_id  query     string  true  "Telegram ID владельца"
 // @Success      204  {string}  string  "No Content"
+// @Failure      400  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
 // @Failure      404  {object}  map[string]string
+// @Security     ApiKeyAuth
 // @Router       /ads/{id} [delete]
 func (h *AdHandler) Delete(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
-	id, _ := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
-	if err := h.Repo.Delete(id); err != nil {
-		http.Error(w, err.Error(), http.StatusNotFound)
+	id, err := parseAdID(r)
+	if err != nil {
+		http.Error(w, "invalid ad id: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	actor, ok := actorFromRequest(r, h.Admins)
+	if !ok {
+		http.Error(w, "missing telegram_id", http.StatusBadRequest)
+		return
+	}
+	if !actor.Admin && denyBanned(w, h.Sanctions, actor.TelegramID) {
+		return
+	}
+	ad, err := h.Repo.Delete(id, actor)
+	if err != nil {
+		writeMutationError(w, "Delete", err)
 		return
 	}
+
+	// Фото удаляем после коммита: висящий объект лучше, чем объявление без фото.
+	h.removePhoto(ad, ad.PhotosUrls)
 	w.WriteHeader(http.StatusNoContent)
 }
 
 // Archive архивирует объявление (ставит флаг archived = true).
 // @Summary      Архивировать объявление
-// @Description  Помечает объявление как архивное (archived = true).
+// @Description  Помечает объявление как архивное (archived = true). Доступно только владельцу или администратору.
 // @Tags         ads
-// @Param        id   path      int  true  "ID объявления"
+// @Param        id           path      int     true  "ID объявления"
+// @Param        telegram_id  query     string  true  "Telegram ID владельца"
 // @Success      204  {string}  string  "No Content"
+// @Failure      400  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
 // @Failure      500  {object}  map[string]string
-// @Router       /ads/{id}/archive [post]
+// @Security     ApiKeyAuth
+// @Router       /ads/{id}/archive [patch]
 func (h *AdHandler) Archive(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
-	id, _ := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
-	if err := h.Repo.Archive(id); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	id, err := parseAdID(r)
+	if err != nil {
+		http.Error(w, "invalid ad id: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	actor, ok := actorFromRequest(r, h.Admins)
+	if !ok {
+		http.Error(w, "missing telegram_id", http.StatusBadRequest)
+		return
+	}
+	if !actor.Admin && denyBanned(w, h.Sanctions, actor.TelegramID) {
+		return
+	}
+	if _, err := h.Repo.Archive(id, actor); err != nil {
+		writeMutationError(w, "Archive", err)
 		return
 	}
 	w.WriteHeader(http.StatusNoContent)
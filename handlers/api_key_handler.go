@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"poppins/auth"
+	"poppins/domain"
+	"poppins/repository"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+type APIKeyHandler struct {
+	Repo *repository.APIKeyRepo
+}
+
+func NewAPIKeyHandler(repo *repository.APIKeyRepo) *APIKeyHandler {
+	return &APIKeyHandler{Repo: repo}
+}
+
+// CreateAPIKeyRequest — payload для выпуска ключа
+type CreateAPIKeyRequest struct {
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+}
+
+// CreateAPIKeyResponse — выпущенный ключ; поле key больше нигде не отдаётся.
+type CreateAPIKeyResponse struct {
+	*domain.APIKey
+	Key string `json:"key"`
+}
+
+// Create выпускает новый API-ключ.
+// @Summary      Выпустить API-ключ
+// @Description  Создаёт ключ с указанными scope'ами (read, bot, moderator, admin). Открытый ключ возвращается только в этом ответе.
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        key  body      CreateAPIKeyRequest  true  "Имя и scope'ы ключа"
+// @Success      201  {object}  CreateAPIKeyResponse
+// @Failure      400  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Security     ApiKeyAuth
+// @Router       /admin/api-keys [post]
+func (h *APIKeyHandler) Create(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	var req CreateAPIKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.Name == "" || len(req.Scopes) == 0 {
+		http.Error(w, "name and scopes are required", http.StatusBadRequest)
+		return
+	}
+	for _, s := range req.Scopes {
+		if !auth.ValidScope(s) {
+			http.Error(w, "unknown scope: "+s, http.StatusBadRequest)
+			return
+		}
+	}
+
+	plain, hash, err := auth.GenerateKey()
+	if err != nil {
+		log.Printf("GenerateKey error: %v", err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	key := &domain.APIKey{Name: req.Name, Prefix: auth.DisplayPrefix(plain), Scopes: req.Scopes}
+	if err := h.Repo.Create(key, hash); err != nil {
+		log.Printf("APIKeyRepo.Create error: %v", err)
+		http.Error(w, "could not create api key", http.StatusInternalServerError)
+		return
+	}
+	log.Printf("api key %d (%s) issued by %s", key.ID, key.Name, auth.FromContext(r.Context()).Name)
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(CreateAPIKeyResponse{APIKey: key, Key: plain})
+}
+
+// List возвращает все выпущенные ключи без секретов.
+// @Summary      Список API-ключей
+// @Tags         admin
+// @Produce      json
+// @Success      200  {array}   domain.APIKey
+// @Failure      500  {object}  map[string]string
+// @Security     ApiKeyAuth
+// @Router       /admin/api-keys [get]
+func (h *APIKeyHandler) List(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	keys, err := h.Repo.List()
+	if err != nil {
+		log.Printf("APIKeyRepo.List error: %v", err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	if keys == nil {
+		keys = []*domain.APIKey{}
+	}
+	json.NewEncoder(w).Encode(keys)
+}
+
+// Revoke отзывает ключ.
+// @Summary      Отозвать API-ключ
+// @Tags         admin
+// @Produce      json
+// @Param        id   path      int  true  "ID ключа"
+// @Success      200  {object}  domain.APIKey
+// @Failure      400  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Security     ApiKeyAuth
+// @Router       /admin/api-keys/{id} [delete]
+func (h *APIKeyHandler) Revoke(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "invalid key id: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	key, err := h.Repo.Revoke(id)
+	if errors.Is(err, repository.ErrNotFound) {
+		http.Error(w, "api key not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("APIKeyRepo.Revoke error: %v", err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	log.Printf("api key %d (%s) revoked by %s", key.ID, key.Name, auth.FromContext(r.Context()).Name)
+	json.NewEncoder(w).Encode(key)
+}
@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"io"
+	"log"
+	"net/http"
+	"poppins/catalog"
+	"poppins/domain"
+	"poppins/repository"
+	"strings"
+)
+
+type CatalogHandler struct {
+	Ads      *repository.AdRepo
+	Taxonomy *catalog.Taxonomy
+	// Shop — заголовок фидов; пустые URL и AdURL заполняются из BaseURL.
+	Shop    catalog.Shop
+	BaseURL string
+}
+
+func NewCatalogHandler(ads *repository.AdRepo, taxonomy *catalog.Taxonomy, shop catalog.Shop, baseURL string) *CatalogHandler {
+	return &CatalogHandler{Ads: ads, Taxonomy: taxonomy, Shop: shop, BaseURL: strings.TrimRight(baseURL, "/")}
+}
+
+// YML отдаёт объявления в формате Яндекс YML.
+// @Summary      Фид объявлений YML
+// @Description  Все опубликованные объявления по тем же фильтрам, что и GET /ads, в формате Яндекс YML. Категории сопоставляются с деревом категорий фида; телефон продавца в фид не попадает. Ответ передаётся по мере чтения из БД.
+// @Tags         feeds
+// @Produce      application/xml
+// @Param        search     query  string  false  "Ключевое слово в заголовке"
+// @Param        max_price  query  int     false  "Максимальная цена"
+// @Param        category   query  string  false  "Категория"
+// @Success      200
+// @Failure      400  {object}  map[string]string
+// @Security     ApiKeyAuth
+// @Router       /feeds/ads.yml [get]
+func (h *CatalogHandler) YML(w http.ResponseWriter, r *http.Request) {
+	h.serve(w, r, catalog.WriteYML)
+}
+
+// Avito отдаёт объявления в формате автозагрузки Авито.
+// @Summary      Фид объявлений Авито
+// @Description  Опубликованные объявления по тем же фильтрам, что и GET /ads, в XML-формате автозагрузки Авито. Объявления категорий, для которых нет сопоставления с категориями Авито, пропускаются.
+// @Tags         feeds
+// @Produce      application/xml
+// @Param        search     query  string  false  "Ключевое слово в заголовке"
+// @Param        max_price  query  int     false  "Максимальная цена"
+// @Param        category   query  string  false  "Категория"
+// @Success      200
+// @Failure      400  {object}  map[string]string
+// @Security     ApiKeyAuth
+// @Router       /feeds/avito.xml [get]
+func (h *CatalogHandler) Avito(w http.ResponseWriter, r *http.Request) {
+	h.serve(w, r, catalog.WriteAvito)
+}
+
+func (h *CatalogHandler) serve(w http.ResponseWriter, r *http.Request,
+	write func(io.Writer, catalog.Shop, *catalog.Taxonomy, catalog.Source) error) {
+	f, err := parseAdFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	// Фид читают сторонние площадки: в него попадает то же, что видит анонимный поиск
+	f.Viewer = ""
+
+	base := publicBaseURL(h.BaseURL, r)
+	shop := h.Shop
+	if shop.URL == "" {
+		shop.URL = base
+	}
+	if shop.AdURL == "" {
+		shop.AdURL = base + "/ads/%d"
+	}
+	shop.PhotoBase = base
+
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	err = write(w, shop, h.Taxonomy, func(fn func(*domain.Advertisement) error) error {
+		return h.Ads.Each(f, fn)
+	})
+	if err != nil {
+		// Заголовки уже отправлены: клиент получит оборванный, невалидный XML
+		log.Printf("catalog feed %s: %v", r.URL.Path, err)
+	}
+}
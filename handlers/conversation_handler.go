@@ -0,0 +1,328 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"poppins/domain"
+	"poppins/repository"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gorilla/mux"
+)
+
+// maxMessageLen — максимальная длина сообщения в символах.
+const maxMessageLen = 4000
+
+type ConversationHandler struct {
+	Repo      *repository.ConversationRepo
+	Sanctions *repository.SanctionRepo
+}
+
+func NewConversationHandler(repo *repository.ConversationRepo, sanctions *repository.SanctionRepo) *ConversationHandler {
+	return &ConversationHandler{Repo: repo, Sanctions: sanctions}
+}
+
+// SendMessageRequest — текст сообщения
+type SendMessageRequest struct {
+	Text string `json:"text"`
+}
+
+// MarkReadRequest — до какого сообщения собеседника переписка прочитана
+type MarkReadRequest struct {
+	UpToID int64 `json:"up_to_id"`
+}
+
+// Start начинает переписку с продавцом по объявлению.
+// @Summary      Написать продавцу
+// @Description  Открывает переписку по объявлению (или продолжает начатую) и пересылает сообщение продавцу через бота. Telegram-аккаунты участников друг другу не раскрываются.
+// @Tags         conversations
+// @Accept       json
+// @Produce      json
+// @Param        id           path      int                 true  "ID объявления"
+// @Param        telegram_id  query     string              true  "Telegram ID покупателя"
+// @Param        message      body      SendMessageRequest  true  "Сообщение"
+// @Success      201  {object}  domain.Message
+// @Failure      400  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Security     ApiKeyAuth
+// @Router       /ads/{id}/conversations [post]
+func (h *ConversationHandler) Start(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	adID, err := parseAdID(r)
+	if err != nil {
+		http.Error(w, "invalid ad id: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	telegramID, text, ok := h.decodeMessage(w, r)
+	if !ok {
+		return
+	}
+
+	d, err := h.Repo.Start(adID, telegramID, text)
+	if errors.Is(err, repository.ErrForbidden) {
+		http.Error(w, "cannot message own ad", http.StatusForbidden)
+		return
+	}
+	if err != nil {
+		writeConversationError(w, "ConversationRepo.Start", err)
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(d.Message)
+}
+
+// Send отправляет сообщение собеседнику.
+// @Summary      Отправить сообщение
+// @Tags         conversations
+// @Accept       json
+// @Produce      json
+// @Param        id           path      int                 true  "ID переписки"
+// @Param        telegram_id  query     string              true  "Telegram ID участника"
+// @Param        message      body      SendMessageRequest  true  "Сообщение"
+// @Success      201  {object}  domain.Message
+// @Failure      400  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Security     ApiKeyAuth
+// @Router       /conversations/{id}/messages [post]
+func (h *ConversationHandler) Send(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	id, ok := parseConversationID(w, r)
+	if !ok {
+		return
+	}
+	telegramID, text, ok := h.decodeMessage(w, r)
+	if !ok {
+		return
+	}
+
+	d, err := h.Repo.Send(id, telegramID, text)
+	if err != nil {
+		writeConversationError(w, "ConversationRepo.Send", err)
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(d.Message)
+}
+
+// ListByTelegram возвращает переписки пользователя.
+// @Summary      Переписки пользователя
+// @Description  Переписки, где пользователь покупатель или продавец, с последним сообщением и числом непрочитанных.
+// @Tags         conversations
+// @Produce      json
+// @Param        telegramId  path      string  true  "Telegram ID пользователя"
+// @Success      200  {array}   domain.Conversation
+// @Failure      500  {object}  map[string]string
+// @Security     ApiKeyAuth
+// @Router       /users/{telegramId}/conversations [get]
+func (h *ConversationHandler) ListByTelegram(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	list, err := h.Repo.ListByTelegram(mux.Vars(r)["telegramId"])
+	if err != nil {
+		log.Printf("ConversationRepo.ListByTelegram error: %v", err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	if list == nil {
+		list = []*domain.Conversation{}
+	}
+	json.NewEncoder(w).Encode(list)
+}
+
+// Messages возвращает сообщения переписки.
+// @Summary      Сообщения переписки
+// @Description  Свежие первыми. read_at у своих сообщений — когда их прочитал собеседник.
+// @Tags         conversations
+// @Produce      json
+// @Param        id           path      int     true   "ID переписки"
+// @Param        telegram_id  query     string  true   "Telegram ID участника"
+// @Param        before_id    query     int     false  "Страница сообщений старше этого ID"
+// @Param        limit        query     int     false  "Максимум сообщений (по умолчанию 50)"
+// @Success      200  {array}   domain.Message
+// @Failure      400  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Security     ApiKeyAuth
+// @Router       /conversations/{id}/messages [get]
+func (h *ConversationHandler) Messages(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	id, ok := parseConversationID(w, r)
+	if !ok {
+		return
+	}
+	q := r.URL.Query()
+	telegramID := q.Get("telegram_id")
+	if telegramID == "" {
+		http.Error(w, "missing telegram_id", http.StatusBadRequest)
+		return
+	}
+	var beforeID int64
+	if b := q.Get("before_id"); b != "" {
+		n, err := strconv.ParseInt(b, 10, 64)
+		if err != nil || n <= 0 {
+			http.Error(w, "invalid before_id", http.StatusBadRequest)
+			return
+		}
+		beforeID = n
+	}
+	limit := 50
+	if l := q.Get("limit"); l != "" {
+		n, err := strconv.Atoi(l)
+		if err != nil || n <= 0 || n > 200 {
+			http.Error(w, "invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+
+	list, err := h.Repo.Messages(id, telegramID, beforeID, limit)
+	if err != nil {
+		writeConversationError(w, "ConversationRepo.Messages", err)
+		return
+	}
+	if list == nil {
+		list = []*domain.Message{}
+	}
+	json.NewEncoder(w).Encode(list)
+}
+
+// MarkRead отмечает сообщения собеседника прочитанными.
+// @Summary      Отметить прочитанным
+// @Tags         conversations
+// @Accept       json
+// @Produce      json
+// @Param        id           path      int              true   "ID переписки"
+// @Param        telegram_id  query     string           true   "Telegram ID участника"
+// @Param        body         body      MarkReadRequest  false  "up_to_id: 0 или не указан — все сообщения"
+// @Success      200  {object}  map[string]int64
+// @Failure      400  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Security     ApiKeyAuth
+// @Router       /conversations/{id}/read [post]
+func (h *ConversationHandler) MarkRead(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	id, ok := parseConversationID(w, r)
+	if !ok {
+		return
+	}
+	telegramID := r.URL.Query().Get("telegram_id")
+	if telegramID == "" {
+		http.Error(w, "missing telegram_id", http.StatusBadRequest)
+		return
+	}
+	var req MarkReadRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "invalid body: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	n, err := h.Repo.MarkRead(id, telegramID, req.UpToID)
+	if err != nil {
+		writeConversationError(w, "ConversationRepo.MarkRead", err)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]int64{"marked": n})
+}
+
+// Block запрещает собеседнику писать в переписку.
+// @Summary      Заблокировать собеседника
+// @Tags         conversations
+// @Param        id           path      int     true  "ID переписки"
+// @Param        telegram_id  query     string  true  "Telegram ID участника"
+// @Success      204
+// @Failure      403  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Security     ApiKeyAuth
+// @Router       /conversations/{id}/block [post]
+func (h *ConversationHandler) Block(w http.ResponseWriter, r *http.Request) {
+	h.setBlocked(w, r, true)
+}
+
+// Unblock снимает блокировку собеседника.
+// @Summary      Разблокировать собеседника
+// @Tags         conversations
+// @Param        id           path      int     true  "ID переписки"
+// @Param        telegram_id  query     string  true  "Telegram ID участника"
+// @Success      204
+// @Failure      403  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Router       /conversations/{id}/block [delete]
+func (h *ConversationHandler) Unblock(w http.ResponseWriter, r *http.Request) {
+	h.setBlocked(w, r, false)
+}
+
+func (h *ConversationHandler) setBlocked(w http.ResponseWriter, r *http.Request, blocked bool) {
+	id, ok := parseConversationID(w, r)
+	if !ok {
+		return
+	}
+	telegramID := r.URL.Query().Get("telegram_id")
+	if telegramID == "" {
+		http.Error(w, "missing telegram_id", http.StatusBadRequest)
+		return
+	}
+	if err := h.Repo.SetBlocked(id, telegramID, blocked); err != nil {
+		writeConversationError(w, "ConversationRepo.SetBlocked", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// decodeMessage достаёт отправителя и текст сообщения, проверяя бан.
+func (h *ConversationHandler) decodeMessage(w http.ResponseWriter, r *http.Request) (telegramID, text string, ok bool) {
+	telegramID = r.URL.Query().Get("telegram_id")
+	if telegramID == "" {
+		http.Error(w, "missing telegram_id", http.StatusBadRequest)
+		return "", "", false
+	}
+	if denyBanned(w, h.Sanctions, telegramID) {
+		return "", "", false
+	}
+	var req SendMessageRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid body: "+err.Error(), http.StatusBadRequest)
+		return "", "", false
+	}
+	text = strings.TrimSpace(req.Text)
+	if text == "" {
+		http.Error(w, "text is required", http.StatusBadRequest)
+		return "", "", false
+	}
+	if utf8.RuneCountInString(text) > maxMessageLen {
+		http.Error(w, "text is too long", http.StatusBadRequest)
+		return "", "", false
+	}
+	return telegramID, text, true
+}
+
+func parseConversationID(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "invalid conversation id: "+err.Error(), http.StatusBadRequest)
+		return 0, false
+	}
+	return id, true
+}
+
+// writeConversationError переводит ошибки ConversationRepo в HTTP-статусы.
+func writeConversationError(w http.ResponseWriter, op string, err error) {
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		http.Error(w, "not found", http.StatusNotFound)
+	case errors.Is(err, repository.ErrForbidden):
+		http.Error(w, "access denied", http.StatusForbidden)
+	case errors.Is(err, repository.ErrBlocked):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	default:
+		log.Printf("%s error: %v", op, err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"poppins/domain"
+	"poppins/export"
+	"poppins/repository"
+
+	"github.com/gorilla/mux"
+)
+
+type ExportHandler struct {
+	Repo  *repository.ExportRepo
+	Store *export.Store
+}
+
+func NewExportHandler(repo *repository.ExportRepo, store *export.Store) *ExportHandler {
+	return &ExportHandler{Repo: repo, Store: store}
+}
+
+// Get возвращает выгрузку персональных данных пользователя.
+// @Summary      Выгрузка персональных данных
+// @Description  Если готовой выгрузки нет, ставит новую в очередь и отвечает 202; архив собирается в фоне, о готовности пользователь получит сообщение от бота. Готовая выгрузка (200) содержит download_url — ссылку на ZIP с профилем, всеми объявлениями (включая архивные) и их фото, избранным, перепиской и отзывами; ссылка действует до expires_at.
+// @Tags         users
+// @Produce      json
+// @Param        telegramId  path  string  true  "Telegram ID пользователя"
+// @Success      200  {object}  domain.DataExport
+// @Success      202  {object}  domain.DataExport
+// @Failure      404  {object}  map[string]string
+// @Security     ApiKeyAuth
+// @Router       /users/{telegramId}/export [get]
+func (h *ExportHandler) Get(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	e, _, err := h.Repo.Request(mux.Vars(r)["telegramId"])
+	if errors.Is(err, repository.ErrNotFound) {
+		http.Error(w, "user not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("ExportRepo.Request error: %v", err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	if e.Status != domain.ExportReady {
+		w.Header().Set("Retry-After", "30")
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(e)
+		return
+	}
+	if e.DownloadURL, err = h.Store.DownloadURL(r.Context(), e); err != nil {
+		log.Printf("export %d download url: %v", e.ID, err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(e)
+}
@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"poppins/domain"
+	"poppins/repository"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+type FavoriteHandler struct {
+	Repo      *repository.FavoriteRepo
+	Sanctions *repository.SanctionRepo
+}
+
+func NewFavoriteHandler(repo *repository.FavoriteRepo, sanctions *repository.SanctionRepo) *FavoriteHandler {
+	return &FavoriteHandler{Repo: repo, Sanctions: sanctions}
+}
+
+// Add добавляет объявление в избранное.
+// @Summary      Добавить в избранное
+// @Tags         favorites
+// @Param        telegramId  path  string  true  "Telegram ID пользователя"
+// @Param        adId        path  int     true  "ID объявления"
+// @Success      204
+// @Failure      400  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Security     ApiKeyAuth
+// @Router       /users/{telegramId}/favorites/{adId} [put]
+func (h *FavoriteHandler) Add(w http.ResponseWriter, r *http.Request) {
+	telegramID, adID, ok := parseFavorite(w, r)
+	if !ok {
+		return
+	}
+	if denyBanned(w, h.Sanctions, telegramID) {
+		return
+	}
+	err := h.Repo.Add(telegramID, adID)
+	if errors.Is(err, repository.ErrNotFound) {
+		http.Error(w, "ad or user not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("FavoriteRepo.Add error: %v", err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Remove убирает объявление из избранного.
+// @Summary      Убрать из избранного
+// @Tags         favorites
+// @Param        telegramId  path  string  true  "Telegram ID пользователя"
+// @Param        adId        path  int     true  "ID объявления"
+// @Success      204
+// @Failure      400  {object}  map[string]string
+// @Security     ApiKeyAuth
+// @Router       /users/{telegramId}/favorites/{adId} [delete]
+func (h *FavoriteHandler) Remove(w http.ResponseWriter, r *http.Request) {
+	telegramID, adID, ok := parseFavorite(w, r)
+	if !ok {
+		return
+	}
+	if err := h.Repo.Remove(telegramID, adID); err != nil {
+		log.Printf("FavoriteRepo.Remove error: %v", err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// List возвращает избранные объявления пользователя.
+// @Summary      Избранное пользователя
+// @Tags         favorites
+// @Produce      json
+// @Param        telegramId  path  string  true  "Telegram ID пользователя"
+// @Success      200  {array}   domain.Advertisement
+// @Failure      500  {object}  map[string]string
+// @Security     ApiKeyAuth
+// @Router       /users/{telegramId}/favorites [get]
+func (h *FavoriteHandler) List(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	ads, err := h.Repo.List(mux.Vars(r)["telegramId"])
+	if err != nil {
+		log.Printf("FavoriteRepo.List error: %v", err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	if ads == nil {
+		ads = []*domain.Advertisement{}
+	}
+	for _, ad := range ads {
+		ad.MaskContact()
+	}
+	json.NewEncoder(w).Encode(ads)
+}
+
+func parseFavorite(w http.ResponseWriter, r *http.Request) (string, int64, bool) {
+	vars := mux.Vars(r)
+	adID, err := strconv.ParseInt(vars["adId"], 10, 64)
+	if err != nil {
+		http.Error(w, "invalid ad id: "+err.Error(), http.StatusBadRequest)
+		return "", 0, false
+	}
+	return vars["telegramId"], adID, true
+}
@@ -0,0 +1,190 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"poppins/domain"
+	"poppins/feed"
+	"poppins/repository"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// feedSize — сколько последних объявлений попадает в ленту.
+const feedSize = 50
+
+type FeedHandler struct {
+	Ads *repository.AdRepo
+	// BaseURL — внешний адрес API для ссылок в лентах; пустой — берётся из
+	// запроса.
+	BaseURL string
+}
+
+func NewFeedHandler(ads *repository.AdRepo, baseURL string) *FeedHandler {
+	return &FeedHandler{Ads: ads, BaseURL: strings.TrimRight(baseURL, "/")}
+}
+
+// Search отдаёт Atom-ленту объявлений по параметрам поиска.
+// @Summary      Atom-лента поиска
+// @Description  Последние 50 объявлений по тем же фильтрам, что и GET /ads, новые первыми. Поддерживает If-None-Match и If-Modified-Since. Ключ API не нужен.
+// @Tags         feeds
+// @Produce      application/atom+xml
+// @Param        search     query  string  false  "Ключевое слово в заголовке"
+// @Param        max_price  query  int     false  "Максимальная цена"
+// @Param        category   query  string  false  "Категория"
+// @Success      200
+// @Success      304
+// @Failure      400  {object}  map[string]string
+// @Router       /feeds/ads.atom [get]
+func (h *FeedHandler) Search(w http.ResponseWriter, r *http.Request) {
+	f, err := parseAdFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	// Лента публичная: читатель видит то же, что и анонимный поиск
+	f.Viewer = ""
+	ads, err := h.Ads.Search(f)
+	if err != nil {
+		log.Printf("AdRepo.Search error: %v", err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	// ID ленты зависит только от фильтров, а не от порядка параметров и адреса
+	q := url.Values{}
+	for name, v := range map[string]string{"search": f.Keyword, "category": f.Category} {
+		if v != "" {
+			q.Set(name, v)
+		}
+	}
+	if f.MaxPrice > 0 {
+		q.Set("max_price", strconv.FormatInt(f.MaxPrice, 10))
+	}
+	title := "Объявления"
+	if f.Keyword != "" {
+		title += ": " + f.Keyword
+	}
+	if f.Category != "" {
+		title += " в категории " + f.Category
+	}
+	h.serve(w, r, &feed.Feed{ID: feed.ID("search:" + q.Encode()), Title: title}, ads)
+}
+
+// Seller отдаёт Atom-ленту объявлений продавца.
+// @Summary      Atom-лента продавца
+// @Description  Последние 50 опубликованных объявлений продавца, новые первыми. Поддерживает If-None-Match и If-Modified-Since. Ключ API не нужен.
+// @Tags         feeds
+// @Produce      application/atom+xml
+// @Param        telegramId  path  string  true  "Telegram ID продавца"
+// @Success      200
+// @Success      304
+// @Router       /users/{telegramId}/ads.atom [get]
+func (h *FeedHandler) Seller(w http.ResponseWriter, r *http.Request) {
+	telegramID := mux.Vars(r)["telegramId"]
+	ads, err := h.Ads.GetByTelegramID(telegramID, "")
+	if err != nil {
+		log.Printf("AdRepo.GetByTelegramID error: %v", err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	title := "Объявления продавца"
+	if len(ads) > 0 {
+		title += " " + ads[0].UserName
+	}
+	f := &feed.Feed{ID: feed.ID("seller:" + telegramID), Title: title}
+	if len(ads) > 0 {
+		f.Author = &feed.Person{Name: ads[0].UserName}
+	}
+	h.serve(w, r, f, ads)
+}
+
+// serve дополняет ленту записями и отдаёт её с ETag и Last-Modified;
+// условные запросы обрабатывает http.ServeContent.
+func (h *FeedHandler) serve(w http.ResponseWriter, r *http.Request, f *feed.Feed, ads []*domain.Advertisement) {
+	sort.SliceStable(ads, func(i, j int) bool { return ads[i].CreatedAt.After(ads[j].CreatedAt) })
+	if len(ads) > feedSize {
+		ads = ads[:feedSize]
+	}
+
+	base := publicBaseURL(h.BaseURL, r)
+	f.Links = []feed.Link{{Rel: "self", Type: "application/atom+xml", Href: base + r.URL.RequestURI()}}
+	var updated time.Time
+	for _, ad := range ads {
+		if ad.UpdatedAt.After(updated) {
+			updated = ad.UpdatedAt
+		}
+		f.Entries = append(f.Entries, adEntry(ad, base))
+	}
+	if updated.IsZero() {
+		// Пустой ленте нужна постоянная дата, иначе ETag менялся бы на каждом запросе
+		updated = time.Unix(0, 0)
+	}
+	f.Updated = feed.Time(updated)
+
+	body, err := f.Marshal()
+	if err != nil {
+		log.Printf("marshal feed: %v", err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	sum := sha256.Sum256(body)
+	w.Header().Set("Content-Type", feed.ContentType)
+	w.Header().Set("ETag", `"`+hex.EncodeToString(sum[:16])+`"`)
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	http.ServeContent(w, r, "", updated, bytes.NewReader(body))
+}
+
+// adEntry — запись ленты об объявлении. Телефон продавца в ленту не попадает.
+func adEntry(ad *domain.Advertisement, base string) *feed.Entry {
+	e := &feed.Entry{
+		ID:        feed.ID("ad:" + strconv.FormatInt(ad.ID, 10)),
+		Title:     ad.Title,
+		Updated:   feed.Time(ad.UpdatedAt),
+		Published: feed.Time(ad.CreatedAt),
+		Author:    &feed.Person{Name: ad.UserName},
+	}
+	if ad.Category != "" {
+		e.Categories = []feed.Category{{Term: ad.Category}}
+	}
+	if ad.PhotosUrls != "" {
+		e.Links = append(e.Links, feed.Link{
+			Rel:  "enclosure",
+			Type: mime.TypeByExtension(path.Ext(ad.PhotosUrls)),
+			Href: base + ad.PhotosUrls,
+		})
+	}
+	text := fmt.Sprintf("Цена: %d", ad.Price)
+	if ad.Address != "" {
+		text += "\nАдрес: " + ad.Address
+	}
+	if ad.Description != "" {
+		text += "\n\n" + ad.Description
+	}
+	e.Content = &feed.Text{Type: "text", Body: text}
+	return e
+}
+
+// publicBaseURL возвращает внешний адрес API без завершающего слэша:
+// configured, если он задан, иначе адрес из запроса.
+func publicBaseURL(configured string, r *http.Request) string {
+	if configured != "" {
+		return configured
+	}
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host
+}
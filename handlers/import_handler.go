@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"path/filepath"
+	"poppins/domain"
+	"poppins/imports"
+	"poppins/repository"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/minio/minio-go/v7"
+)
+
+// maxImportSize — предельный размер файла импорта вместе с фотографиями.
+const maxImportSize = 100 << 20
+
+type ImportHandler struct {
+	Repo        *repository.ImportRepo
+	MinioClient *minio.Client
+	// Bucket — закрытый бакет, где файл ждёт обработки.
+	Bucket    string
+	Sanctions *repository.SanctionRepo
+	MaxRows   int
+}
+
+func NewImportHandler(repo *repository.ImportRepo, mc *minio.Client, bucket string,
+	sanctions *repository.SanctionRepo, maxRows int) *ImportHandler {
+	return &ImportHandler{Repo: repo, MinioClient: mc, Bucket: bucket, Sanctions: sanctions, MaxRows: maxRows}
+}
+
+// Create принимает файл с объявлениями и ставит импорт в очередь.
+// @Summary      Импорт объявлений из файла
+// @Description  file — CSV, JSON или ZIP. Колонки CSV (ключи объектов JSON-массива): title, price (обязательные), description, address, category и photo_url — ссылка на фото — или photo — имя файла фото в ZIP. В ZIP кладутся ads.csv или ads.json и сами фото. Разделитель CSV — запятая или точка с запятой. Структура файла проверяется сразу (400), строки — в фоне теми же правилами, что и POST /ads; строки с ошибками пропускаются, остальные публикуются. Ход и ошибки строк — в GET /users/{telegramId}/imports/{id}.
+// @Tags         ads
+// @Accept       multipart/form-data
+// @Produce      json
+// @Param        telegramId  path      string  true  "Telegram ID продавца"
+// @Param        file        formData  file    true  "Файл .csv, .json или .zip"
+// @Param        Idempotency-Key  header  string  false "Ключ идемпотентности"
+// @Success      202  {object}  domain.ImportJob
+// @Failure      400  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Security     ApiKeyAuth
+// @Router       /users/{telegramId}/imports [post]
+func (h *ImportHandler) Create(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	telegramID := mux.Vars(r)["telegramId"]
+	if denyBanned(w, h.Sanctions, telegramID) {
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportSize+1<<20)
+	if err := r.ParseMultipartForm(32 << 20); err != nil {
+		http.Error(w, "cannot parse form: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	file, fh, err := r.FormFile("file")
+	if err != nil {
+		http.Error(w, "file is required: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+	format, err := imports.DetectFormat(fh.Filename)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	// Структуру файла проверяем сразу, чтобы не ставить в очередь заведомо негодный
+	src, err := imports.Parse(format, file, fh.Size, h.MaxRows)
+	if err != nil {
+		http.Error(w, "invalid file: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	objectName := fmt.Sprintf("%s/%d%s", telegramID, time.Now().UnixNano(), filepath.Ext(fh.Filename))
+	if _, err := h.MinioClient.PutObject(context.Background(), h.Bucket, objectName,
+		io.NewSectionReader(file, 0, fh.Size), fh.Size, minio.PutObjectOptions{}); err != nil {
+		log.Printf("upload import file: %v", err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	j := &domain.ImportJob{
+		TelegramID:   telegramID,
+		Format:       format,
+		Filename:     filepath.Base(fh.Filename),
+		Total:        len(src.Rows),
+		SourceObject: objectName,
+	}
+	if err := h.Repo.Create(j); err != nil {
+		if err := h.MinioClient.RemoveObject(context.Background(), h.Bucket, objectName, minio.RemoveObjectOptions{}); err != nil {
+			log.Printf("remove import file %s: %v", objectName, err)
+		}
+		if errors.Is(err, repository.ErrNotFound) {
+			http.Error(w, "user not found", http.StatusNotFound)
+			return
+		}
+		log.Printf("ImportRepo.Create error: %v", err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Location", fmt.Sprintf("/users/%s/imports/%d", telegramID, j.ID))
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(j)
+}
+
+// Get возвращает ход импорта и результат каждой обработанной строки.
+// @Summary      Статус импорта
+// @Tags         ads
+// @Produce      json
+// @Param        telegramId  path  string  true  "Telegram ID продавца"
+// @Param        id          path  int     true  "ID импорта"
+// @Success      200  {object}  domain.ImportJob
+// @Failure      404  {object}  map[string]string
+// @Security     ApiKeyAuth
+// @Router       /users/{telegramId}/imports/{id} [get]
+func (h *ImportHandler) Get(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	vars := mux.Vars(r)
+	id, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "invalid import id: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	j, err := h.Repo.Get(vars["telegramId"], id)
+	if errors.Is(err, repository.ErrNotFound) {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("ImportRepo.Get error: %v", err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(j)
+}
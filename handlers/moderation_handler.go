@@ -0,0 +1,183 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"poppins/domain"
+	"poppins/repository"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+type ModerationHandler struct {
+	Repo *repository.ModerationRepo
+}
+
+func NewModerationHandler(repo *repository.ModerationRepo) *ModerationHandler {
+	return &ModerationHandler{Repo: repo}
+}
+
+// ModerationDecisionRequest — payload для claim/approve/reject
+type ModerationDecisionRequest struct {
+	Moderator  string `json:"moderator"`
+	ReasonCode string `json:"reason_code"`
+	Comment    string `json:"comment"`
+}
+
+// List возвращает очередь модерации.
+// @Summary      Очередь модерации
+// @Description  Возвращает задачи модерации в порядке поступления. Без status — все открытые.
+// @Tags         moderation
+// @Produce      json
+// @Param        status  query     string  false  "pending, claimed, approved или rejected"
+// @Param        limit   query     int     false  "Максимум задач (по умолчанию 50)"
+// @Success      200  {array}   domain.ModerationTask
+// @Failure      400  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Security     ApiKeyAuth
+// @Router       /moderation/tasks [get]
+func (h *ModerationHandler) List(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	q := r.URL.Query()
+	limit := 50
+	if l := q.Get("limit"); l != "" {
+		n, err := strconv.Atoi(l)
+		if err != nil || n <= 0 || n > 500 {
+			http.Error(w, "invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+	tasks, err := h.Repo.List(q.Get("status"), limit)
+	if err != nil {
+		log.Printf("ModerationRepo.List error: %v", err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	if tasks == nil {
+		tasks = []*domain.ModerationTask{}
+	}
+	json.NewEncoder(w).Encode(tasks)
+}
+
+// decodeDecision разбирает ID задачи и тело запроса. Если модератор не указан,
+// используется имя API-ключа.
+func decodeDecision(r *http.Request) (int64, ModerationDecisionRequest, error) {
+	var req ModerationDecisionRequest
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		return 0, req, fmt.Errorf("invalid task id: %w", err)
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			return 0, req, fmt.Errorf("invalid body: %w", err)
+		}
+	}
+	if req.Moderator == "" {
+		req.Moderator = principalName(r)
+	}
+	if req.Moderator == "" {
+		return 0, req, errors.New("moderator is required")
+	}
+	return id, req, nil
+}
+
+func writeModerationError(w http.ResponseWriter, op string, err error) {
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		http.Error(w, "task not found", http.StatusNotFound)
+	case errors.Is(err, repository.ErrConflict):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		log.Printf("%s error: %v", op, err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+	}
+}
+
+// Claim берёт задачу в работу.
+// @Summary      Взять задачу модерации
+// @Description  Закрепляет задачу за модератором, чтобы её не взял другой.
+// @Tags         moderation
+// @Accept       json
+// @Produce      json
+// @Param        id    path      int                        true   "ID задачи"
+// @Param        body  body      ModerationDecisionRequest  false  "Модератор"
+// @Success      200  {object}  domain.ModerationTask
+// @Failure      404  {object}  map[string]string
+// @Failure      409  {object}  map[string]string
+// @Security     ApiKeyAuth
+// @Router       /moderation/tasks/{id}/claim [post]
+func (h *ModerationHandler) Claim(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	id, req, err := decodeDecision(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	task, err := h.Repo.Claim(id, req.Moderator)
+	if err != nil {
+		writeModerationError(w, "Claim", err)
+		return
+	}
+	json.NewEncoder(w).Encode(task)
+}
+
+// Approve публикует объявление.
+// @Summary      Одобрить объявление
+// @Tags         moderation
+// @Accept       json
+// @Produce      json
+// @Param        id    path      int                        true   "ID задачи"
+// @Param        body  body      ModerationDecisionRequest  false  "Модератор и комментарий"
+// @Success      200  {object}  domain.ModerationTask
+// @Failure      404  {object}  map[string]string
+// @Failure      409  {object}  map[string]string
+// @Security     ApiKeyAuth
+// @Router       /moderation/tasks/{id}/approve [post]
+func (h *ModerationHandler) Approve(w http.ResponseWriter, r *http.Request) {
+	h.resolve(w, r, true)
+}
+
+// Reject отклоняет объявление с кодом причины.
+// @Summary      Отклонить объявление
+// @Description  reason_code: spam, prohibited_item, wrong_category, misleading, offensive, contacts_in_ad, other.
+// @Tags         moderation
+// @Accept       json
+// @Produce      json
+// @Param        id    path      int                        true  "ID задачи"
+// @Param        body  body      ModerationDecisionRequest  true  "Модератор, причина и комментарий"
+// @Success      200  {object}  domain.ModerationTask
+// @Failure      400  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      409  {object}  map[string]string
+// @Security     ApiKeyAuth
+// @Router       /moderation/tasks/{id}/reject [post]
+func (h *ModerationHandler) Reject(w http.ResponseWriter, r *http.Request) {
+	h.resolve(w, r, false)
+}
+
+func (h *ModerationHandler) resolve(w http.ResponseWriter, r *http.Request, approve bool) {
+	w.Header().Set("Content-Type", "application/json")
+	id, req, err := decodeDecision(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !approve {
+		if _, ok := domain.RejectionReasons[req.ReasonCode]; !ok {
+			http.Error(w, "unknown reason_code: "+req.ReasonCode, http.StatusBadRequest)
+			return
+		}
+	}
+
+	task, err := h.Repo.Resolve(id, req.Moderator, approve, req.ReasonCode, req.Comment)
+	if err != nil {
+		writeModerationError(w, "Resolve", err)
+		return
+	}
+	json.NewEncoder(w).Encode(task)
+}
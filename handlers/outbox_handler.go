@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"poppins/domain"
+	"poppins/repository"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+type OutboxHandler struct {
+	Repo *repository.OutboxRepo
+}
+
+func NewOutboxHandler(repo *repository.OutboxRepo) *OutboxHandler {
+	return &OutboxHandler{Repo: repo}
+}
+
+// BotStatusRequest — бот сообщает, что пользователь заблокировал его или
+// снова запустил (обновление my_chat_member).
+type BotStatusRequest struct {
+	Blocked bool `json:"blocked"`
+}
+
+// List возвращает уведомления из очереди по статусу.
+// @Summary      Очередь уведомлений
+// @Description  status: pending, sent или dead (по умолчанию dead — недоставленные).
+// @Tags         admin
+// @Produce      json
+// @Param        status  query  string  false  "Статус"
+// @Param        limit   query  int     false  "Максимум записей (по умолчанию 50)"
+// @Success      200  {array}   domain.Notification
+// @Failure      400  {object}  map[string]string
+// @Security     ApiKeyAuth
+// @Router       /admin/outbox [get]
+func (h *OutboxHandler) List(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	status := r.URL.Query().Get("status")
+	switch status {
+	case "":
+		status = domain.NotificationDead
+	case domain.NotificationPending, domain.NotificationSent, domain.NotificationDead:
+	default:
+		http.Error(w, "unknown status: "+status, http.StatusBadRequest)
+		return
+	}
+	limit, ok := parseLimit(w, r)
+	if !ok {
+		return
+	}
+	list, err := h.Repo.List(status, limit)
+	if err != nil {
+		log.Printf("OutboxRepo.List error: %v", err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	if list == nil {
+		list = []*domain.Notification{}
+	}
+	json.NewEncoder(w).Encode(list)
+}
+
+// Requeue возвращает недоставленное уведомление в очередь.
+// @Summary      Повторить доставку уведомления
+// @Tags         admin
+// @Produce      json
+// @Param        id  path  int  true  "ID уведомления"
+// @Success      200  {object}  domain.Notification
+// @Failure      404  {object}  map[string]string
+// @Failure      409  {object}  map[string]string
+// @Security     ApiKeyAuth
+// @Router       /admin/outbox/{id}/requeue [post]
+func (h *OutboxHandler) Requeue(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "invalid notification id: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	n, err := h.Repo.Requeue(id)
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		http.Error(w, "notification not found", http.StatusNotFound)
+		return
+	case errors.Is(err, repository.ErrConflict):
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	case err != nil:
+		log.Printf("OutboxRepo.Requeue error: %v", err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(n)
+}
+
+// SetBotStatus отмечает, что пользователь заблокировал бота или снова его запустил.
+// @Summary      Статус бота у пользователя
+// @Description  Пока бот заблокирован, уведомления пользователю не отправляются.
+// @Tags         users
+// @Accept       json
+// @Param        telegramId  path  string            true  "Telegram ID пользователя"
+// @Param        body        body  BotStatusRequest  true  "Заблокирован ли бот"
+// @Success      204
+// @Failure      404  {object}  map[string]string
+// @Security     ApiKeyAuth
+// @Router       /users/{telegramId}/bot-status [put]
+func (h *OutboxHandler) SetBotStatus(w http.ResponseWriter, r *http.Request) {
+	var req BotStatusRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	err := h.Repo.SetBotBlocked(mux.Vars(r)["telegramId"], req.Blocked)
+	if errors.Is(err, repository.ErrNotFound) {
+		http.Error(w, "user not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("OutboxRepo.SetBotBlocked error: %v", err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
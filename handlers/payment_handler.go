@@ -0,0 +1,383 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"poppins/domain"
+	"poppins/payments"
+	"poppins/repository"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+type PaymentHandler struct {
+	Orders   *repository.OrderRepo
+	Ads      *repository.AdRepo
+	Provider payments.Provider
+	Admins   Admins
+	Currency string
+}
+
+func NewPaymentHandler(orders *repository.OrderRepo, ads *repository.AdRepo, provider payments.Provider, admins Admins, currency string) *PaymentHandler {
+	return &PaymentHandler{Orders: orders, Ads: ads, Provider: provider, Admins: admins, Currency: currency}
+}
+
+// CreateOrderRequest — покупка пакета продвижения
+type CreateOrderRequest struct {
+	Package string `json:"package"`
+}
+
+// CreateOrderResponse — заказ и выставленный счёт
+type CreateOrderResponse struct {
+	Order   *domain.Order     `json:"order"`
+	Invoice *payments.Invoice `json:"invoice"`
+}
+
+// RefundRequest — возврат оплаты
+type RefundRequest struct {
+	// External — деньги уже возвращены в кабинете провайдера, нужно только
+	// отметить возврат.
+	External bool `json:"external"`
+}
+
+// CreatePromotionOrder выставляет счёт на пакет продвижения объявления.
+// @Summary      Купить продвижение
+// @Description  Создаёт заказ и выставляет счёт у платёжного провайдера. После оплаты продвижение подключается автоматически. Поддерживает Idempotency-Key.
+// @Tags         payments
+// @Accept       json
+// @Produce      json
+// @Param        id           path      int                 true  "ID объявления"
+// @Param        telegram_id  query     string              true  "Telegram ID владельца"
+// @Param        body         body      CreateOrderRequest  true  "Пакет"
+// @Success      201  {object}  CreateOrderResponse
+// @Failure      400  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      502  {object}  map[string]string
+// @Failure      503  {object}  map[string]string
+// @Security     ApiKeyAuth
+// @Router       /ads/{id}/orders [post]
+func (h *PaymentHandler) CreatePromotionOrder(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if h.Provider == nil {
+		http.Error(w, "payments are disabled", http.StatusServiceUnavailable)
+		return
+	}
+	adID, err := parseAdID(r)
+	if err != nil {
+		http.Error(w, "invalid ad id: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	telegramID := r.URL.Query().Get("telegram_id")
+	if telegramID == "" {
+		http.Error(w, "missing telegram_id", http.StatusBadRequest)
+		return
+	}
+	var req CreateOrderRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	pkg, ok := domain.PromotionPackages[req.Package]
+	if !ok {
+		http.Error(w, "unknown package: "+req.Package, http.StatusBadRequest)
+		return
+	}
+	owner, err := h.Ads.OwnerTelegramID(adID)
+	if errors.Is(err, repository.ErrNotFound) {
+		http.Error(w, "ad not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("OwnerTelegramID error: %v", err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	if owner != telegramID {
+		http.Error(w, "access denied", http.StatusForbidden)
+		return
+	}
+
+	o := &domain.Order{
+		TelegramID: telegramID,
+		AdID:       &adID,
+		Product:    domain.PromotionProduct(pkg.Code),
+		Title:      pkg.Title,
+		Amount:     pkg.Price * 100,
+		Currency:   h.Currency,
+		Provider:   h.Provider.Name(),
+	}
+	if err := h.Orders.Create(o); err != nil {
+		log.Printf("OrderRepo.Create error: %v", err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	inv, err := h.Provider.CreateInvoice(o)
+	if err != nil {
+		log.Printf("create invoice for order %d: %v", o.ID, err)
+		if _, _, err := h.Orders.Transition(o.ID, domain.OrderFailed, repository.OrderChange{Source: "invoice error"}); err != nil {
+			log.Printf("fail order %d: %v", o.ID, err)
+		}
+		http.Error(w, "payment provider error", http.StatusBadGateway)
+		return
+	}
+	o, _, err = h.Orders.Transition(o.ID, domain.OrderPending, repository.OrderChange{Source: "invoice"})
+	if err != nil {
+		log.Printf("OrderRepo.Transition error: %v", err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(CreateOrderResponse{Order: o, Invoice: inv})
+}
+
+// Get возвращает заказ владельцу или администратору.
+// @Summary      Заказ
+// @Tags         payments
+// @Produce      json
+// @Param        id           path      int     true  "ID заказа"
+// @Param        telegram_id  query     string  true  "Telegram ID покупателя"
+// @Success      200  {object}  domain.Order
+// @Failure      403  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Security     ApiKeyAuth
+// @Router       /orders/{id} [get]
+func (h *PaymentHandler) Get(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	o, ok := h.ownedOrder(w, r)
+	if !ok {
+		return
+	}
+	json.NewEncoder(w).Encode(o)
+}
+
+// Cancel отменяет неоплаченный заказ.
+// @Summary      Отменить заказ
+// @Tags         payments
+// @Produce      json
+// @Param        id           path      int     true  "ID заказа"
+// @Param        telegram_id  query     string  true  "Telegram ID покупателя"
+// @Success      200  {object}  domain.Order
+// @Failure      403  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      409  {object}  map[string]string
+// @Security     ApiKeyAuth
+// @Router       /orders/{id}/cancel [post]
+func (h *PaymentHandler) Cancel(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	o, ok := h.ownedOrder(w, r)
+	if !ok {
+		return
+	}
+	o, _, err := h.Orders.Transition(o.ID, domain.OrderCancelled, repository.OrderChange{Source: "user"})
+	if err != nil {
+		writeOrderError(w, "OrderRepo.Transition", err)
+		return
+	}
+	json.NewEncoder(w).Encode(o)
+}
+
+// ListByTelegram возвращает заказы пользователя.
+// @Summary      Заказы пользователя
+// @Tags         payments
+// @Produce      json
+// @Param        telegramId  path  string  true  "Telegram ID пользователя"
+// @Success      200  {array}   domain.Order
+// @Security     ApiKeyAuth
+// @Router       /users/{telegramId}/orders [get]
+func (h *PaymentHandler) ListByTelegram(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	list, err := h.Orders.ListByTelegram(mux.Vars(r)["telegramId"])
+	if err != nil {
+		log.Printf("OrderRepo.ListByTelegram error: %v", err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	if list == nil {
+		list = []*domain.Order{}
+	}
+	json.NewEncoder(w).Encode(list)
+}
+
+// Refund возвращает оплату и отключает купленную услугу.
+// @Summary      Возврат оплаты
+// @Description  Если провайдер не поддерживает возврат через API, верните деньги в его кабинете и передайте external=true.
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        id    path      int            true   "ID заказа"
+// @Param        body  body      RefundRequest  false  "Параметры возврата"
+// @Success      200  {object}  domain.Order
+// @Failure      404  {object}  map[string]string
+// @Failure      409  {object}  map[string]string
+// @Failure      501  {object}  map[string]string
+// @Security     ApiKeyAuth
+// @Router       /admin/orders/{id}/refund [post]
+func (h *PaymentHandler) Refund(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "invalid order id: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	var req RefundRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "invalid body: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	o, err := h.Orders.Get(id)
+	if err != nil {
+		writeOrderError(w, "OrderRepo.Get", err)
+		return
+	}
+	if o.Status != domain.OrderPaid {
+		http.Error(w, "only paid orders can be refunded", http.StatusConflict)
+		return
+	}
+
+	source := "admin " + principalName(r)
+	if req.External {
+		source += " (external)"
+	} else {
+		if h.Provider == nil || h.Provider.Name() != o.Provider {
+			http.Error(w, "order provider is not configured, use external refund", http.StatusNotImplemented)
+			return
+		}
+		if err := h.Provider.Refund(o); errors.Is(err, payments.ErrRefundUnsupported) {
+			http.Error(w, err.Error()+", use external refund", http.StatusNotImplemented)
+			return
+		} else if err != nil {
+			log.Printf("refund order %d: %v", o.ID, err)
+			http.Error(w, "payment provider error", http.StatusBadGateway)
+			return
+		}
+	}
+
+	o, _, err = h.Orders.Transition(o.ID, domain.OrderRefunded, repository.OrderChange{Source: source})
+	if err != nil {
+		writeOrderError(w, "OrderRepo.Transition", err)
+		return
+	}
+	json.NewEncoder(w).Encode(o)
+}
+
+// Webhook принимает уведомления платёжного провайдера. Маршрут не требует
+// API-ключа: подлинность проверяет провайдер. Ответ не 2xx заставляет
+// провайдера повторить уведомление.
+// @Summary      Уведомления платёжного провайдера
+// @Tags         payments
+// @Accept       json
+// @Param        provider  path  string  true  "telegram или fake"
+// @Success      200
+// @Failure      400  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Router       /payments/{provider}/webhook [post]
+func (h *PaymentHandler) Webhook(w http.ResponseWriter, r *http.Request) {
+	if h.Provider == nil || mux.Vars(r)["provider"] != h.Provider.Name() {
+		http.Error(w, "unknown payment provider", http.StatusNotFound)
+		return
+	}
+	cb, err := h.Provider.ParseCallback(r)
+	if err != nil {
+		log.Printf("payment callback: %v", err)
+		http.Error(w, "invalid callback", http.StatusBadRequest)
+		return
+	}
+	if cb == nil {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	switch cb.Kind {
+	case payments.CallbackConfirm:
+		ok, reason := h.confirmable(cb)
+		if err := h.Provider.Confirm(cb, ok, reason); err != nil {
+			log.Printf("confirm order %d: %v", cb.OrderID, err)
+			http.Error(w, "confirm failed", http.StatusBadGateway)
+			return
+		}
+	case payments.CallbackPaid:
+		_, _, err := h.Orders.Transition(cb.OrderID, domain.OrderPaid, repository.OrderChange{
+			Source: h.Provider.Name(), ChargeID: cb.ChargeID, Amount: cb.Amount, Currency: cb.Currency,
+		})
+		if errors.Is(err, repository.ErrNotFound) || errors.Is(err, repository.ErrConflict) {
+			// Повтор не поможет — фиксируем для разбора вручную
+			log.Printf("PAYMENT NEEDS ATTENTION: order %d charge %s: %v", cb.OrderID, cb.ChargeID, err)
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		if err != nil {
+			log.Printf("OrderRepo.Transition error: %v", err)
+			http.Error(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	case payments.CallbackFailed:
+		_, _, err := h.Orders.Transition(cb.OrderID, domain.OrderFailed, repository.OrderChange{Source: h.Provider.Name()})
+		if err != nil && !errors.Is(err, repository.ErrNotFound) && !errors.Is(err, repository.ErrConflict) {
+			log.Printf("OrderRepo.Transition error: %v", err)
+			http.Error(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// confirmable решает, можно ли принять оплату заказа.
+func (h *PaymentHandler) confirmable(cb *payments.Callback) (bool, string) {
+	o, err := h.Orders.Get(cb.OrderID)
+	if err != nil {
+		log.Printf("confirm order %d: %v", cb.OrderID, err)
+		return false, "Заказ не найден"
+	}
+	switch {
+	case o.Status != domain.OrderPending && o.Status != domain.OrderCreated:
+		return false, "Заказ уже оплачен или отменён"
+	case cb.Amount != 0 && cb.Amount != o.Amount:
+		return false, "Сумма заказа изменилась, оформите его заново"
+	case o.AdID == nil:
+		return false, "Объявление удалено"
+	}
+	return true, ""
+}
+
+// ownedOrder читает заказ из пути и проверяет, что он принадлежит вызывающему.
+func (h *PaymentHandler) ownedOrder(w http.ResponseWriter, r *http.Request) (*domain.Order, bool) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "invalid order id: "+err.Error(), http.StatusBadRequest)
+		return nil, false
+	}
+	actor, ok := actorFromRequest(r, h.Admins)
+	if !ok {
+		http.Error(w, "missing telegram_id", http.StatusBadRequest)
+		return nil, false
+	}
+	o, err := h.Orders.Get(id)
+	if err != nil {
+		writeOrderError(w, "OrderRepo.Get", err)
+		return nil, false
+	}
+	if !actor.Owns(o.TelegramID) {
+		http.Error(w, "access denied", http.StatusForbidden)
+		return nil, false
+	}
+	return o, true
+}
+
+// writeOrderError переводит ошибки OrderRepo в HTTP-статусы.
+func writeOrderError(w http.ResponseWriter, op string, err error) {
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		http.Error(w, "order not found", http.StatusNotFound)
+	case errors.Is(err, repository.ErrConflict):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		log.Printf("%s error: %v", op, err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+	}
+}
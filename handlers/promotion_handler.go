@@ -0,0 +1,191 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"poppins/domain"
+	"poppins/repository"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+type PromotionHandler struct {
+	Repo   *repository.PromotionRepo
+	Ads    *repository.AdRepo
+	Admins Admins
+}
+
+func NewPromotionHandler(repo *repository.PromotionRepo, ads *repository.AdRepo, admins Admins) *PromotionHandler {
+	return &PromotionHandler{Repo: repo, Ads: ads, Admins: admins}
+}
+
+// CreatePromotionRequest — подключение пакета продвижения
+type CreatePromotionRequest struct {
+	Package  string     `json:"package"`
+	StartsAt *time.Time `json:"starts_at"`
+	EndsAt   *time.Time `json:"ends_at"`
+}
+
+// Packages возвращает каталог пакетов продвижения.
+// @Summary      Пакеты продвижения
+// @Tags         promotions
+// @Produce      json
+// @Success      200  {array}  domain.PromotionPackage
+// @Security     ApiKeyAuth
+// @Router       /promotions/packages [get]
+func (h *PromotionHandler) Packages(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	list := make([]domain.PromotionPackage, 0, len(domain.PromotionPackages))
+	for _, p := range domain.PromotionPackages {
+		list = append(list, p)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Price < list[j].Price })
+	json.NewEncoder(w).Encode(list)
+}
+
+// Create подключает объявлению пакет продвижения без оплаты.
+// @Summary      Подключить продвижение
+// @Description  package: top, highlight, pinned. По умолчанию действует с текущего момента на срок пакета.
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        id    path      int                     true  "ID объявления"
+// @Param        body  body      CreatePromotionRequest  true  "Пакет и период"
+// @Success      201  {object}  domain.Promotion
+// @Failure      400  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Security     ApiKeyAuth
+// @Router       /admin/ads/{id}/promotions [post]
+func (h *PromotionHandler) Create(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	adID, err := parseAdID(r)
+	if err != nil {
+		http.Error(w, "invalid ad id: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	var req CreatePromotionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	pkg, ok := domain.PromotionPackages[req.Package]
+	if !ok {
+		http.Error(w, "unknown package: "+req.Package, http.StatusBadRequest)
+		return
+	}
+	startsAt := time.Now()
+	if req.StartsAt != nil {
+		startsAt = *req.StartsAt
+	}
+	endsAt := startsAt.Add(pkg.Duration)
+	if req.EndsAt != nil {
+		endsAt = *req.EndsAt
+	}
+	if !endsAt.After(startsAt) {
+		http.Error(w, "ends_at must be after starts_at", http.StatusBadRequest)
+		return
+	}
+
+	p, err := h.Repo.Create(adID, pkg.Code, startsAt, endsAt, principalName(r))
+	if errors.Is(err, repository.ErrNotFound) {
+		http.Error(w, "ad not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("PromotionRepo.Create error: %v", err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(p)
+}
+
+// List возвращает продвижения объявления владельцу или администратору.
+// @Summary      Продвижения объявления
+// @Tags         promotions
+// @Produce      json
+// @Param        id           path      int     true  "ID объявления"
+// @Param        telegram_id  query     string  true  "Telegram ID владельца"
+// @Success      200  {array}   domain.Promotion
+// @Failure      403  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Security     ApiKeyAuth
+// @Router       /ads/{id}/promotions [get]
+func (h *PromotionHandler) List(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	adID, err := parseAdID(r)
+	if err != nil {
+		http.Error(w, "invalid ad id: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	actor, ok := actorFromRequest(r, h.Admins)
+	if !ok {
+		http.Error(w, "missing telegram_id", http.StatusBadRequest)
+		return
+	}
+	owner, err := h.Ads.OwnerTelegramID(adID)
+	if errors.Is(err, repository.ErrNotFound) {
+		http.Error(w, "ad not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("OwnerTelegramID error: %v", err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	if !actor.Owns(owner) {
+		http.Error(w, "access denied", http.StatusForbidden)
+		return
+	}
+
+	list, err := h.Repo.ListByAd(adID)
+	if err != nil {
+		log.Printf("PromotionRepo.ListByAd error: %v", err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	if list == nil {
+		list = []*domain.Promotion{}
+	}
+	json.NewEncoder(w).Encode(list)
+}
+
+// Cancel досрочно отключает продвижение.
+// @Summary      Отключить продвижение
+// @Tags         admin
+// @Produce      json
+// @Param        id           path  int  true  "ID объявления"
+// @Param        promotionId  path  int  true  "ID продвижения"
+// @Success      200  {object}  domain.Promotion
+// @Failure      404  {object}  map[string]string
+// @Security     ApiKeyAuth
+// @Router       /admin/ads/{id}/promotions/{promotionId} [delete]
+func (h *PromotionHandler) Cancel(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	adID, err := parseAdID(r)
+	if err != nil {
+		http.Error(w, "invalid ad id: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	id, err := strconv.ParseInt(mux.Vars(r)["promotionId"], 10, 64)
+	if err != nil {
+		http.Error(w, "invalid promotion id: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	p, err := h.Repo.Cancel(adID, id)
+	if errors.Is(err, repository.ErrNotFound) {
+		http.Error(w, "promotion not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("PromotionRepo.Cancel error: %v", err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(p)
+}
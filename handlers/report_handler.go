@@ -0,0 +1,194 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"poppins/domain"
+	"poppins/repository"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+type ReportHandler struct {
+	Repo      *repository.ReportRepo
+	Sanctions *repository.SanctionRepo
+	// HideThreshold — после скольких жалоб от разных пользователей объявление
+	// скрывается до решения модератора; 0 — не скрывать.
+	HideThreshold int
+}
+
+func NewReportHandler(repo *repository.ReportRepo, sanctions *repository.SanctionRepo, hideThreshold int) *ReportHandler {
+	return &ReportHandler{Repo: repo, Sanctions: sanctions, HideThreshold: hideThreshold}
+}
+
+// CreateReportRequest — payload жалобы
+type CreateReportRequest struct {
+	Reason string `json:"reason"`
+	Text   string `json:"text"`
+}
+
+// ResolveReportRequest — решение модератора по жалобе
+type ResolveReportRequest struct {
+	Moderator string `json:"moderator"`
+	Outcome   string `json:"outcome"`
+	Comment   string `json:"comment"`
+}
+
+// Create принимает жалобу покупателя на объявление.
+// @Summary      Пожаловаться на объявление
+// @Description  reason: scam, prohibited, spam, wrong_info, offensive, sold, other. Один пользователь может пожаловаться на объявление один раз.
+// @Tags         reports
+// @Accept       json
+// @Produce      json
+// @Param        id           path      int                  true  "ID объявления"
+// @Param        telegram_id  query     string               true  "Telegram ID пожаловавшегося"
+// @Param        report       body      CreateReportRequest  true  "Причина и текст"
+// @Success      201  {object}  domain.Report
+// @Failure      400  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      409  {object}  map[string]string
+// @Security     ApiKeyAuth
+// @Router       /ads/{id}/reports [post]
+func (h *ReportHandler) Create(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	adID, err := parseAdID(r)
+	if err != nil {
+		http.Error(w, "invalid ad id: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	telegramID := r.URL.Query().Get("telegram_id")
+	if telegramID == "" {
+		http.Error(w, "missing telegram_id", http.StatusBadRequest)
+		return
+	}
+	if denyBanned(w, h.Sanctions, telegramID) {
+		return
+	}
+	var req CreateReportRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if _, ok := domain.ReportReasons[req.Reason]; !ok {
+		http.Error(w, "unknown reason: "+req.Reason, http.StatusBadRequest)
+		return
+	}
+	if len(req.Text) > 2000 {
+		http.Error(w, "text is too long", http.StatusBadRequest)
+		return
+	}
+
+	rep := &domain.Report{AdID: adID, ReporterTelegramID: telegramID, Reason: req.Reason, Text: req.Text}
+	hidden, err := h.Repo.Create(rep, h.HideThreshold)
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		http.Error(w, "ad or user not found", http.StatusNotFound)
+		return
+	case errors.Is(err, repository.ErrForbidden):
+		http.Error(w, "cannot report own ad", http.StatusForbidden)
+		return
+	case errors.Is(err, repository.ErrConflict):
+		http.Error(w, "ad already reported", http.StatusConflict)
+		return
+	case err != nil:
+		log.Printf("ReportRepo.Create error: %v", err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	if hidden {
+		log.Printf("ad %d hidden after %d reports", adID, h.HideThreshold)
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(rep)
+}
+
+// List возвращает жалобы для модераторов.
+// @Summary      Жалобы на объявления
+// @Tags         moderation
+// @Produce      json
+// @Param        status  query     string  false  "open (по умолчанию) или resolved"
+// @Param        limit   query     int     false  "Максимум жалоб (по умолчанию 50)"
+// @Success      200  {array}   domain.Report
+// @Failure      400  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Security     ApiKeyAuth
+// @Router       /moderation/reports [get]
+func (h *ReportHandler) List(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	q := r.URL.Query()
+	limit := 50
+	if l := q.Get("limit"); l != "" {
+		n, err := strconv.Atoi(l)
+		if err != nil || n <= 0 || n > 500 {
+			http.Error(w, "invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+	reports, err := h.Repo.List(q.Get("status"), limit)
+	if err != nil {
+		log.Printf("ReportRepo.List error: %v", err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	if reports == nil {
+		reports = []*domain.Report{}
+	}
+	json.NewEncoder(w).Encode(reports)
+}
+
+// Resolve фиксирует решение по жалобе; решение распространяется на все
+// открытые жалобы на это объявление.
+// @Summary      Решение по жалобе
+// @Description  outcome: dismissed (вернуть скрытое объявление), ad_removed (снять с публикации), seller_warned.
+// @Tags         moderation
+// @Accept       json
+// @Produce      json
+// @Param        id    path      int                   true  "ID жалобы"
+// @Param        body  body      ResolveReportRequest  true  "Решение"
+// @Success      200  {array}   domain.Report
+// @Failure      400  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      409  {object}  map[string]string
+// @Security     ApiKeyAuth
+// @Router       /moderation/reports/{id}/resolve [post]
+func (h *ReportHandler) Resolve(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "invalid report id: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	var req ResolveReportRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !domain.ReportOutcomes[req.Outcome] {
+		http.Error(w, "unknown outcome: "+req.Outcome, http.StatusBadRequest)
+		return
+	}
+	if req.Moderator == "" {
+		req.Moderator = principalName(r)
+	}
+
+	resolved, err := h.Repo.Resolve(id, req.Moderator, req.Outcome, req.Comment)
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		http.Error(w, "report not found", http.StatusNotFound)
+		return
+	case errors.Is(err, repository.ErrConflict):
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	case err != nil:
+		log.Printf("ReportRepo.Resolve error: %v", err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(resolved)
+}
@@ -0,0 +1,366 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"poppins/domain"
+	"poppins/repository"
+	"poppins/rules"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gorilla/mux"
+)
+
+// maxReviewLen — максимальная длина отзыва и ответа продавца в символах.
+const maxReviewLen = 2000
+
+type ReviewHandler struct {
+	Repo      *repository.ReviewRepo
+	Sanctions *repository.SanctionRepo
+	Rules     *rules.Engine
+}
+
+func NewReviewHandler(repo *repository.ReviewRepo, sanctions *repository.SanctionRepo, engine *rules.Engine) *ReviewHandler {
+	return &ReviewHandler{Repo: repo, Sanctions: sanctions, Rules: engine}
+}
+
+// CreateReviewRequest — оценка и текст отзыва
+type CreateReviewRequest struct {
+	Rating int    `json:"rating"`
+	Text   string `json:"text"`
+}
+
+// ReviewReplyRequest — ответ продавца на отзыв
+type ReviewReplyRequest struct {
+	Text string `json:"text"`
+}
+
+// FlagReviewRequest — почему отзыв нужно проверить
+type FlagReviewRequest struct {
+	Reason string `json:"reason"`
+}
+
+// ReviewDecisionRequest — решение модератора по отзыву
+type ReviewDecisionRequest struct {
+	Moderator string `json:"moderator"`
+	Comment   string `json:"comment"`
+}
+
+// Create сохраняет отзыв о продавце.
+// @Summary      Оставить отзыв о продавце
+// @Description  Оценка 1–5 и текст. Один отзыв от покупателя на объявление; оставить его может только тот, кто писал продавцу или открывал его контакт. Отзыв с подозрительным текстом публикуется после модерации.
+// @Tags         reviews
+// @Accept       json
+// @Produce      json
+// @Param        id           path      int                  true  "ID объявления"
+// @Param        telegram_id  query     string               true  "Telegram ID покупателя"
+// @Param        review       body      CreateReviewRequest  true  "Отзыв"
+// @Success      201  {object}  domain.Review
+// @Failure      400  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      409  {object}  map[string]string
+// @Failure      422  {object}  map[string]interface{}
+// @Security     ApiKeyAuth
+// @Router       /ads/{id}/reviews [post]
+func (h *ReviewHandler) Create(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	adID, err := parseAdID(r)
+	if err != nil {
+		http.Error(w, "invalid ad id: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	telegramID := r.URL.Query().Get("telegram_id")
+	if telegramID == "" {
+		http.Error(w, "missing telegram_id", http.StatusBadRequest)
+		return
+	}
+	if denyBanned(w, h.Sanctions, telegramID) {
+		return
+	}
+	var req CreateReviewRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.Rating < 1 || req.Rating > 5 {
+		http.Error(w, "rating must be between 1 and 5", http.StatusBadRequest)
+		return
+	}
+	text, ok := h.checkText(w, req.Text)
+	if !ok {
+		return
+	}
+	status := domain.ReviewPublished
+	if h.Rules.CheckText(text).Flag {
+		status = domain.ReviewPending
+	}
+
+	rv, err := h.Repo.Create(adID, telegramID, req.Rating, text, status)
+	if err != nil {
+		writeReviewError(w, "ReviewRepo.Create", err)
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(rv)
+}
+
+// ListBySeller возвращает опубликованные отзывы о продавце.
+// @Summary      Отзывы о продавце
+// @Tags         reviews
+// @Produce      json
+// @Param        telegramId  path   string  true   "Telegram ID продавца"
+// @Param        limit       query  int     false  "Максимум отзывов (по умолчанию 50)"
+// @Success      200  {array}   domain.Review
+// @Failure      400  {object}  map[string]string
+// @Security     ApiKeyAuth
+// @Router       /users/{telegramId}/reviews [get]
+func (h *ReviewHandler) ListBySeller(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	limit, ok := parseLimit(w, r)
+	if !ok {
+		return
+	}
+	list, err := h.Repo.ListBySeller(mux.Vars(r)["telegramId"], limit)
+	if err != nil {
+		log.Printf("ReviewRepo.ListBySeller error: %v", err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	if list == nil {
+		list = []*domain.Review{}
+	}
+	json.NewEncoder(w).Encode(list)
+}
+
+// Reply сохраняет ответ продавца на отзыв.
+// @Summary      Ответить на отзыв
+// @Tags         reviews
+// @Accept       json
+// @Produce      json
+// @Param        id           path      int                 true  "ID отзыва"
+// @Param        telegram_id  query     string              true  "Telegram ID продавца"
+// @Param        reply        body      ReviewReplyRequest  true  "Ответ"
+// @Success      200  {object}  domain.Review
+// @Failure      400  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      422  {object}  map[string]interface{}
+// @Security     ApiKeyAuth
+// @Router       /reviews/{id}/reply [post]
+func (h *ReviewHandler) Reply(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	id, telegramID, ok := parseReviewRequest(w, r)
+	if !ok {
+		return
+	}
+	if denyBanned(w, h.Sanctions, telegramID) {
+		return
+	}
+	var req ReviewReplyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	text, ok := h.checkText(w, req.Text)
+	if !ok {
+		return
+	}
+	if text == "" {
+		http.Error(w, "text is required", http.StatusBadRequest)
+		return
+	}
+
+	rv, err := h.Repo.Reply(id, telegramID, text)
+	if err != nil {
+		writeReviewError(w, "ReviewRepo.Reply", err)
+		return
+	}
+	json.NewEncoder(w).Encode(rv)
+}
+
+// Flag отправляет отзыв на проверку модератору.
+// @Summary      Пожаловаться на отзыв
+// @Description  Отзыв остаётся опубликованным, пока модератор не примет решение.
+// @Tags         reviews
+// @Accept       json
+// @Produce      json
+// @Param        id           path      int                true  "ID отзыва"
+// @Param        telegram_id  query     string             true  "Telegram ID пожаловавшегося"
+// @Param        body         body      FlagReviewRequest  true  "Причина"
+// @Success      200  {object}  domain.Review
+// @Failure      400  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Security     ApiKeyAuth
+// @Router       /reviews/{id}/flag [post]
+func (h *ReviewHandler) Flag(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	id, telegramID, ok := parseReviewRequest(w, r)
+	if !ok {
+		return
+	}
+	var req FlagReviewRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if utf8.RuneCountInString(req.Reason) > 500 {
+		http.Error(w, "reason is too long", http.StatusBadRequest)
+		return
+	}
+	rv, err := h.Repo.Flag(id, telegramID, strings.TrimSpace(req.Reason))
+	if err != nil {
+		writeReviewError(w, "ReviewRepo.Flag", err)
+		return
+	}
+	json.NewEncoder(w).Encode(rv)
+}
+
+// ListForModeration возвращает отзывы, ждущие модератора.
+// @Summary      Отзывы на модерации
+// @Description  Отложенные правилами проверки и отмеченные пользователями.
+// @Tags         moderation
+// @Produce      json
+// @Param        limit  query  int  false  "Максимум отзывов (по умолчанию 50)"
+// @Success      200  {array}   domain.Review
+// @Failure      400  {object}  map[string]string
+// @Security     ApiKeyAuth
+// @Router       /moderation/reviews [get]
+func (h *ReviewHandler) ListForModeration(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	limit, ok := parseLimit(w, r)
+	if !ok {
+		return
+	}
+	list, err := h.Repo.ListForModeration(limit)
+	if err != nil {
+		log.Printf("ReviewRepo.ListForModeration error: %v", err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	if list == nil {
+		list = []*domain.Review{}
+	}
+	json.NewEncoder(w).Encode(list)
+}
+
+// Publish публикует отзыв (или оставляет опубликованным, сняв отметку).
+// @Summary      Опубликовать отзыв
+// @Tags         moderation
+// @Accept       json
+// @Produce      json
+// @Param        id    path      int                    true   "ID отзыва"
+// @Param        body  body      ReviewDecisionRequest  false  "Модератор и комментарий"
+// @Success      200  {object}  domain.Review
+// @Failure      404  {object}  map[string]string
+// @Security     ApiKeyAuth
+// @Router       /moderation/reviews/{id}/publish [post]
+func (h *ReviewHandler) Publish(w http.ResponseWriter, r *http.Request) {
+	h.moderate(w, r, true)
+}
+
+// Hide скрывает отзыв: он перестаёт учитываться в рейтинге продавца.
+// @Summary      Скрыть отзыв
+// @Tags         moderation
+// @Accept       json
+// @Produce      json
+// @Param        id    path      int                    true   "ID отзыва"
+// @Param        body  body      ReviewDecisionRequest  false  "Модератор и комментарий"
+// @Success      200  {object}  domain.Review
+// @Failure      404  {object}  map[string]string
+// @Router       /moderation/reviews/{id}/hide [post]
+func (h *ReviewHandler) Hide(w http.ResponseWriter, r *http.Request) {
+	h.moderate(w, r, false)
+}
+
+func (h *ReviewHandler) moderate(w http.ResponseWriter, r *http.Request, publish bool) {
+	w.Header().Set("Content-Type", "application/json")
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "invalid review id: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	var req ReviewDecisionRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "invalid body: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	if req.Moderator == "" {
+		req.Moderator = principalName(r)
+	}
+
+	rv, err := h.Repo.Moderate(id, req.Moderator, publish, req.Comment)
+	if err != nil {
+		writeReviewError(w, "ReviewRepo.Moderate", err)
+		return
+	}
+	json.NewEncoder(w).Encode(rv)
+}
+
+// checkText проверяет длину и правила; при нарушении отвечает клиенту.
+func (h *ReviewHandler) checkText(w http.ResponseWriter, text string) (string, bool) {
+	text = strings.TrimSpace(text)
+	if utf8.RuneCountInString(text) > maxReviewLen {
+		http.Error(w, "text is too long", http.StatusBadRequest)
+		return "", false
+	}
+	if verdict := h.Rules.CheckText(text); verdict.Reject {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"error": "text violates content rules",
+			"hits":  verdict.Hits,
+		})
+		return "", false
+	}
+	return text, true
+}
+
+func parseReviewRequest(w http.ResponseWriter, r *http.Request) (int64, string, bool) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "invalid review id: "+err.Error(), http.StatusBadRequest)
+		return 0, "", false
+	}
+	telegramID := r.URL.Query().Get("telegram_id")
+	if telegramID == "" {
+		http.Error(w, "missing telegram_id", http.StatusBadRequest)
+		return 0, "", false
+	}
+	return id, telegramID, true
+}
+
+// parseLimit разбирает query-параметр limit (по умолчанию 50, не больше 500).
+func parseLimit(w http.ResponseWriter, r *http.Request) (int, bool) {
+	l := r.URL.Query().Get("limit")
+	if l == "" {
+		return 50, true
+	}
+	n, err := strconv.Atoi(l)
+	if err != nil || n <= 0 || n > 500 {
+		http.Error(w, "invalid limit", http.StatusBadRequest)
+		return 0, false
+	}
+	return n, true
+}
+
+// writeReviewError переводит ошибки ReviewRepo в HTTP-статусы.
+func writeReviewError(w http.ResponseWriter, op string, err error) {
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		http.Error(w, "not found", http.StatusNotFound)
+	case errors.Is(err, repository.ErrForbidden):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, repository.ErrConflict):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		log.Printf("%s error: %v", op, err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+	}
+}
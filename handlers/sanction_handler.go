@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"poppins/domain"
+	"poppins/repository"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+type SanctionHandler struct {
+	Repo *repository.SanctionRepo
+}
+
+func NewSanctionHandler(repo *repository.SanctionRepo) *SanctionHandler {
+	return &SanctionHandler{Repo: repo}
+}
+
+// CreateSanctionRequest — payload для бана
+type CreateSanctionRequest struct {
+	Kind   string `json:"kind"`
+	Reason string `json:"reason"`
+	// Duration — срок вида "72h"; пустой — бессрочно.
+	Duration string `json:"duration"`
+}
+
+// LiftSanctionRequest — payload для снятия санкции
+type LiftSanctionRequest struct {
+	Reason string `json:"reason"`
+}
+
+// Create банит пользователя.
+// @Summary      Забанить пользователя
+// @Description  kind: ban (блокировка с уведомлением) или shadow_ban (объявления видит только сам пользователь). Без duration — бессрочно.
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        telegramId  path      string                 true  "Telegram ID пользователя"
+// @Param        sanction    body      CreateSanctionRequest  true  "Вид, причина и срок"
+// @Success      201  {object}  domain.Sanction
+// @Failure      400  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Security     ApiKeyAuth
+// @Router       /admin/users/{telegramId}/sanctions [post]
+func (h *SanctionHandler) Create(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	var req CreateSanctionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.Kind != domain.SanctionBan && req.Kind != domain.SanctionShadowBan {
+		http.Error(w, "unknown kind: "+req.Kind, http.StatusBadRequest)
+		return
+	}
+	if req.Reason == "" {
+		http.Error(w, "reason is required", http.StatusBadRequest)
+		return
+	}
+
+	s := &domain.Sanction{
+		TelegramID: mux.Vars(r)["telegramId"],
+		Kind:       req.Kind,
+		Reason:     req.Reason,
+		// Автор санкции в журнале — владелец API-ключа, а не поле запроса
+		CreatedBy: principalName(r),
+	}
+	if req.Duration != "" {
+		d, err := time.ParseDuration(req.Duration)
+		if err != nil || d <= 0 {
+			http.Error(w, "invalid duration", http.StatusBadRequest)
+			return
+		}
+		until := time.Now().Add(d)
+		s.ExpiresAt = &until
+	}
+
+	if err := h.Repo.Create(s); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			http.Error(w, "user not found", http.StatusNotFound)
+			return
+		}
+		log.Printf("SanctionRepo.Create error: %v", err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	log.Printf("sanction %d (%s) on %s by %s: %s", s.ID, s.Kind, s.TelegramID, s.CreatedBy, s.Reason)
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(s)
+}
+
+// List возвращает историю санкций пользователя.
+// @Summary      История санкций пользователя
+// @Tags         admin
+// @Produce      json
+// @Param        telegramId  path      string  true  "Telegram ID пользователя"
+// @Success      200  {array}   domain.Sanction
+// @Failure      500  {object}  map[string]string
+// @Security     ApiKeyAuth
+// @Router       /admin/users/{telegramId}/sanctions [get]
+func (h *SanctionHandler) List(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	list, err := h.Repo.ListByTelegram(mux.Vars(r)["telegramId"])
+	if err != nil {
+		log.Printf("SanctionRepo.ListByTelegram error: %v", err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	if list == nil {
+		list = []*domain.Sanction{}
+	}
+	json.NewEncoder(w).Encode(list)
+}
+
+// Lift снимает санкцию досрочно.
+// @Summary      Снять санкцию
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        telegramId  path      string               true   "Telegram ID пользователя"
+// @Param        id          path      int                  true   "ID санкции"
+// @Param        body        body      LiftSanctionRequest  false  "Причина снятия"
+// @Success      200  {object}  domain.Sanction
+// @Failure      400  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Security     ApiKeyAuth
+// @Router       /admin/users/{telegramId}/sanctions/{id} [delete]
+func (h *SanctionHandler) Lift(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	vars := mux.Vars(r)
+	id, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "invalid sanction id: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	var req LiftSanctionRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "invalid body: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	s, err := h.Repo.Lift(vars["telegramId"], id, principalName(r), req.Reason)
+	if errors.Is(err, repository.ErrNotFound) {
+		http.Error(w, "active sanction not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("SanctionRepo.Lift error: %v", err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	log.Printf("sanction %d (%s) on %s lifted by %s", s.ID, s.Kind, s.TelegramID, s.LiftedBy)
+	json.NewEncoder(w).Encode(s)
+}
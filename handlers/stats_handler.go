@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"poppins/repository"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// maxStatsDays — максимальная длина периода статистики.
+const maxStatsDays = 366
+
+type StatsHandler struct {
+	Repo   *repository.StatsRepo
+	Admins Admins
+}
+
+func NewStatsHandler(repo *repository.StatsRepo, admins Admins) *StatsHandler {
+	return &StatsHandler{Repo: repo, Admins: admins}
+}
+
+// Seller возвращает статистику объявлений продавца.
+// @Summary      Статистика объявлений продавца
+// @Description  Уникальные просмотры (один пользователь раз в сутки), добавления в избранное и раскрытия контакта по дням, по каждому объявлению и в сумме. По умолчанию — последние 30 дней. Дни считаются по UTC. Доступно только самому продавцу и администраторам.
+// @Tags         ads
+// @Produce      json
+// @Param        telegramId   path   string  true   "Telegram ID продавца"
+// @Param        telegram_id  query  string  true   "Telegram ID владельца"
+// @Param        from        query  string  false  "Начало периода, YYYY-MM-DD"
+// @Param        to          query  string  false  "Конец периода включительно, YYYY-MM-DD"
+// @Success      200  {object}  domain.SellerStats
+// @Failure      400  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Security     ApiKeyAuth
+// @Router       /users/{telegramId}/ads/stats [get]
+func (h *StatsHandler) Seller(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	seller := mux.Vars(r)["telegramId"]
+	actor, ok := actorFromRequest(r, h.Admins)
+	if !ok {
+		http.Error(w, "missing telegram_id", http.StatusBadRequest)
+		return
+	}
+	if !actor.Owns(seller) {
+		http.Error(w, "access denied", http.StatusForbidden)
+		return
+	}
+
+	q := r.URL.Query()
+	to := time.Now().UTC().Truncate(24 * time.Hour)
+	if v := q.Get("to"); v != "" {
+		t, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			http.Error(w, "invalid to: want YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+		to = t
+	}
+	from := to.AddDate(0, 0, -29)
+	if v := q.Get("from"); v != "" {
+		t, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			http.Error(w, "invalid from: want YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+		from = t
+	}
+	if from.After(to) {
+		http.Error(w, "from is after to", http.StatusBadRequest)
+		return
+	}
+	if to.Sub(from) >= maxStatsDays*24*time.Hour {
+		http.Error(w, "period is too long", http.StatusBadRequest)
+		return
+	}
+
+	st, err := h.Repo.Seller(seller, from, to)
+	if err != nil {
+		log.Printf("StatsRepo.Seller error: %v", err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(st)
+}
@@ -0,0 +1,186 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"poppins/domain"
+	"poppins/repository"
+	"poppins/stream"
+	"strconv"
+	"time"
+)
+
+type StreamHandler struct {
+	Broker *stream.Broker
+	Events *repository.EventRepo
+	// ReplayLimit — сколько пропущенных событий клиент может догнать по
+	// Last-Event-ID; если пропущено больше, он получает reset.
+	ReplayLimit int
+	// Heartbeat — как часто отправляется комментарий, чтобы прокси не
+	// закрывали простаивающее соединение.
+	Heartbeat time.Duration
+}
+
+func NewStreamHandler(broker *stream.Broker, events *repository.EventRepo, replayLimit int) *StreamHandler {
+	return &StreamHandler{Broker: broker, Events: events, ReplayLimit: replayLimit, Heartbeat: 25 * time.Second}
+}
+
+// adStateData — data события о снятом с публикации объявлении: содержимое
+// скрытого объявления в поток не попадает.
+type adStateData struct {
+	ID       int64  `json:"id"`
+	Status   string `json:"status"`
+	Archived bool   `json:"archived"`
+}
+
+// Ads отдаёт поток изменений объявлений в формате Server-Sent Events.
+// @Summary      Поток изменений объявлений (SSE)
+// @Description  События ad.created, ad.updated, ad.archived и ad.deleted по тем же фильтрам, что и поиск. data — событие в формате webhooks; для объявлений, снятых с публикации, data содержит только id, status и archived. После переподключения клиент передаёт Last-Event-ID (или last_event_id) и получает пропущенное. Если пропущенное уже не хранится, приходит событие reset — список нужно перезагрузить через GET /ads.
+// @Tags         ads
+// @Produce      text/event-stream
+// @Param        search         query   string  false  "Ключевое слово в заголовке"
+// @Param        max_price      query   int     false  "Максимальная цена"
+// @Param        category       query   string  false  "Категория"
+// @Param        telegram_id    query   string  false  "Telegram ID смотрящего"
+// @Param        last_event_id  query   int     false  "ID последнего полученного события"
+// @Param        Last-Event-ID  header  int     false  "ID последнего полученного события"
+// @Success      200
+// @Failure      400  {object}  map[string]string
+// @Security     ApiKeyAuth
+// @Router       /ads/stream [get]
+func (h *StreamHandler) Ads(w http.ResponseWriter, r *http.Request) {
+	f, err := parseAdFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	lastID := r.Header.Get("Last-Event-ID")
+	if lastID == "" {
+		lastID = r.URL.Query().Get("last_event_id")
+	}
+	var after int64
+	if lastID != "" {
+		if after, err = strconv.ParseInt(lastID, 10, 64); err != nil || after < 0 {
+			http.Error(w, "invalid Last-Event-ID", http.StatusBadRequest)
+			return
+		}
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	// Подписываемся до чтения журнала, чтобы не потерять события между ними
+	sub := h.Broker.Subscribe()
+	defer h.Broker.Unsubscribe(sub)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, "retry: 5000\n\n")
+
+	replayed := map[int64]bool{}
+	if lastID != "" {
+		events, reset, err := h.replay(after)
+		if err != nil {
+			log.Printf("stream replay: %v", err)
+			return
+		}
+		if reset {
+			fmt.Fprint(w, "event: reset\ndata: {}\n\n")
+		}
+		for _, ev := range events {
+			replayed[ev.Event.ID] = true
+			writeAdEvent(w, f, ev)
+		}
+	}
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(h.Heartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case ev, ok := <-sub.C:
+			if !ok {
+				// Клиент не успевал читать; он переподключится с Last-Event-ID
+				return
+			}
+			if replayed[ev.Event.ID] {
+				continue
+			}
+			writeAdEvent(w, f, ev)
+			flusher.Flush()
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": ping\n\n")
+			flusher.Flush()
+		}
+	}
+}
+
+// replay читает события после after из журнала. reset — часть пропущенного
+// уже удалена или пропущено больше ReplayLimit.
+func (h *StreamHandler) replay(after int64) ([]*repository.AdEvent, bool, error) {
+	first, err := h.Events.FirstID()
+	if err != nil {
+		return nil, false, err
+	}
+	if first > after+1 {
+		return nil, true, nil
+	}
+	events, err := h.Events.AdEventsAfter(after, h.ReplayLimit+1)
+	if err != nil {
+		return nil, false, err
+	}
+	if len(events) > h.ReplayLimit {
+		return nil, true, nil
+	}
+	return events, false, nil
+}
+
+// streamAdData — data события объявления в потоке: пустое поле TelegramID
+// перекрывает одноимённое поле AdEventData и не попадает в JSON.
+type streamAdData struct {
+	domain.AdEventData
+	TelegramID string `json:"telegram_id,omitempty"`
+}
+
+// writeAdEvent пишет событие клиенту, если объявление подходит под фильтр.
+func writeAdEvent(w http.ResponseWriter, f repository.AdFilter, ev *repository.AdEvent) {
+	ad := &ev.Ad
+	if !f.Match(ad, ev.OwnerHidden) {
+		return
+	}
+	e := *ev.Event
+	public := ad.Status == domain.AdStatusActive && !ad.Archived
+	switch {
+	case e.Type == domain.EventAdCreated && !public:
+		// Объявление на премодерации ещё никто не видит
+		return
+	case !public || e.Type == domain.EventAdDeleted:
+		data, err := json.Marshal(adStateData{ID: ad.ID, Status: ad.Status, Archived: ad.Archived})
+		if err != nil {
+			return
+		}
+		e.Data = data
+	}
+	if public && e.Type != domain.EventAdDeleted {
+		// Поток читает любой клиент с read-ключом: продавец в нём не раскрывается
+		data, err := json.Marshal(streamAdData{AdEventData: *ad})
+		if err != nil {
+			return
+		}
+		e.Data = data
+	}
+	body, err := json.Marshal(e)
+	if err != nil {
+		log.Printf("marshal stream event %d: %v", e.ID, err)
+		return
+	}
+	fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", e.ID, e.Type, body)
+}
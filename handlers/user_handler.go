@@ -2,19 +2,22 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"github.com/gorilla/mux"
 	"log"
 	"net/http"
 	"poppins/domain"
+	"poppins/ratelimit"
 	"poppins/repository"
 )
 
 type UserHandler struct {
-	Repo *repository.UserRepo
+	Repo      *repository.UserRepo
+	Sanctions *repository.SanctionRepo
 }
 
-func NewUserHandler(repo *repository.UserRepo) *UserHandler {
-	return &UserHandler{Repo: repo}
+func NewUserHandler(repo *repository.UserRepo, sanctions *repository.SanctionRepo) *UserHandler {
+	return &UserHandler{Repo: repo, Sanctions: sanctions}
 }
 
 // Create создаёт нового пользователя.
@@ -24,9 +27,11 @@ func NewUserHandler(repo *repository.UserRepo) *UserHandler {
 // @Accept       json
 // @Produce      json
 // @Param        user  body      domain.User  true  "Данные пользователя"
+// @Param        Idempotency-Key  header  string  false  "Ключ идемпотентности: повтор с тем же ключом вернёт сохранённый ответ"
 // @Success      201   {object}  domain.User
 // @Failure      400   {object}  map[string]string
 // @Failure      500   {object}  map[string]string
+// @Security     ApiKeyAuth
 // @Router       /users [post]
 func (h *UserHandler) Create(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
@@ -51,6 +56,7 @@ func (h *UserHandler) Create(w http.ResponseWriter, r *http.Request) {
 // @Param        telegramId   path      int  true  "ID пользователя"
 // @Success      200  {object}  domain.User
 // @Failure      404  {object}  map[string]string
+// @Security     ApiKeyAuth
 // @Router       /users/{telegramId} [get]
 func (h *UserHandler) Get(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
@@ -63,24 +69,35 @@ func (h *UserHandler) Get(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(u)
 }
 
-// Delete удаляет пользователя по TelegramID.
-// @Summary      Удалить пользователя
-// @Description  Удаляет запись пользователя из БД по его идентификатору.
+// Delete удаляет аккаунт пользователя со всеми данными.
+// @Summary      Удалить аккаунт
+// @Description  Удаляет пользователя, все его объявления (включая архивные), переписки, избранное, отзывы, заказы и выгрузки. Фото объявлений и архивы выгрузок удаляются из хранилища в фоне. Возвращает квитанцию с числом удалённых записей.
 // @Tags         users
 // @Produce      json
 // @Param        telegramId   path      int  true  "TelegramID пользователя"
-// @Success      200  {object}  domain.User
+// @Success      200  {object}  domain.DeletionReceipt
+// @Failure      403  {object}  map[string]string
 // @Failure      404  {object}  map[string]string
+// @Security     ApiKeyAuth
 // @Router       /users/{telegramId} [delete]
 func (h *UserHandler) Delete(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	id, _ := mux.Vars(r)["telegramId"]
-	u, err := h.Repo.Delete(id)
+	// Иначе забаненный удалил бы аккаунт вместе с историей санкций и завёл новый
+	if denyBanned(w, h.Sanctions, id) {
+		return
+	}
+	receipt, err := h.Repo.Delete(id, ratelimit.UserKeys(id))
+	if errors.Is(err, repository.ErrNotFound) {
+		http.Error(w, "user not found", http.StatusNotFound)
+		return
+	}
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusNotFound)
+		log.Printf("UserRepo.Delete error: %v", err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
 		return
 	}
-	json.NewEncoder(w).Encode(u)
+	json.NewEncoder(w).Encode(receipt)
 }
 
 type UpdateNameRequest struct {
@@ -93,6 +110,9 @@ func (h *UserHandler) UpdateName(w http.ResponseWriter, r *http.Request) {
 
 	// 1. Парсим telegramId
 	id := mux.Vars(r)["telegramId"]
+	if denyBanned(w, h.Sanctions, id) {
+		return
+	}
 
 	// 2. Декодим тело { "name": "Новое Имя" }
 	var req UpdateNameRequest
@@ -123,6 +143,9 @@ func (h *UserHandler) UpdatePhone(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
 	id := mux.Vars(r)["telegramId"]
+	if denyBanned(w, h.Sanctions, id) {
+		return
+	}
 
 	var req UpdatePhoneRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -155,6 +178,9 @@ func (h *UserHandler) UpdateContact(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "missing telegramId in path", http.StatusBadRequest)
 		return
 	}
+	if denyBanned(w, h.Sanctions, telegramID) {
+		return
+	}
 
 	// 2) Декодим тело запроса
 	var req UpdateContactRequest
@@ -0,0 +1,343 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"poppins/domain"
+	"poppins/repository"
+	"poppins/webhooks"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+type WebhookHandler struct {
+	Repo *repository.WebhookRepo
+}
+
+func NewWebhookHandler(repo *repository.WebhookRepo) *WebhookHandler {
+	return &WebhookHandler{Repo: repo}
+}
+
+// CreateWebhookRequest — новая подписка
+type CreateWebhookRequest struct {
+	URL         string   `json:"url"`
+	Events      []string `json:"events"`
+	Description string   `json:"description"`
+}
+
+// UpdateWebhookRequest — изменение подписки; отсутствующие поля не меняются.
+type UpdateWebhookRequest struct {
+	URL         *string  `json:"url"`
+	Events      []string `json:"events"`
+	Description *string  `json:"description"`
+	Active      *bool    `json:"active"`
+	// RotateSecret выпускает новый секрет; старый перестаёт действовать сразу.
+	RotateSecret bool `json:"rotate_secret"`
+}
+
+// WebhookSecretResponse — подписка с секретом; секрет отдаётся только при
+// создании и смене.
+type WebhookSecretResponse struct {
+	*domain.WebhookSubscription
+	Secret string `json:"secret"`
+}
+
+// Create создаёт подписку на события.
+// @Summary      Создать подписку на события
+// @Description  events: ad.created, ad.updated, ad.archived, ad.deleted, user.created, user.updated, user.deleted или "*". Секрет для проверки подписи Webhook-Signature возвращается только в этом ответе.
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        body  body      CreateWebhookRequest  true  "Адрес и типы событий"
+// @Success      201  {object}  WebhookSecretResponse
+// @Failure      400  {object}  map[string]string
+// @Security     ApiKeyAuth
+// @Router       /admin/webhooks [post]
+func (h *WebhookHandler) Create(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	var req CreateWebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := validateWebhook(req.URL, req.Events); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	secret, err := webhooks.GenerateSecret()
+	if err != nil {
+		log.Printf("generate webhook secret: %v", err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	s := &domain.WebhookSubscription{
+		URL:         req.URL,
+		Events:      req.Events,
+		Description: req.Description,
+		Active:      true,
+		Secret:      secret,
+		CreatedBy:   principalName(r),
+	}
+	if err := h.Repo.CreateSubscription(s); err != nil {
+		log.Printf("WebhookRepo.CreateSubscription error: %v", err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(WebhookSecretResponse{WebhookSubscription: s, Secret: secret})
+}
+
+// List возвращает все подписки.
+// @Summary      Подписки на события
+// @Tags         admin
+// @Produce      json
+// @Success      200  {array}   domain.WebhookSubscription
+// @Security     ApiKeyAuth
+// @Router       /admin/webhooks [get]
+func (h *WebhookHandler) List(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	list, err := h.Repo.ListSubscriptions()
+	if err != nil {
+		log.Printf("WebhookRepo.ListSubscriptions error: %v", err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	if list == nil {
+		list = []*domain.WebhookSubscription{}
+	}
+	json.NewEncoder(w).Encode(list)
+}
+
+// Get возвращает подписку.
+// @Summary      Подписка на события
+// @Tags         admin
+// @Produce      json
+// @Param        id  path  int  true  "ID подписки"
+// @Success      200  {object}  domain.WebhookSubscription
+// @Failure      404  {object}  map[string]string
+// @Security     ApiKeyAuth
+// @Router       /admin/webhooks/{id} [get]
+func (h *WebhookHandler) Get(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	s, ok := h.subscription(w, r)
+	if !ok {
+		return
+	}
+	json.NewEncoder(w).Encode(s)
+}
+
+// Update меняет подписку: адрес, события, описание, активность или секрет.
+// @Summary      Изменить подписку
+// @Description  active=false приостанавливает доставку; накопленные события уйдут после включения.
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        id    path      int                   true  "ID подписки"
+// @Param        body  body      UpdateWebhookRequest  true  "Изменения"
+// @Success      200  {object}  WebhookSecretResponse
+// @Failure      400  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Security     ApiKeyAuth
+// @Router       /admin/webhooks/{id} [patch]
+func (h *WebhookHandler) Update(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	var req UpdateWebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	s, ok := h.subscription(w, r)
+	if !ok {
+		return
+	}
+	if req.URL != nil {
+		s.URL = *req.URL
+	}
+	if req.Events != nil {
+		s.Events = req.Events
+	}
+	if req.Description != nil {
+		s.Description = *req.Description
+	}
+	if req.Active != nil {
+		s.Active = *req.Active
+	}
+	if err := validateWebhook(s.URL, s.Events); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	resp := WebhookSecretResponse{WebhookSubscription: s}
+	if req.RotateSecret {
+		secret, err := webhooks.GenerateSecret()
+		if err != nil {
+			log.Printf("generate webhook secret: %v", err)
+			http.Error(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+		s.Secret, resp.Secret = secret, secret
+	}
+	if err := h.Repo.UpdateSubscription(s); err != nil {
+		writeWebhookError(w, "WebhookRepo.UpdateSubscription", err)
+		return
+	}
+	json.NewEncoder(w).Encode(resp)
+}
+
+// Delete удаляет подписку вместе с журналом доставок.
+// @Summary      Удалить подписку
+// @Tags         admin
+// @Param        id  path  int  true  "ID подписки"
+// @Success      204
+// @Failure      404  {object}  map[string]string
+// @Security     ApiKeyAuth
+// @Router       /admin/webhooks/{id} [delete]
+func (h *WebhookHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseWebhookID(w, r, "id")
+	if !ok {
+		return
+	}
+	if err := h.Repo.DeleteSubscription(id); err != nil {
+		writeWebhookError(w, "WebhookRepo.DeleteSubscription", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Deliveries возвращает журнал доставок подписки.
+// @Summary      Журнал доставок
+// @Tags         admin
+// @Produce      json
+// @Param        id      path   int     true   "ID подписки"
+// @Param        status  query  string  false  "pending, delivered или failed"
+// @Param        limit   query  int     false  "Максимум записей (по умолчанию 50)"
+// @Success      200  {array}   domain.WebhookDelivery
+// @Failure      400  {object}  map[string]string
+// @Security     ApiKeyAuth
+// @Router       /admin/webhooks/{id}/deliveries [get]
+func (h *WebhookHandler) Deliveries(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	id, ok := parseWebhookID(w, r, "id")
+	if !ok {
+		return
+	}
+	status := r.URL.Query().Get("status")
+	switch status {
+	case "", domain.WebhookPending, domain.WebhookDelivered, domain.WebhookFailed:
+	default:
+		http.Error(w, "unknown status: "+status, http.StatusBadRequest)
+		return
+	}
+	limit, ok := parseLimit(w, r)
+	if !ok {
+		return
+	}
+	list, err := h.Repo.ListDeliveries(id, status, limit)
+	if err != nil {
+		writeWebhookError(w, "WebhookRepo.ListDeliveries", err)
+		return
+	}
+	if list == nil {
+		list = []*domain.WebhookDelivery{}
+	}
+	json.NewEncoder(w).Encode(list)
+}
+
+// Delivery возвращает доставку с журналом попыток.
+// @Summary      Доставка события
+// @Tags         admin
+// @Produce      json
+// @Param        id          path  int  true  "ID подписки"
+// @Param        deliveryId  path  int  true  "ID доставки"
+// @Success      200  {object}  domain.WebhookDelivery
+// @Failure      404  {object}  map[string]string
+// @Security     ApiKeyAuth
+// @Router       /admin/webhooks/{id}/deliveries/{deliveryId} [get]
+func (h *WebhookHandler) Delivery(w http.ResponseWriter, r *http.Request) {
+	h.withDelivery(w, r, h.Repo.GetDelivery, "WebhookRepo.GetDelivery")
+}
+
+// Redeliver повторяет доставку события.
+// @Summary      Повторить доставку
+// @Description  Ставит доставку в очередь заново с новым счётчиком попыток, в том числе уже доставленную.
+// @Tags         admin
+// @Produce      json
+// @Param        id          path  int  true  "ID подписки"
+// @Param        deliveryId  path  int  true  "ID доставки"
+// @Success      200  {object}  domain.WebhookDelivery
+// @Failure      404  {object}  map[string]string
+// @Router       /admin/webhooks/{id}/deliveries/{deliveryId}/redeliver [post]
+func (h *WebhookHandler) Redeliver(w http.ResponseWriter, r *http.Request) {
+	h.withDelivery(w, r, h.Repo.Redeliver, "WebhookRepo.Redeliver")
+}
+
+func (h *WebhookHandler) withDelivery(w http.ResponseWriter, r *http.Request,
+	fn func(subscriptionID, id int64) (*domain.WebhookDelivery, error), op string) {
+	w.Header().Set("Content-Type", "application/json")
+	subID, ok := parseWebhookID(w, r, "id")
+	if !ok {
+		return
+	}
+	id, ok := parseWebhookID(w, r, "deliveryId")
+	if !ok {
+		return
+	}
+	d, err := fn(subID, id)
+	if err != nil {
+		writeWebhookError(w, op, err)
+		return
+	}
+	json.NewEncoder(w).Encode(d)
+}
+
+func (h *WebhookHandler) subscription(w http.ResponseWriter, r *http.Request) (*domain.WebhookSubscription, bool) {
+	id, ok := parseWebhookID(w, r, "id")
+	if !ok {
+		return nil, false
+	}
+	s, err := h.Repo.GetSubscription(id)
+	if err != nil {
+		writeWebhookError(w, "WebhookRepo.GetSubscription", err)
+		return nil, false
+	}
+	return s, true
+}
+
+// validateWebhook проверяет адрес подписки и типы событий.
+func validateWebhook(rawURL string, events []string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return errors.New("url must be an absolute http(s) URL")
+	}
+	if len(events) == 0 {
+		return errors.New("events are required")
+	}
+	for _, e := range events {
+		if e != "*" && !domain.ValidEventType(e) {
+			return errors.New("unknown event type: " + e)
+		}
+	}
+	return nil
+}
+
+func parseWebhookID(w http.ResponseWriter, r *http.Request, name string) (int64, bool) {
+	id, err := strconv.ParseInt(mux.Vars(r)[name], 10, 64)
+	if err != nil {
+		http.Error(w, "invalid "+name+": "+err.Error(), http.StatusBadRequest)
+		return 0, false
+	}
+	return id, true
+}
+
+// writeWebhookError переводит ошибки WebhookRepo в HTTP-статусы.
+func writeWebhookError(w http.ResponseWriter, op string, err error) {
+	if errors.Is(err, repository.ErrNotFound) {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+	log.Printf("%s error: %v", op, err)
+	http.Error(w, "internal server error", http.StatusInternalServerError)
+}
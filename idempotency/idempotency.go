@@ -0,0 +1,170 @@
+// Package idempotency реализует заголовок Idempotency-Key: повтор запроса
+// с тем же ключом получает сохранённый ответ вместо повторного выполнения.
+package idempotency
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"strconv"
+	"time"
+
+	"poppins/auth"
+	"poppins/repository"
+)
+
+const (
+	// Header — заголовок с ключом идемпотентности.
+	Header = "Idempotency-Key"
+	// ReplayedHeader помечает ответ, взятый из сохранённых.
+	ReplayedHeader = "Idempotent-Replayed"
+
+	maxKeyLen  = 255
+	maxBodyLen = 32 << 20
+)
+
+// Middleware обеспечивает идемпотентность хендлера.
+type Middleware struct {
+	Repo *repository.IdempotencyRepo
+}
+
+func New(repo *repository.IdempotencyRepo) *Middleware {
+	return &Middleware{Repo: repo}
+}
+
+// scope — пространство ключей вызывающего сервиса, чтобы ключи разных
+// клиентов не пересекались.
+func scope(r *http.Request) string {
+	p := auth.FromContext(r.Context())
+	switch {
+	case p == nil:
+		return "anonymous"
+	case p.KeyID != 0:
+		return "key:" + strconv.FormatInt(p.KeyID, 10)
+	default:
+		return "name:" + p.Name
+	}
+}
+
+// Fingerprint — хеш метода, пути и тела запроса. Граница multipart
+// генерируется клиентом заново при каждой отправке, поэтому из тела
+// и Content-Type она исключается.
+func Fingerprint(r *http.Request, body []byte) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s %s\n", r.Method, r.URL.Path)
+
+	mediaType, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err == nil {
+		fmt.Fprintf(h, "%s\n", mediaType)
+		if boundary := params["boundary"]; boundary != "" {
+			body = bytes.ReplaceAll(body, []byte(boundary), nil)
+		}
+	}
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Wrap оборачивает хендлер. Запросы без заголовка выполняются как обычно.
+func (m *Middleware) Wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(Header)
+		if key == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if len(key) > maxKeyLen {
+			http.Error(w, "idempotency key is too long", http.StatusBadRequest)
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBodyLen))
+		if err != nil {
+			http.Error(w, "request body is too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		sc, fp := scope(r), Fingerprint(r, body)
+		rec, reserved, err := m.Repo.Reserve(sc, key, fp)
+		if err != nil {
+			log.Printf("idempotency: %v", err)
+			http.Error(w, "failed to check idempotency key", http.StatusInternalServerError)
+			return
+		}
+		if !reserved {
+			replay(w, rec, fp)
+			return
+		}
+
+		rw := &recorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rw, r)
+
+		// Ошибки сервера и превышение лимита не фиксируются: повтор с тем же
+		// ключом должен выполниться заново.
+		if rw.status >= 500 || rw.status == http.StatusTooManyRequests {
+			if err := m.Repo.Release(sc, key); err != nil {
+				log.Printf("idempotency release %q: %v", key, err)
+			}
+			return
+		}
+		if err := m.Repo.Complete(sc, key, rw.status, w.Header().Clone(), rw.body.Bytes()); err != nil {
+			log.Printf("idempotency save %q: %v", key, err)
+		}
+	})
+}
+
+// replay отвечает на повтор запроса.
+func replay(w http.ResponseWriter, rec *repository.IdempotencyRecord, fingerprint string) {
+	if rec.Fingerprint != fingerprint {
+		http.Error(w, "idempotency key was already used with a different request", http.StatusConflict)
+		return
+	}
+	if rec.StatusCode == 0 {
+		w.Header().Set("Retry-After", "1")
+		http.Error(w, "request with this idempotency key is still in progress", http.StatusConflict)
+		return
+	}
+	for k, v := range rec.Header {
+		w.Header()[k] = v
+	}
+	w.Header().Set(ReplayedHeader, "true")
+	w.WriteHeader(rec.StatusCode)
+	w.Write(rec.Body)
+}
+
+// recorder пропускает ответ клиенту и запоминает его копию.
+type recorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (r *recorder) WriteHeader(status int) {
+	if !r.wroteHeader {
+		r.status, r.wroteHeader = status, true
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *recorder) Write(b []byte) (int, error) {
+	r.wroteHeader = true
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
+
+// PurgeLoop периодически удаляет устаревшие ключи.
+func (m *Middleware) PurgeLoop(every time.Duration) {
+	for range time.Tick(every) {
+		if n, err := m.Repo.PurgeExpired(); err != nil {
+			log.Printf("purge idempotency keys: %v", err)
+		} else if n > 0 {
+			log.Printf("purged %d idempotency keys", n)
+		}
+	}
+}
@@ -0,0 +1,252 @@
+// Package imports создаёт объявления пачкой из CSV- или JSON-файла.
+package imports
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"poppins/domain"
+	"strconv"
+	"strings"
+)
+
+// Row — строка файла импорта. Фото задаётся ссылкой (PhotoURL) или именем
+// файла в ZIP-архиве (Photo).
+type Row struct {
+	Line        int
+	Title       string
+	Description string
+	Price       string
+	Address     string
+	Category    string
+	PhotoURL    string
+	Photo       string
+}
+
+// columns — поля строки по названиям колонок CSV и ключам JSON.
+var columns = map[string]func(*Row) *string{
+	"title":       func(r *Row) *string { return &r.Title },
+	"description": func(r *Row) *string { return &r.Description },
+	"price":       func(r *Row) *string { return &r.Price },
+	"address":     func(r *Row) *string { return &r.Address },
+	"category":    func(r *Row) *string { return &r.Category },
+	"photo_url":   func(r *Row) *string { return &r.PhotoURL },
+	"photo":       func(r *Row) *string { return &r.Photo },
+}
+
+// Source — разобранный файл импорта.
+type Source struct {
+	Rows []*Row
+	// Archive — содержимое ZIP с фотографиями; nil для CSV и JSON.
+	Archive *zip.Reader
+}
+
+// DetectFormat определяет формат файла по расширению имени.
+func DetectFormat(filename string) (string, error) {
+	switch strings.ToLower(path.Ext(filename)) {
+	case ".csv":
+		return domain.ImportCSV, nil
+	case ".json":
+		return domain.ImportJSON, nil
+	case ".zip":
+		return domain.ImportZIP, nil
+	}
+	return "", errors.New("unsupported file type: expected .csv, .json or .zip")
+}
+
+// Parse разбирает файл формата format. Ошибки в структуре файла (неизвестные
+// колонки, битый JSON, больше maxRows строк) делают весь файл негодным;
+// значения полей проверяет Validate для каждой строки отдельно.
+func Parse(format string, r io.ReaderAt, size int64, maxRows int) (*Source, error) {
+	var rows []*Row
+	var err error
+	switch format {
+	case domain.ImportCSV:
+		rows, err = parseCSV(io.NewSectionReader(r, 0, size))
+	case domain.ImportJSON:
+		rows, err = parseJSON(io.NewSectionReader(r, 0, size))
+	case domain.ImportZIP:
+		return parseZIP(r, size, maxRows)
+	default:
+		return nil, fmt.Errorf("unknown format %q", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, errors.New("file has no rows")
+	}
+	if len(rows) > maxRows {
+		return nil, fmt.Errorf("file has %d rows, at most %d are allowed", len(rows), maxRows)
+	}
+	return &Source{Rows: rows}, nil
+}
+
+// parseZIP ищет в корне архива ads.csv или ads.json; остальные файлы —
+// фотографии, на которые ссылается колонка photo.
+func parseZIP(r io.ReaderAt, size int64, maxRows int) (*Source, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("invalid zip: %w", err)
+	}
+	for _, name := range []string{"ads.csv", "ads.json"} {
+		f := findFile(zr, name)
+		if f == nil {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, fmt.Errorf("open %s: %w", name, err)
+		}
+		data, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return nil, fmt.Errorf("read %s: %w", name, err)
+		}
+		format, _ := DetectFormat(name)
+		src, err := Parse(format, bytes.NewReader(data), int64(len(data)), maxRows)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		src.Archive = zr
+		return src, nil
+	}
+	return nil, errors.New("zip must contain ads.csv or ads.json")
+}
+
+func findFile(zr *zip.Reader, name string) *zip.File {
+	for _, f := range zr.File {
+		if path.Clean(f.Name) == name {
+			return f
+		}
+	}
+	return nil
+}
+
+// parseCSV читает CSV с заголовком. Разделитель — запятая или точка с
+// запятой (так сохраняет Excel в русской локали); BOM в начале пропускается.
+func parseCSV(r io.Reader) ([]*Row, error) {
+	br := bufio.NewReader(r)
+	if bom, _ := br.Peek(3); bytes.Equal(bom, []byte("\xef\xbb\xbf")) {
+		br.Discard(3)
+	}
+	first, _ := br.Peek(4096)
+	if i := bytes.IndexByte(first, '\n'); i >= 0 {
+		first = first[:i]
+	}
+
+	cr := csv.NewReader(br)
+	if bytes.Count(first, []byte(";")) > bytes.Count(first, []byte(",")) {
+		cr.Comma = ';'
+	}
+	cr.TrimLeadingSpace = true
+	header, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("read header: %w", err)
+	}
+	fields := make([]func(*Row) *string, len(header))
+	seen := map[string]bool{}
+	for i, h := range header {
+		h = strings.ToLower(strings.TrimSpace(h))
+		field, ok := columns[h]
+		if !ok {
+			return nil, fmt.Errorf("unknown column %q", h)
+		}
+		fields[i] = field
+		seen[h] = true
+	}
+	if err := checkColumns(seen); err != nil {
+		return nil, err
+	}
+
+	var rows []*Row
+	for {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := cr.FieldPos(0)
+		row := &Row{Line: line}
+		for i, v := range rec {
+			*fields[i](row) = strings.TrimSpace(v)
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// parseJSON читает массив объектов с ключами как у колонок CSV; price —
+// число или строка.
+func parseJSON(r io.Reader) ([]*Row, error) {
+	var items []map[string]interface{}
+	if err := json.NewDecoder(r).Decode(&items); err != nil {
+		return nil, fmt.Errorf("invalid json: %w", err)
+	}
+	seen := map[string]bool{}
+	rows := make([]*Row, 0, len(items))
+	for i, item := range items {
+		row := &Row{Line: i + 1}
+		for k, v := range item {
+			field, ok := columns[k]
+			if !ok {
+				return nil, fmt.Errorf("item %d: unknown field %q", i+1, k)
+			}
+			seen[k] = true
+			switch v := v.(type) {
+			case string:
+				*field(row) = strings.TrimSpace(v)
+			case float64:
+				*field(row) = strconv.FormatFloat(v, 'f', -1, 64)
+			case nil:
+			default:
+				return nil, fmt.Errorf("item %d: field %q must be a string or a number", i+1, k)
+			}
+		}
+		rows = append(rows, row)
+	}
+	if len(rows) > 0 {
+		if err := checkColumns(seen); err != nil {
+			return nil, err
+		}
+	}
+	return rows, nil
+}
+
+func checkColumns(seen map[string]bool) error {
+	for _, c := range []string{"title", "price"} {
+		if !seen[c] {
+			return fmt.Errorf("missing column %q", c)
+		}
+	}
+	if !seen["photo_url"] && !seen["photo"] {
+		return errors.New(`missing column "photo_url" or "photo"`)
+	}
+	return nil
+}
+
+// Validate проверяет значения строки и возвращает цену.
+func (r *Row) Validate() (int64, error) {
+	if r.Title == "" {
+		return 0, errors.New("title is required")
+	}
+	price, err := strconv.ParseInt(strings.ReplaceAll(r.Price, " ", ""), 10, 64)
+	if err != nil || price < 0 {
+		return 0, fmt.Errorf("invalid price %q", r.Price)
+	}
+	switch {
+	case r.PhotoURL == "" && r.Photo == "":
+		return 0, errors.New("photo_url or photo is required")
+	case r.PhotoURL != "" && r.Photo != "":
+		return 0, errors.New("only one of photo_url and photo is allowed")
+	}
+	return price, nil
+}
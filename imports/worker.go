@@ -0,0 +1,343 @@
+package imports
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"poppins/dedup"
+	"poppins/domain"
+	"poppins/moderation"
+	"poppins/repository"
+	"poppins/rules"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+)
+
+// maxPhotoSize — предельный размер одного фото, как у формы POST /ads.
+const maxPhotoSize = 20 << 20
+
+// Worker обрабатывает импорты из очереди: каждая строка файла проходит те же
+// проверки, что и POST /ads (правила, премодерация, повторы), и создаёт
+// объявление; ошибки строк записываются в отчёт, остальные строки импортируются.
+type Worker struct {
+	Repo       *repository.ImportRepo
+	Ads        *repository.AdRepo
+	Rules      *rules.Engine
+	Policy     moderation.Policy
+	Duplicates dedup.Policy
+	Storage    *minio.Client
+	// PhotoBucket — бакет фотографий объявлений, SourceBucket — загруженных файлов.
+	PhotoBucket  string
+	SourceBucket string
+	// Client скачивает фото по photo_url; адреса внутренней сети запрещены.
+	Client      *http.Client
+	MaxRows     int
+	MaxAttempts int
+	Lease       time.Duration
+}
+
+func NewWorker(repo *repository.ImportRepo, ads *repository.AdRepo, engine *rules.Engine,
+	policy moderation.Policy, duplicates dedup.Policy, storage *minio.Client,
+	photoBucket, sourceBucket string, maxRows int) *Worker {
+	return &Worker{
+		Repo:         repo,
+		Ads:          ads,
+		Rules:        engine,
+		Policy:       policy,
+		Duplicates:   duplicates,
+		Storage:      storage,
+		PhotoBucket:  photoBucket,
+		SourceBucket: sourceBucket,
+		Client:       publicClient(15 * time.Second),
+		MaxRows:      maxRows,
+		MaxAttempts:  3,
+		Lease:        30 * time.Minute,
+	}
+}
+
+// publicClient — HTTP-клиент, который соединяется только с публичными
+// адресами: ссылка на фото не должна открывать доступ к внутренней сети.
+func publicClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: 10 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			ip := net.ParseIP(host)
+			if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+				ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() {
+				return fmt.Errorf("address %s is not allowed", host)
+			}
+			return nil
+		},
+	}
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: &http.Transport{DialContext: dialer.DialContext, Proxy: nil},
+	}
+}
+
+// EnsureBucket создаёт закрытый бакет для загруженных файлов, если его нет.
+func EnsureBucket(ctx context.Context, client *minio.Client, bucket string) error {
+	exists, err := client.BucketExists(ctx, bucket)
+	if err != nil || exists {
+		return err
+	}
+	return client.MakeBucket(ctx, bucket, minio.MakeBucketOptions{})
+}
+
+// Run проверяет очередь каждые every; пока в ней есть импорты, берёт их без ожидания.
+func (w *Worker) Run(every time.Duration) {
+	for {
+		j, err := w.Repo.Claim(w.Lease)
+		if err != nil {
+			log.Printf("import: %v", err)
+		}
+		if j == nil {
+			time.Sleep(every)
+			continue
+		}
+		w.process(j)
+	}
+}
+
+func (w *Worker) process(j *domain.ImportJob) {
+	err := w.run(j)
+	var fileErr *FileError
+	switch {
+	case err == nil:
+		err = w.Repo.Finish(j.ID, domain.ImportDone, "")
+	case errors.As(err, &fileErr):
+		err = w.Repo.Finish(j.ID, domain.ImportFailed, fileErr.Error())
+	case j.Attempts >= w.MaxAttempts:
+		log.Printf("import %d failed after %d attempts: %v", j.ID, j.Attempts, err)
+		err = w.Repo.Finish(j.ID, domain.ImportFailed, "internal error")
+	default:
+		log.Printf("import %d: %v", j.ID, err)
+		err = w.Repo.Retry(j.ID, time.Now().Add(time.Duration(j.Attempts)*time.Minute), err.Error())
+	}
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		log.Printf("import: update import %d: %v", j.ID, err)
+	}
+}
+
+// FileError — файл импорта негоден целиком; повторять обработку бесполезно.
+type FileError struct {
+	Err error
+}
+
+func (e *FileError) Error() string {
+	return e.Err.Error()
+}
+
+func (w *Worker) run(j *domain.ImportJob) error {
+	f, err := os.CreateTemp("", "import-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	obj, err := w.Storage.GetObject(context.Background(), w.SourceBucket, j.SourceObject, minio.GetObjectOptions{})
+	if err != nil {
+		return fmt.Errorf("get source: %w", err)
+	}
+	size, err := io.Copy(f, obj)
+	obj.Close()
+	if err != nil {
+		return fmt.Errorf("download source: %w", err)
+	}
+
+	src, err := Parse(j.Format, f, size, w.MaxRows)
+	if err != nil {
+		return &FileError{Err: err}
+	}
+	if err := w.Repo.SetTotal(j.ID, len(src.Rows)); err != nil {
+		return err
+	}
+	done, err := w.Repo.Processed(j.ID)
+	if err != nil {
+		return err
+	}
+	for _, row := range src.Rows {
+		if done[row.Line] {
+			continue
+		}
+		res := &domain.ImportRowResult{Line: row.Line, Status: domain.ImportRowCreated}
+		ad, err := w.importRow(j.TelegramID, src, row)
+		if err != nil {
+			var internal *internalError
+			if errors.As(err, &internal) {
+				// Сбой хранилища или БД: строку повторим вместе со всем импортом
+				return internal.Err
+			}
+			res.Status, res.Error = domain.ImportRowFailed, err.Error()
+		} else {
+			res.AdID = &ad.ID
+		}
+		if err := w.Repo.RecordRow(j.ID, res); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// internalError — строка не обработана из-за сбоя, а не из-за своих данных.
+type internalError struct {
+	Err error
+}
+
+func (e *internalError) Error() string {
+	return e.Err.Error()
+}
+
+// importRow создаёт объявление из строки так же, как AdHandler.Create.
+func (w *Worker) importRow(telegramID string, src *Source, row *Row) (*domain.Advertisement, error) {
+	price, err := row.Validate()
+	if err != nil {
+		return nil, err
+	}
+	ad := &domain.Advertisement{
+		TelegramID:  telegramID,
+		Title:       row.Title,
+		Description: row.Description,
+		Price:       price,
+		Address:     row.Address,
+		Category:    row.Category,
+	}
+	verdict := w.Rules.Check(ad)
+	if verdict.Reject {
+		return nil, errors.New("ad violates content rules: " + verdict.Summary())
+	}
+	ad.RiskScore = verdict.Score
+
+	photo, name, err := w.photo(src, row)
+	if err != nil {
+		return nil, err
+	}
+	contentType := http.DetectContentType(photo)
+	if !strings.HasPrefix(contentType, "image/") {
+		return nil, fmt.Errorf("photo %s is not an image (%s)", name, contentType)
+	}
+
+	textHash := int64(dedup.TextHash(ad.Title, ad.Description))
+	ad.TextHash = &textHash
+	if ph, err := dedup.ImageHash(bytes.NewReader(photo)); err == nil {
+		photoHash := int64(ph)
+		ad.PhotoHash = &photoHash
+	}
+
+	review := w.review(verdict, ad.Category, domain.ModerationTriggerCreated)
+	if w.Duplicates.Mode != dedup.ModeOff {
+		dup, err := w.Ads.FindDuplicate(ad, time.Now().Add(-w.Duplicates.Window), dedup.PhotoThreshold, dedup.TextThreshold)
+		if err != nil {
+			return nil, &internalError{Err: err}
+		}
+		if dup != nil {
+			// Слить повтор в оригинал, как делает POST /ads в режиме merge,
+			// при импорте нельзя: строка сообщает о повторе
+			if w.Duplicates.Mode != dedup.ModeFlag {
+				return nil, fmt.Errorf("duplicate of ad %d", dup.AdID)
+			}
+			ad.DuplicateOf = &dup.AdID
+			notes := fmt.Sprintf("похоже на объявление /ads/%d", dup.AdID)
+			if review != nil && review.Notes != "" {
+				notes = review.Notes + "; " + notes
+			}
+			review = &domain.ModerationTask{Trigger: domain.ModerationTriggerDuplicate, Notes: notes}
+		}
+	}
+
+	ext := path.Ext(name)
+	if exts, _ := mime.ExtensionsByType(contentType); ext == "" && len(exts) > 0 {
+		ext = exts[0]
+	}
+	// Имя объекта как у AdHandler.uploadPhoto: по префиксу определяется владелец фото
+	objectName := fmt.Sprintf("ads/%s_%d%s", telegramID, time.Now().UnixNano(), ext)
+	if _, err := w.Storage.PutObject(context.Background(), w.PhotoBucket, objectName,
+		bytes.NewReader(photo), int64(len(photo)), minio.PutObjectOptions{ContentType: contentType}); err != nil {
+		return nil, &internalError{Err: fmt.Errorf("upload photo: %w", err)}
+	}
+	ad.PhotosUrls = "/ads/" + objectName
+
+	if err := w.Ads.Create(ad, review); err != nil {
+		if err := w.Storage.RemoveObject(context.Background(), w.PhotoBucket, objectName, minio.RemoveObjectOptions{}); err != nil {
+			log.Printf("import: remove photo %s: %v", objectName, err)
+		}
+		return nil, &internalError{Err: fmt.Errorf("create ad: %w", err)}
+	}
+	return ad, nil
+}
+
+// review — как AdHandler.review: nil, если объявление публикуется сразу.
+func (w *Worker) review(verdict rules.Result, category, trigger string) *domain.ModerationTask {
+	switch {
+	case verdict.Flag:
+		return &domain.ModerationTask{Trigger: domain.ModerationTriggerRules, Notes: verdict.Summary()}
+	case w.Policy.Requires(category):
+		return &domain.ModerationTask{Trigger: trigger}
+	}
+	return nil
+}
+
+// photo читает фото строки из архива или по ссылке и возвращает его вместе
+// с именем файла.
+func (w *Worker) photo(src *Source, row *Row) ([]byte, string, error) {
+	if row.Photo != "" {
+		if src.Archive == nil {
+			return nil, "", errors.New("photo files can only be used in a zip archive; use photo_url")
+		}
+		f := findFile(src.Archive, path.Clean(row.Photo))
+		if f == nil {
+			return nil, "", fmt.Errorf("photo %s not found in archive", row.Photo)
+		}
+		if f.UncompressedSize64 > maxPhotoSize {
+			return nil, "", fmt.Errorf("photo %s is larger than %d MB", row.Photo, maxPhotoSize>>20)
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, "", fmt.Errorf("open photo %s: %w", row.Photo, err)
+		}
+		defer rc.Close()
+		data, err := io.ReadAll(io.LimitReader(rc, maxPhotoSize))
+		if err != nil {
+			return nil, "", fmt.Errorf("read photo %s: %w", row.Photo, err)
+		}
+		return data, row.Photo, nil
+	}
+
+	u, err := url.Parse(row.PhotoURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, "", fmt.Errorf("invalid photo_url %q", row.PhotoURL)
+	}
+	resp, err := w.Client.Get(u.String())
+	if err != nil {
+		return nil, "", fmt.Errorf("download photo: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("download photo: %s", resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxPhotoSize+1))
+	if err != nil {
+		return nil, "", fmt.Errorf("download photo: %w", err)
+	}
+	if len(data) > maxPhotoSize {
+		return nil, "", fmt.Errorf("photo is larger than %d MB", maxPhotoSize>>20)
+	}
+	return data, path.Base(u.Path), nil
+}
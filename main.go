@@ -1,11 +1,9 @@
 package main
 
 import (
-	"context"
 	"database/sql"
 	"fmt"
 	"log"
-	"net/http"
 	"os"
 
 	"github.com/joho/godotenv"
@@ -14,12 +12,6 @@ import (
 	"github.com/minio/minio-go/v7/pkg/credentials"
 
 	"poppins/config"
-	"poppins/handlers"
-	"poppins/repository"
-	"poppins/router"
-
-	httpSwagger "github.com/swaggo/http-swagger"
-	_ "poppins/docs"
 )
 
 // @title           Monolith Ads API
@@ -28,79 +20,77 @@ import (
 // @host      localhost:8080
 // @BasePath  /
 
+// @securityDefinitions.apikey  ApiKeyAuth
+// @in                          header
+// @name                        X-API-Key
+// @description                 API-ключ; можно передать и как "Authorization: Bearer <ключ>".
+
+const usage = `usage: poppins <command> [arguments]
+
+commands:
+  serve          start the HTTP server (default)
+  migrate        apply, roll back or list database migrations
+  seed           fill the database with fake users and ads for local development
+  user ban       ban or shadow-ban a user
+  user unban     lift a user's active sanctions
+  ad archive     archive an ad
+  storage gc     delete queued and orphaned objects from storage
+
+Run "poppins <command> -h" for the arguments of a command.`
+
 func main() {
 	// Загружаем .env (если есть)
 	if err := godotenv.Load(); err != nil {
 		log.Println("No .env file found, using environment")
 	}
 
-	// Конфиг
+	// Конфиг общий для всех команд
 	cfg := config.LoadConfig()
 
-	// Инициализация MinIO-клиента
-	minioClient, err := minio.New(cfg.MinIOEndpoint, &minio.Options{
-		Creds:  credentials.NewStaticV4(cfg.MinIOAccessKey, cfg.MinIOSecretKey, ""),
-		Secure: cfg.MinIOUseSSL,
-	})
-	if err != nil {
-		log.Fatal(err)
-	}
-
-	ctx := context.Background()
-
-	// Проверяем и создаём бакет, если нужно
-	exists, err := minioClient.BucketExists(ctx, cfg.MinIOBucket)
-	if err != nil {
-		log.Fatal(err)
-	}
-	if !exists {
-		if err := minioClient.MakeBucket(ctx, cfg.MinIOBucket, minio.MakeBucketOptions{}); err != nil {
-			log.Fatal(err)
-		}
+	// Без команды бинарник, как и раньше, запускает сервер
+	if len(os.Args) < 2 {
+		serve(cfg)
+		return
 	}
-
-	// Выставляем публичную read-only политику на весь бакет
-	publicReadPolicy := fmt.Sprintf(`{
-  "Version":"2012-10-17",
-  "Statement":[
-    {
-      "Effect":"Allow",
-      "Principal":{"AWS":["*"]},
-      "Action":["s3:GetObject"],
-      "Resource":["arn:aws:s3:::%s/*"]
-    }
-  ]
-}`, cfg.MinIOBucket)
-
-	if err := minioClient.SetBucketPolicy(ctx, cfg.MinIOBucket, publicReadPolicy); err != nil {
-		log.Fatalf("cannot set public policy on bucket %q: %v", cfg.MinIOBucket, err)
+	args := os.Args[2:]
+	switch os.Args[1] {
+	case "serve":
+		serve(cfg)
+	case "migrate":
+		os.Exit(migrateCommand(cfg, args))
+	case "seed":
+		os.Exit(seedCommand(cfg, args))
+	case "user":
+		os.Exit(userCommand(cfg, args))
+	case "ad":
+		os.Exit(adCommand(cfg, args))
+	case "storage":
+		os.Exit(storageCommand(cfg, args))
+	case "help", "-h", "--help":
+		fmt.Println(usage)
+	default:
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
 	}
-	log.Printf("Bucket %q is now publicly readable", cfg.MinIOBucket)
+}
 
-	// Подключаемся к базе
+// openDB открывает пул соединений с базой из конфига.
+func openDB(cfg *config.Config) (*sql.DB, error) {
 	db, err := sql.Open(cfg.DBDriver, cfg.DBDSN)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
-	defer db.Close()
-
-	// Миграции
-	migrations, _ := os.ReadFile("schema.sql")
-	if _, err := db.Exec(string(migrations)); err != nil {
-		log.Fatal("migrations failed:", err)
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("connect to database: %w", err)
 	}
+	return db, nil
+}
 
-	// Репозитории и хендлеры
-	userRepo := repository.NewUserRepo(db)
-	adRepo := repository.NewAdRepo(db)
-	uh := handlers.NewUserHandler(userRepo)
-	ah := handlers.NewAdHandler(adRepo, minioClient, cfg.MinIOBucket)
-
-	// Роутер и Swagger
-	r := router.NewRouter(uh, ah)
-	r.PathPrefix("/swagger/").Handler(httpSwagger.WrapHandler)
-
-	// Старт сервера
-	log.Println("Server started on port", cfg.ServerPort)
-	log.Fatal(http.ListenAndServe(":"+cfg.ServerPort, r))
+// newMinIO создаёт клиент хранилища из конфига.
+func newMinIO(cfg *config.Config) (*minio.Client, error) {
+	return minio.New(cfg.MinIOEndpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(cfg.MinIOAccessKey, cfg.MinIOSecretKey, ""),
+		Secure: cfg.MinIOUseSSL,
+	})
 }
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"poppins/config"
+	"poppins/migrations"
+)
+
+const migrateUsage = `usage: poppins migrate <command>
+
+commands:
+  up                 apply all pending migrations
+  down [-force] [n]  roll back the last n applied migrations (default 1);
+                     rolling back the initial migration needs -force
+  status             list migrations and whether they are applied`
+
+// migrateCommand выполняет «poppins migrate …» и возвращает код выхода.
+func migrateCommand(cfg *config.Config, args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		return 2
+	}
+	db, err := openDB(cfg)
+	if err != nil {
+		log.Print(err)
+		return 1
+	}
+	defer db.Close()
+	m, err := migrations.New(db)
+	if err != nil {
+		log.Print(err)
+		return 1
+	}
+
+	switch args[0] {
+	case "up":
+		ran, err := m.Up()
+		for _, mg := range ran {
+			fmt.Printf("applied %04d_%s\n", mg.Version, mg.Name)
+		}
+		if err != nil {
+			log.Print(err)
+			return 1
+		}
+		if len(ran) == 0 {
+			fmt.Println("no pending migrations")
+		}
+	case "down":
+		fs := flag.NewFlagSet("migrate down", flag.ContinueOnError)
+		force := fs.Bool("force", false, "allow rolling back the initial migration, which drops all data")
+		if err := fs.Parse(args[1:]); err != nil || fs.NArg() > 1 {
+			fmt.Fprintln(os.Stderr, migrateUsage)
+			return 2
+		}
+		n := 1
+		if fs.NArg() == 1 {
+			if n, err = strconv.Atoi(fs.Arg(0)); err != nil || n < 1 {
+				fmt.Fprintln(os.Stderr, "down: n must be a positive number")
+				return 2
+			}
+		}
+		ran, err := m.Down(n, *force)
+		for _, mg := range ran {
+			fmt.Printf("rolled back %04d_%s\n", mg.Version, mg.Name)
+		}
+		if err != nil {
+			log.Print(err)
+			return 1
+		}
+	case "status":
+		list, err := m.Status()
+		if err != nil {
+			log.Print(err)
+			return 1
+		}
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "VERSION\tNAME\tAPPLIED AT\tSTATE")
+		for _, s := range list {
+			state, at := "pending", "-"
+			if s.AppliedAt != nil {
+				state, at = "applied", s.AppliedAt.Local().Format("2006-01-02 15:04:05")
+			}
+			switch {
+			case s.Changed:
+				state = "applied, changed since"
+			case s.Missing:
+				state = "applied, missing from binary"
+			}
+			fmt.Fprintf(tw, "%04d\t%s\t%s\t%s\n", s.Version, s.Name, at, state)
+		}
+		tw.Flush()
+	default:
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		return 2
+	}
+	return 0
+}
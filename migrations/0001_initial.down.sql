@@ -0,0 +1,29 @@
+-- Удаляет всю схему вместе с данными.
+DROP TABLE IF EXISTS ad_import_rows;
+DROP TABLE IF EXISTS ad_imports;
+DROP TABLE IF EXISTS account_deletions;
+DROP TABLE IF EXISTS storage_deletions;
+DROP TABLE IF EXISTS data_exports;
+DROP TABLE IF EXISTS webhook_attempts;
+DROP TABLE IF EXISTS webhook_deliveries;
+DROP TABLE IF EXISTS webhook_subscriptions;
+DROP TABLE IF EXISTS events;
+DROP TABLE IF EXISTS outbox;
+DROP TABLE IF EXISTS order_events;
+DROP TABLE IF EXISTS orders;
+DROP TABLE IF EXISTS ad_promotions;
+DROP TABLE IF EXISTS reviews;
+DROP TABLE IF EXISTS ad_daily_stats;
+DROP TABLE IF EXISTS ad_views;
+DROP TABLE IF EXISTS favorites;
+DROP TABLE IF EXISTS contact_reveals;
+DROP TABLE IF EXISTS messages;
+DROP TABLE IF EXISTS conversations;
+DROP TABLE IF EXISTS idempotency_keys;
+DROP TABLE IF EXISTS rate_limit_buckets;
+DROP TABLE IF EXISTS user_sanctions;
+DROP TABLE IF EXISTS ad_reports;
+DROP TABLE IF EXISTS moderation_tasks;
+DROP TABLE IF EXISTS api_keys;
+DROP TABLE IF EXISTS advertisements;
+DROP TABLE IF EXISTS users;
@@ -0,0 +1,404 @@
+-- Схема на момент перехода на миграции. Все операторы идемпотентны: базы,
+-- созданные до появления schema_migrations, проходят эту миграцию без изменений.
+
+CREATE TABLE IF NOT EXISTS users (
+                       id SERIAL PRIMARY KEY,
+                       telegram_id TEXT NOT NULL,
+                       name TEXT,
+                       phone TEXT UNIQUE NOT NULL,
+                       preferred_contact TEXT,
+                       created_at TIMESTAMP NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS advertisements (
+                                id SERIAL PRIMARY KEY,
+                                user_id INT NOT NULL REFERENCES users(id),
+                                title TEXT NOT NULL,
+                                description TEXT,
+                                price BIGINT NOT NULL,
+                                photos_urls TEXT NOT NULL,
+                                address TEXT,
+                                archived BOOLEAN NOT NULL DEFAULT FALSE,
+                                created_at TIMESTAMP NOT NULL DEFAULT now(),
+                                updated_at TIMESTAMP NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS api_keys (
+                                id SERIAL PRIMARY KEY,
+                                name TEXT NOT NULL,
+                                prefix TEXT NOT NULL,
+                                key_hash TEXT UNIQUE NOT NULL,
+                                scopes TEXT[] NOT NULL,
+                                created_at TIMESTAMP NOT NULL DEFAULT now(),
+                                last_used_at TIMESTAMP,
+                                revoked_at TIMESTAMP
+);
+
+ALTER TABLE advertisements ADD COLUMN IF NOT EXISTS category TEXT NOT NULL DEFAULT '';
+ALTER TABLE advertisements ADD COLUMN IF NOT EXISTS status TEXT NOT NULL DEFAULT 'active';
+ALTER TABLE advertisements ADD COLUMN IF NOT EXISTS moderation_reason TEXT NOT NULL DEFAULT '';
+
+CREATE TABLE IF NOT EXISTS moderation_tasks (
+                                id SERIAL PRIMARY KEY,
+                                ad_id INT NOT NULL REFERENCES advertisements(id) ON DELETE CASCADE,
+                                status TEXT NOT NULL DEFAULT 'pending',
+                                trigger TEXT NOT NULL,
+                                claimed_by TEXT NOT NULL DEFAULT '',
+                                claimed_at TIMESTAMP,
+                                reason_code TEXT NOT NULL DEFAULT '',
+                                comment TEXT NOT NULL DEFAULT '',
+                                resolved_by TEXT NOT NULL DEFAULT '',
+                                resolved_at TIMESTAMP,
+                                created_at TIMESTAMP NOT NULL DEFAULT now()
+);
+
+-- Не больше одной открытой задачи на объявление
+CREATE UNIQUE INDEX IF NOT EXISTS moderation_tasks_open_ad
+    ON moderation_tasks (ad_id) WHERE status IN ('pending', 'claimed');
+
+ALTER TABLE advertisements ADD COLUMN IF NOT EXISTS risk_score INT NOT NULL DEFAULT 0;
+ALTER TABLE moderation_tasks ADD COLUMN IF NOT EXISTS notes TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE advertisements ADD COLUMN IF NOT EXISTS duplicate_of INT REFERENCES advertisements(id) ON DELETE SET NULL;
+ALTER TABLE advertisements ADD COLUMN IF NOT EXISTS photo_hash BIGINT;
+ALTER TABLE advertisements ADD COLUMN IF NOT EXISTS text_hash BIGINT;
+
+CREATE TABLE IF NOT EXISTS ad_reports (
+                                id SERIAL PRIMARY KEY,
+                                ad_id INT NOT NULL REFERENCES advertisements(id) ON DELETE CASCADE,
+                                reporter_user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+                                reason TEXT NOT NULL,
+                                text TEXT NOT NULL DEFAULT '',
+                                status TEXT NOT NULL DEFAULT 'open',
+                                outcome TEXT NOT NULL DEFAULT '',
+                                comment TEXT NOT NULL DEFAULT '',
+                                resolved_by TEXT NOT NULL DEFAULT '',
+                                resolved_at TIMESTAMP,
+                                created_at TIMESTAMP NOT NULL DEFAULT now(),
+                                UNIQUE (ad_id, reporter_user_id)
+);
+
+CREATE INDEX IF NOT EXISTS ad_reports_open ON ad_reports (ad_id) WHERE status = 'open';
+
+CREATE TABLE IF NOT EXISTS user_sanctions (
+                                id SERIAL PRIMARY KEY,
+                                user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+                                kind TEXT NOT NULL,
+                                reason TEXT NOT NULL,
+                                expires_at TIMESTAMP,
+                                created_by TEXT NOT NULL,
+                                created_at TIMESTAMP NOT NULL DEFAULT now(),
+                                lifted_at TIMESTAMP,
+                                lifted_by TEXT NOT NULL DEFAULT '',
+                                lift_reason TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS user_sanctions_user ON user_sanctions (user_id) WHERE lifted_at IS NULL;
+
+-- Состояние ограничителя частоты: теоретическое время прихода следующего
+-- запроса (GCRA), общее для всех экземпляров API
+CREATE TABLE IF NOT EXISTS rate_limit_buckets (
+                                key TEXT PRIMARY KEY,
+                                tat TIMESTAMPTZ NOT NULL
+);
+
+-- Ключи идемпотентности: повтор запроса с тем же ключом получает сохранённый ответ
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+                                scope TEXT NOT NULL,
+                                key TEXT NOT NULL,
+                                fingerprint TEXT NOT NULL,
+                                status_code INT,
+                                headers JSONB,
+                                body BYTEA,
+                                created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+                                PRIMARY KEY (scope, key)
+);
+
+-- Анонимная переписка покупателя с продавцом через бота
+CREATE TABLE IF NOT EXISTS conversations (
+                                id BIGSERIAL PRIMARY KEY,
+                                ad_id BIGINT NOT NULL REFERENCES advertisements(id) ON DELETE CASCADE,
+                                buyer_user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+                                seller_user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+                                buyer_blocked_at TIMESTAMPTZ,
+                                seller_blocked_at TIMESTAMPTZ,
+                                created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+                                last_message_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+                                UNIQUE (ad_id, buyer_user_id)
+);
+CREATE INDEX IF NOT EXISTS conversations_buyer ON conversations (buyer_user_id, last_message_at DESC);
+CREATE INDEX IF NOT EXISTS conversations_seller ON conversations (seller_user_id, last_message_at DESC);
+
+-- hidden: сообщение от пользователя с теневым баном, видно только отправителю
+CREATE TABLE IF NOT EXISTS messages (
+                                id BIGSERIAL PRIMARY KEY,
+                                conversation_id BIGINT NOT NULL REFERENCES conversations(id) ON DELETE CASCADE,
+                                sender_user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+                                text TEXT NOT NULL,
+                                hidden BOOLEAN NOT NULL DEFAULT FALSE,
+                                created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+                                read_at TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS messages_conversation ON messages (conversation_id, id);
+
+-- Журнал раскрытий контакта продавца: кто и когда открыл телефон
+CREATE TABLE IF NOT EXISTS contact_reveals (
+                                id BIGSERIAL PRIMARY KEY,
+                                ad_id BIGINT NOT NULL REFERENCES advertisements(id) ON DELETE CASCADE,
+                                viewer_user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+                                via TEXT NOT NULL DEFAULT '',
+                                created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS contact_reveals_ad ON contact_reveals (ad_id);
+
+-- Избранные объявления пользователей
+CREATE TABLE IF NOT EXISTS favorites (
+                                user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+                                ad_id BIGINT NOT NULL REFERENCES advertisements(id) ON DELETE CASCADE,
+                                created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+                                PRIMARY KEY (user_id, ad_id)
+);
+CREATE INDEX IF NOT EXISTS favorites_ad ON favorites (ad_id);
+
+-- Уникальные просмотры: один пользователь учитывается раз в сутки
+CREATE TABLE IF NOT EXISTS ad_views (
+                                ad_id BIGINT NOT NULL REFERENCES advertisements(id) ON DELETE CASCADE,
+                                viewer TEXT NOT NULL,
+                                day DATE NOT NULL,
+                                PRIMARY KEY (ad_id, day, viewer)
+);
+
+-- Дневные счётчики по объявлениям для графиков продавца
+CREATE TABLE IF NOT EXISTS ad_daily_stats (
+                                ad_id BIGINT NOT NULL REFERENCES advertisements(id) ON DELETE CASCADE,
+                                day DATE NOT NULL,
+                                views BIGINT NOT NULL DEFAULT 0,
+                                favorites BIGINT NOT NULL DEFAULT 0,
+                                contact_reveals BIGINT NOT NULL DEFAULT 0,
+                                PRIMARY KEY (ad_id, day)
+);
+
+-- Отзывы покупателей о продавцах. Отзыв привязан к объявлению и переживает
+-- его удаление (ad_title сохраняется).
+-- status: published — виден и учитывается в рейтинге; pending — ждёт модератора
+-- (сработали правила); hidden — скрыт модератором.
+CREATE TABLE IF NOT EXISTS reviews (
+                                id BIGSERIAL PRIMARY KEY,
+                                ad_id BIGINT REFERENCES advertisements(id) ON DELETE SET NULL,
+                                ad_title TEXT NOT NULL,
+                                seller_user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+                                buyer_user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+                                rating SMALLINT NOT NULL CHECK (rating BETWEEN 1 AND 5),
+                                text TEXT NOT NULL DEFAULT '',
+                                reply TEXT NOT NULL DEFAULT '',
+                                replied_at TIMESTAMPTZ,
+                                status TEXT NOT NULL DEFAULT 'published',
+                                flagged_at TIMESTAMPTZ,
+                                flag_reason TEXT NOT NULL DEFAULT '',
+                                moderation_note TEXT NOT NULL DEFAULT '',
+                                moderated_by TEXT NOT NULL DEFAULT '',
+                                moderated_at TIMESTAMPTZ,
+                                created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+                                UNIQUE (ad_id, buyer_user_id)
+);
+CREATE INDEX IF NOT EXISTS reviews_seller ON reviews (seller_user_id, created_at DESC);
+
+-- Платное продвижение объявлений
+CREATE TABLE IF NOT EXISTS ad_promotions (
+                                id BIGSERIAL PRIMARY KEY,
+                                ad_id BIGINT NOT NULL REFERENCES advertisements(id) ON DELETE CASCADE,
+                                package TEXT NOT NULL,
+                                starts_at TIMESTAMPTZ NOT NULL,
+                                ends_at TIMESTAMPTZ NOT NULL,
+                                created_by TEXT NOT NULL DEFAULT '',
+                                created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+                                cancelled_at TIMESTAMPTZ,
+                                CHECK (ends_at > starts_at)
+);
+CREATE INDEX IF NOT EXISTS ad_promotions_ad ON ad_promotions (ad_id, ends_at);
+
+-- Заказы платных услуг и история их статусов
+CREATE TABLE IF NOT EXISTS orders (
+                                id BIGSERIAL PRIMARY KEY,
+                                user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+                                ad_id BIGINT REFERENCES advertisements(id) ON DELETE SET NULL,
+                                product TEXT NOT NULL,
+                                title TEXT NOT NULL,
+                                amount BIGINT NOT NULL CHECK (amount > 0),
+                                currency TEXT NOT NULL,
+                                provider TEXT NOT NULL,
+                                status TEXT NOT NULL DEFAULT 'created',
+                                provider_charge_id TEXT NOT NULL DEFAULT '',
+                                created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+                                updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+                                paid_at TIMESTAMPTZ,
+                                refunded_at TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS orders_user ON orders (user_id, created_at DESC);
+CREATE UNIQUE INDEX IF NOT EXISTS orders_charge ON orders (provider, provider_charge_id)
+    WHERE provider_charge_id <> '';
+
+CREATE TABLE IF NOT EXISTS order_events (
+                                id BIGSERIAL PRIMARY KEY,
+                                order_id BIGINT NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+                                from_status TEXT NOT NULL,
+                                to_status TEXT NOT NULL,
+                                source TEXT NOT NULL,
+                                created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Очередь уведомлений: пишется в одной транзакции с изменением,
+-- доставляется ботом в фоне
+CREATE TABLE IF NOT EXISTS outbox (
+                                id BIGSERIAL PRIMARY KEY,
+                                telegram_id TEXT NOT NULL,
+                                kind TEXT NOT NULL,
+                                text TEXT NOT NULL,
+                                status TEXT NOT NULL DEFAULT 'pending',
+                                attempts INT NOT NULL DEFAULT 0,
+                                next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+                                last_error TEXT NOT NULL DEFAULT '',
+                                created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+                                sent_at TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS outbox_pending ON outbox (next_attempt_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS outbox_recipient ON outbox (telegram_id, id) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS outbox_dead ON outbox (id) WHERE status = 'dead';
+
+-- Пользователь заблокировал бота: уведомления ему не отправляются
+ALTER TABLE users ADD COLUMN IF NOT EXISTS bot_blocked_at TIMESTAMPTZ;
+
+-- Доменные события и их доставка подписчикам (webhooks)
+CREATE TABLE IF NOT EXISTS events (
+                                id BIGSERIAL PRIMARY KEY,
+                                type TEXT NOT NULL,
+                                version INT NOT NULL,
+                                payload JSONB NOT NULL,
+                                created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS events_created ON events (created_at);
+
+CREATE TABLE IF NOT EXISTS webhook_subscriptions (
+                                id BIGSERIAL PRIMARY KEY,
+                                url TEXT NOT NULL,
+                                events TEXT[] NOT NULL,
+                                description TEXT NOT NULL DEFAULT '',
+                                active BOOLEAN NOT NULL DEFAULT TRUE,
+                                secret TEXT NOT NULL,
+                                created_by TEXT NOT NULL DEFAULT '',
+                                created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+                                updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+                                id BIGSERIAL PRIMARY KEY,
+                                subscription_id BIGINT NOT NULL REFERENCES webhook_subscriptions(id) ON DELETE CASCADE,
+                                event_id BIGINT NOT NULL REFERENCES events(id) ON DELETE CASCADE,
+                                status TEXT NOT NULL DEFAULT 'pending',
+                                attempts INT NOT NULL DEFAULT 0,
+                                next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+                                last_status_code INT NOT NULL DEFAULT 0,
+                                last_error TEXT NOT NULL DEFAULT '',
+                                created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+                                delivered_at TIMESTAMPTZ,
+                                UNIQUE (subscription_id, event_id)
+);
+CREATE INDEX IF NOT EXISTS webhook_deliveries_pending ON webhook_deliveries (next_attempt_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS webhook_deliveries_subscription ON webhook_deliveries (subscription_id, id DESC);
+
+CREATE TABLE IF NOT EXISTS webhook_attempts (
+                                id BIGSERIAL PRIMARY KEY,
+                                delivery_id BIGINT NOT NULL REFERENCES webhook_deliveries(id) ON DELETE CASCADE,
+                                started_at TIMESTAMPTZ NOT NULL,
+                                duration_ms BIGINT NOT NULL,
+                                status_code INT NOT NULL DEFAULT 0,
+                                error TEXT NOT NULL DEFAULT '',
+                                response_body TEXT NOT NULL DEFAULT ''
+);
+CREATE INDEX IF NOT EXISTS webhook_attempts_delivery ON webhook_attempts (delivery_id, id);
+
+-- Выгрузки персональных данных: архив собирается обработчиком и хранится до expires_at
+CREATE TABLE IF NOT EXISTS data_exports (
+                                id BIGSERIAL PRIMARY KEY,
+                                user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+                                status TEXT NOT NULL DEFAULT 'pending',
+                                attempts INT NOT NULL DEFAULT 0,
+                                next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+                                error TEXT NOT NULL DEFAULT '',
+                                object_name TEXT NOT NULL DEFAULT '',
+                                size BIGINT NOT NULL DEFAULT 0,
+                                created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+                                finished_at TIMESTAMPTZ,
+                                expires_at TIMESTAMPTZ
+);
+-- Одновременно у пользователя собирается не больше одной выгрузки
+CREATE UNIQUE INDEX IF NOT EXISTS data_exports_active ON data_exports (user_id) WHERE status IN ('pending', 'running');
+CREATE INDEX IF NOT EXISTS data_exports_user ON data_exports (user_id, id DESC);
+CREATE INDEX IF NOT EXISTS data_exports_queue ON data_exports (next_attempt_at) WHERE status IN ('pending', 'running');
+CREATE INDEX IF NOT EXISTS data_exports_ready ON data_exports (expires_at) WHERE status = 'ready';
+
+-- Объявления удаляются вместе с владельцем
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM pg_constraint
+               WHERE conname = 'advertisements_user_id_fkey' AND confdeltype <> 'c') THEN
+        ALTER TABLE advertisements DROP CONSTRAINT advertisements_user_id_fkey;
+        ALTER TABLE advertisements ADD CONSTRAINT advertisements_user_id_fkey
+            FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE;
+    END IF;
+END $$;
+
+-- Объекты хранилища, которые нужно удалить: ставятся в транзакции удаления
+-- данных и удаляются фоновым обработчиком
+CREATE TABLE IF NOT EXISTS storage_deletions (
+                                id BIGSERIAL PRIMARY KEY,
+                                kind TEXT NOT NULL,
+                                object_name TEXT NOT NULL,
+                                status TEXT NOT NULL DEFAULT 'pending',
+                                attempts INT NOT NULL DEFAULT 0,
+                                next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+                                last_error TEXT NOT NULL DEFAULT '',
+                                created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS storage_deletions_pending ON storage_deletions (next_attempt_at) WHERE status = 'pending';
+
+-- Квитанции об удалении аккаунтов; subject_hash — HMAC-SHA256 от telegram_id
+-- с ключом ERASURE_SECRET: удаление подтверждается, идентификатор не хранится,
+-- а без ключа хеш не пересчитать
+CREATE TABLE IF NOT EXISTS account_deletions (
+                                id BIGSERIAL PRIMARY KEY,
+                                subject_hash TEXT NOT NULL,
+                                receipt JSONB NOT NULL,
+                                created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS account_deletions_subject ON account_deletions (subject_hash);
+
+-- Импорт объявлений из файла: файл лежит в хранилище импортов до окончания обработки
+CREATE TABLE IF NOT EXISTS ad_imports (
+                                id BIGSERIAL PRIMARY KEY,
+                                user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+                                status TEXT NOT NULL DEFAULT 'pending',
+                                format TEXT NOT NULL,
+                                filename TEXT NOT NULL DEFAULT '',
+                                source_object TEXT NOT NULL,
+                                total INT NOT NULL DEFAULT 0,
+                                created INT NOT NULL DEFAULT 0,
+                                failed INT NOT NULL DEFAULT 0,
+                                error TEXT NOT NULL DEFAULT '',
+                                attempts INT NOT NULL DEFAULT 0,
+                                next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+                                created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+                                finished_at TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS ad_imports_queue ON ad_imports (next_attempt_at) WHERE status IN ('pending', 'running');
+CREATE INDEX IF NOT EXISTS ad_imports_user ON ad_imports (user_id, id DESC);
+
+-- Результат каждой строки; по нему повторная обработка пропускает готовые строки
+CREATE TABLE IF NOT EXISTS ad_import_rows (
+                                import_id BIGINT NOT NULL REFERENCES ad_imports(id) ON DELETE CASCADE,
+                                line INT NOT NULL,
+                                status TEXT NOT NULL,
+                                ad_id BIGINT REFERENCES advertisements(id) ON DELETE SET NULL,
+                                error TEXT NOT NULL DEFAULT '',
+                                PRIMARY KEY (import_id, line)
+);
@@ -0,0 +1,7 @@
+-- Санкции удалённых пользователей не к чему привязать: они удаляются.
+DELETE FROM user_sanctions WHERE user_id IS NULL;
+ALTER TABLE user_sanctions DROP CONSTRAINT user_sanctions_user_id_fkey;
+ALTER TABLE user_sanctions ADD CONSTRAINT user_sanctions_user_id_fkey
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE;
+ALTER TABLE user_sanctions ALTER COLUMN user_id SET NOT NULL;
+ALTER TABLE user_sanctions DROP COLUMN subject_hash;
@@ -0,0 +1,7 @@
+-- История санкций переживает удаление пользователя: запись отвязывается от
+-- users и хранит хеш telegram_id, как account_deletions.
+ALTER TABLE user_sanctions ADD COLUMN subject_hash TEXT NOT NULL DEFAULT '';
+ALTER TABLE user_sanctions ALTER COLUMN user_id DROP NOT NULL;
+ALTER TABLE user_sanctions DROP CONSTRAINT user_sanctions_user_id_fkey;
+ALTER TABLE user_sanctions ADD CONSTRAINT user_sanctions_user_id_fkey
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE SET NULL;
@@ -0,0 +1,44 @@
+-- Ключи, не помещающиеся в INT, откат не переживут.
+ALTER TABLE user_sanctions
+    ALTER COLUMN id TYPE INT,
+    ALTER COLUMN user_id TYPE INT,
+    ALTER COLUMN expires_at TYPE TIMESTAMP,
+    ALTER COLUMN created_at TYPE TIMESTAMP,
+    ALTER COLUMN lifted_at TYPE TIMESTAMP;
+ALTER SEQUENCE user_sanctions_id_seq AS INT;
+
+ALTER TABLE ad_reports
+    ALTER COLUMN id TYPE INT,
+    ALTER COLUMN ad_id TYPE INT,
+    ALTER COLUMN reporter_user_id TYPE INT,
+    ALTER COLUMN resolved_at TYPE TIMESTAMP,
+    ALTER COLUMN created_at TYPE TIMESTAMP;
+ALTER SEQUENCE ad_reports_id_seq AS INT;
+
+ALTER TABLE moderation_tasks
+    ALTER COLUMN id TYPE INT,
+    ALTER COLUMN ad_id TYPE INT,
+    ALTER COLUMN claimed_at TYPE TIMESTAMP,
+    ALTER COLUMN resolved_at TYPE TIMESTAMP,
+    ALTER COLUMN created_at TYPE TIMESTAMP;
+ALTER SEQUENCE moderation_tasks_id_seq AS INT;
+
+ALTER TABLE api_keys
+    ALTER COLUMN id TYPE INT,
+    ALTER COLUMN created_at TYPE TIMESTAMP,
+    ALTER COLUMN last_used_at TYPE TIMESTAMP,
+    ALTER COLUMN revoked_at TYPE TIMESTAMP;
+ALTER SEQUENCE api_keys_id_seq AS INT;
+
+ALTER TABLE advertisements
+    ALTER COLUMN id TYPE INT,
+    ALTER COLUMN user_id TYPE INT,
+    ALTER COLUMN duplicate_of TYPE INT,
+    ALTER COLUMN created_at TYPE TIMESTAMP,
+    ALTER COLUMN updated_at TYPE TIMESTAMP;
+ALTER SEQUENCE advertisements_id_seq AS INT;
+
+ALTER TABLE users
+    ALTER COLUMN id TYPE INT,
+    ALTER COLUMN created_at TYPE TIMESTAMP;
+ALTER SEQUENCE users_id_seq AS INT;
@@ -0,0 +1,46 @@
+-- Таблицы первых версий переводятся на типы, принятые в остальной схеме:
+-- BIGINT для ключей и ссылок, TIMESTAMPTZ для времени. Значения TIMESTAMP
+-- записывались через now() в часовом поясе сессии и в нём же и читаются.
+ALTER SEQUENCE users_id_seq AS BIGINT;
+ALTER TABLE users
+    ALTER COLUMN id TYPE BIGINT,
+    ALTER COLUMN created_at TYPE TIMESTAMPTZ;
+
+ALTER SEQUENCE advertisements_id_seq AS BIGINT;
+ALTER TABLE advertisements
+    ALTER COLUMN id TYPE BIGINT,
+    ALTER COLUMN user_id TYPE BIGINT,
+    ALTER COLUMN duplicate_of TYPE BIGINT,
+    ALTER COLUMN created_at TYPE TIMESTAMPTZ,
+    ALTER COLUMN updated_at TYPE TIMESTAMPTZ;
+
+ALTER SEQUENCE api_keys_id_seq AS BIGINT;
+ALTER TABLE api_keys
+    ALTER COLUMN id TYPE BIGINT,
+    ALTER COLUMN created_at TYPE TIMESTAMPTZ,
+    ALTER COLUMN last_used_at TYPE TIMESTAMPTZ,
+    ALTER COLUMN revoked_at TYPE TIMESTAMPTZ;
+
+ALTER SEQUENCE moderation_tasks_id_seq AS BIGINT;
+ALTER TABLE moderation_tasks
+    ALTER COLUMN id TYPE BIGINT,
+    ALTER COLUMN ad_id TYPE BIGINT,
+    ALTER COLUMN claimed_at TYPE TIMESTAMPTZ,
+    ALTER COLUMN resolved_at TYPE TIMESTAMPTZ,
+    ALTER COLUMN created_at TYPE TIMESTAMPTZ;
+
+ALTER SEQUENCE ad_reports_id_seq AS BIGINT;
+ALTER TABLE ad_reports
+    ALTER COLUMN id TYPE BIGINT,
+    ALTER COLUMN ad_id TYPE BIGINT,
+    ALTER COLUMN reporter_user_id TYPE BIGINT,
+    ALTER COLUMN resolved_at TYPE TIMESTAMPTZ,
+    ALTER COLUMN created_at TYPE TIMESTAMPTZ;
+
+ALTER SEQUENCE user_sanctions_id_seq AS BIGINT;
+ALTER TABLE user_sanctions
+    ALTER COLUMN id TYPE BIGINT,
+    ALTER COLUMN user_id TYPE BIGINT,
+    ALTER COLUMN expires_at TYPE TIMESTAMPTZ,
+    ALTER COLUMN created_at TYPE TIMESTAMPTZ,
+    ALTER COLUMN lifted_at TYPE TIMESTAMPTZ;
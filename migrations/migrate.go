@@ -0,0 +1,278 @@
+// Package migrations применяет к базе нумерованные миграции, встроенные в
+// бинарник. Миграция — пара файлов NNNN_название.up.sql и
+// NNNN_название.down.sql; применённые записываются в schema_migrations вместе
+// с контрольной суммой, поэтому изменить уже применённую миграцию нельзя —
+// изменения схемы оформляются новой миграцией.
+package migrations
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"embed"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:embed *.sql
+var files embed.FS
+
+// lockKey — ключ advisory lock, под которым выполняются миграции: два
+// экземпляра, запущенные одновременно, применяют их по очереди.
+const lockKey = 7364012591
+
+type Migration struct {
+	Version  int64
+	Name     string
+	Up       string
+	Down     string
+	Checksum string
+}
+
+// Status — состояние миграции в базе.
+type Status struct {
+	Version   int64
+	Name      string
+	AppliedAt *time.Time
+	// Changed — файл миграции изменён после применения.
+	Changed bool
+	// Missing — миграция применена, но её нет в бинарнике.
+	Missing bool
+}
+
+// Load читает миграции из fsys, упорядоченные по версии.
+func Load(fsys fs.FS) ([]*Migration, error) {
+	names, err := fs.Glob(fsys, "*.sql")
+	if err != nil {
+		return nil, err
+	}
+	byVersion := map[int64]*Migration{}
+	for _, name := range names {
+		base := strings.TrimSuffix(name, ".sql")
+		direction := path.Ext(base)
+		base = strings.TrimSuffix(base, direction)
+		num, title, ok := strings.Cut(base, "_")
+		version, err := strconv.ParseInt(num, 10, 64)
+		if !ok || err != nil || version <= 0 || (direction != ".up" && direction != ".down") {
+			return nil, fmt.Errorf("migration %s: name must be NNNN_name.up.sql or NNNN_name.down.sql", name)
+		}
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return nil, err
+		}
+		m := byVersion[version]
+		if m == nil {
+			m = &Migration{Version: version, Name: title}
+			byVersion[version] = m
+		} else if m.Name != title {
+			return nil, fmt.Errorf("migration %d has two names: %s and %s", version, m.Name, title)
+		}
+		if direction == ".up" {
+			m.Up = string(data)
+			sum := sha256.Sum256(data)
+			m.Checksum = hex.EncodeToString(sum[:])
+		} else {
+			m.Down = string(data)
+		}
+	}
+	list := make([]*Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up file", m.Version, m.Name)
+		}
+		list = append(list, m)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Version < list[j].Version })
+	return list, nil
+}
+
+type Migrator struct {
+	DB         *sql.DB
+	Migrations []*Migration
+}
+
+// New возвращает мигратор со встроенными миграциями.
+func New(db *sql.DB) (*Migrator, error) {
+	list, err := Load(files)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{DB: db, Migrations: list}, nil
+}
+
+type applied struct {
+	name      string
+	checksum  string
+	appliedAt time.Time
+}
+
+// locked выполняет fn на отдельном соединении под advisory lock: блокировка
+// сессионная, поэтому все запросы миграций идут через одно соединение.
+func (m *Migrator) locked(fn func(conn *sql.Conn, done map[int64]applied) error) error {
+	ctx := context.Background()
+	conn, err := m.DB.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, lockKey); err != nil {
+		return fmt.Errorf("acquire migration lock: %w", err)
+	}
+	defer conn.ExecContext(ctx, `SELECT pg_advisory_unlock($1)`, lockKey)
+
+	if _, err := conn.ExecContext(ctx,
+		`CREATE TABLE IF NOT EXISTS schema_migrations (
+             version BIGINT PRIMARY KEY,
+             name TEXT NOT NULL,
+             checksum TEXT NOT NULL,
+             applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+         )`); err != nil {
+		return fmt.Errorf("create schema_migrations: %w", err)
+	}
+	rows, err := conn.QueryContext(ctx, `SELECT version, name, checksum, applied_at FROM schema_migrations`)
+	if err != nil {
+		return fmt.Errorf("list applied migrations: %w", err)
+	}
+	defer rows.Close()
+	done := map[int64]applied{}
+	for rows.Next() {
+		var v int64
+		var a applied
+		if err := rows.Scan(&v, &a.name, &a.checksum, &a.appliedAt); err != nil {
+			return err
+		}
+		done[v] = a
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	rows.Close()
+	return fn(conn, done)
+}
+
+// Up применяет все неприменённые миграции по порядку, каждую в своей
+// транзакции, и возвращает применённые. Если применённая миграция была
+// изменена или новая миграция старше уже применённой, ничего не применяется.
+func (m *Migrator) Up() ([]*Migration, error) {
+	var ran []*Migration
+	err := m.locked(func(conn *sql.Conn, done map[int64]applied) error {
+		var latest int64
+		for v := range done {
+			latest = max(latest, v)
+		}
+		var pending []*Migration
+		for _, mg := range m.Migrations {
+			a, ok := done[mg.Version]
+			switch {
+			case ok && a.checksum != mg.Checksum:
+				return fmt.Errorf("migration %d_%s was changed after it was applied", mg.Version, mg.Name)
+			case ok:
+			case mg.Version < latest:
+				return fmt.Errorf("migration %d_%s is older than applied migration %d", mg.Version, mg.Name, latest)
+			default:
+				pending = append(pending, mg)
+			}
+		}
+		for _, mg := range pending {
+			err := inTx(conn, func(tx *sql.Tx) error {
+				if _, err := tx.Exec(mg.Up); err != nil {
+					return err
+				}
+				_, err := tx.Exec(
+					`INSERT INTO schema_migrations (version, name, checksum) VALUES ($1, $2, $3)`,
+					mg.Version, mg.Name, mg.Checksum)
+				return err
+			})
+			if err != nil {
+				return fmt.Errorf("migration %d_%s: %w", mg.Version, mg.Name, err)
+			}
+			ran = append(ran, mg)
+		}
+		return nil
+	})
+	return ran, err
+}
+
+// Down откатывает n последних применённых миграций и возвращает откаченные.
+// Откат первой миграции удаляет все данные, поэтому без force он
+// запрещён, и тогда не откатывается ничего.
+func (m *Migrator) Down(n int, force bool) ([]*Migration, error) {
+	var ran []*Migration
+	err := m.locked(func(conn *sql.Conn, done map[int64]applied) error {
+		var plan []*Migration
+		for i := len(m.Migrations) - 1; i >= 0 && len(plan) < n; i-- {
+			mg := m.Migrations[i]
+			if _, ok := done[mg.Version]; !ok {
+				continue
+			}
+			if mg.Down == "" {
+				return fmt.Errorf("migration %d_%s has no down file", mg.Version, mg.Name)
+			}
+			if i == 0 && !force {
+				return fmt.Errorf("rolling back %d_%s drops the whole schema; use -force", mg.Version, mg.Name)
+			}
+			plan = append(plan, mg)
+		}
+		for _, mg := range plan {
+			err := inTx(conn, func(tx *sql.Tx) error {
+				if _, err := tx.Exec(mg.Down); err != nil {
+					return err
+				}
+				_, err := tx.Exec(`DELETE FROM schema_migrations WHERE version = $1`, mg.Version)
+				return err
+			})
+			if err != nil {
+				return fmt.Errorf("migration %d_%s: %w", mg.Version, mg.Name, err)
+			}
+			ran = append(ran, mg)
+		}
+		return nil
+	})
+	return ran, err
+}
+
+// Status возвращает состояние всех миграций — из бинарника и из базы — по
+// порядку версий.
+func (m *Migrator) Status() ([]*Status, error) {
+	var list []*Status
+	err := m.locked(func(conn *sql.Conn, done map[int64]applied) error {
+		for _, mg := range m.Migrations {
+			s := &Status{Version: mg.Version, Name: mg.Name}
+			if a, ok := done[mg.Version]; ok {
+				s.AppliedAt = &a.appliedAt
+				s.Changed = a.checksum != mg.Checksum
+				delete(done, mg.Version)
+			}
+			list = append(list, s)
+		}
+		for v, a := range done {
+			list = append(list, &Status{Version: v, Name: a.name, AppliedAt: &a.appliedAt, Missing: true})
+		}
+		sort.Slice(list, func(i, j int) bool { return list[i].Version < list[j].Version })
+		return nil
+	})
+	return list, err
+}
+
+// inTx — как repository.WithTx, но на выделенном соединении.
+func inTx(conn *sql.Conn, fn func(tx *sql.Tx) error) error {
+	tx, err := conn.BeginTx(context.Background(), nil)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+package migrations
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestLoad(t *testing.T) {
+	file := func(s string) *fstest.MapFile { return &fstest.MapFile{Data: []byte(s)} }
+	tests := []struct {
+		name     string
+		fs       fstest.MapFS
+		want     []string
+		wantDown []bool
+		wantErr  string
+	}{
+		{
+			name: "ordered by version",
+			fs: fstest.MapFS{
+				"0010_ten.up.sql":       file("SELECT 10"),
+				"0002_two.up.sql":       file("SELECT 2"),
+				"0002_two.down.sql":     file("SELECT -2"),
+				"0001_initial.up.sql":   file("SELECT 1"),
+				"README.md":             file("not a migration"),
+				"0001_initial.down.sql": file("SELECT -1"),
+			},
+			want:     []string{"1 initial", "2 two", "10 ten"},
+			wantDown: []bool{true, true, false},
+		},
+		{
+			name: "underscores in name",
+			fs:   fstest.MapFS{"0003_add_user_index.up.sql": file("SELECT 1")},
+			want: []string{"3 add_user_index"},
+		},
+		{name: "empty", fs: fstest.MapFS{}, want: []string{}},
+		{
+			name:    "no version",
+			fs:      fstest.MapFS{"initial.up.sql": file("SELECT 1")},
+			wantErr: "name must be",
+		},
+		{
+			name:    "version not a number",
+			fs:      fstest.MapFS{"v1_initial.up.sql": file("SELECT 1")},
+			wantErr: "name must be",
+		},
+		{
+			name:    "zero version",
+			fs:      fstest.MapFS{"0000_initial.up.sql": file("SELECT 1")},
+			wantErr: "name must be",
+		},
+		{
+			name:    "no direction",
+			fs:      fstest.MapFS{"0001_initial.sql": file("SELECT 1")},
+			wantErr: "name must be",
+		},
+		{
+			name:    "unknown direction",
+			fs:      fstest.MapFS{"0001_initial.redo.sql": file("SELECT 1")},
+			wantErr: "name must be",
+		},
+		{
+			name:    "down without up",
+			fs:      fstest.MapFS{"0001_initial.down.sql": file("SELECT 1")},
+			wantErr: "has no up file",
+		},
+		{
+			name: "two names for a version",
+			fs: fstest.MapFS{
+				"0001_initial.up.sql": file("SELECT 1"),
+				"0001_first.down.sql": file("SELECT -1"),
+			},
+			wantErr: "has two names",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			list, err := Load(tt.fs)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := make([]string, len(list))
+			for i, m := range list {
+				got[i] = fmt.Sprintf("%d %s", m.Version, m.Name)
+				if m.Checksum == "" {
+					t.Errorf("migration %d has no checksum", m.Version)
+				}
+				if tt.wantDown != nil && (m.Down != "") != tt.wantDown[i] {
+					t.Errorf("migration %d has down = %q", m.Version, m.Down)
+				}
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("Load = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// Встроенные миграции должны загружаться и идти подряд с первой версии.
+func TestEmbedded(t *testing.T) {
+	list, err := Load(files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, m := range list {
+		if m.Version != int64(i+1) {
+			t.Errorf("migration %d_%s: want version %d", m.Version, m.Name, i+1)
+		}
+		if m.Down == "" {
+			t.Errorf("migration %d_%s has no down file", m.Version, m.Name)
+		}
+	}
+}
@@ -0,0 +1,26 @@
+// Package moderation решает, какие объявления проходят премодерацию.
+package moderation
+
+// Policy — режим премодерации: для всех объявлений или для выбранных категорий.
+type Policy struct {
+	All        bool
+	Categories map[string]bool
+}
+
+// NewPolicy строит политику из конфигурации. Категория "*" включает
+// премодерацию для всех объявлений.
+func NewPolicy(categories []string) Policy {
+	p := Policy{Categories: make(map[string]bool, len(categories))}
+	for _, c := range categories {
+		if c == "*" {
+			p.All = true
+		}
+		p.Categories[c] = true
+	}
+	return p
+}
+
+// Requires сообщает, должно ли объявление категории category пройти премодерацию.
+func (p Policy) Requires(category string) bool {
+	return p.All || p.Categories[category]
+}
@@ -0,0 +1,85 @@
+// Package notify доставляет пользователям сообщения через Telegram-бота.
+package notify
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Notifier отправляет текстовое сообщение пользователю Telegram.
+type Notifier interface {
+	Notify(telegramID, text string) error
+}
+
+var (
+	// ErrUnreachable — пользователь заблокировал бота, удалил аккаунт или
+	// ни разу не запускал бота; повторять отправку бессмысленно.
+	ErrUnreachable = errors.New("recipient unreachable")
+	// ErrRejected — Telegram отклонил сообщение (например, слишком длинное).
+	ErrRejected = errors.New("message rejected")
+)
+
+// RetryAfterError — Telegram просит подождать перед следующей отправкой.
+type RetryAfterError struct {
+	After time.Duration
+}
+
+func (e *RetryAfterError) Error() string {
+	return fmt.Sprintf("flood control, retry after %s", e.After)
+}
+
+// Telegram отправляет сообщения от имени бота. В личном чате chat_id
+// совпадает с telegram_id пользователя.
+type Telegram struct {
+	Bot *tgbotapi.BotAPI
+}
+
+func NewTelegram(token string) (*Telegram, error) {
+	bot, err := tgbotapi.NewBotAPI(token)
+	if err != nil {
+		return nil, fmt.Errorf("telegram bot: %w", err)
+	}
+	return &Telegram{Bot: bot}, nil
+}
+
+func (t *Telegram) Notify(telegramID, text string) error {
+	chatID, err := strconv.ParseInt(telegramID, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid telegram id %q: %w", telegramID, err)
+	}
+	_, err = t.Bot.Send(tgbotapi.NewMessage(chatID, text))
+	return classify(err)
+}
+
+// classify переводит ошибки Bot API в ErrUnreachable, ErrRejected и
+// RetryAfterError; сетевые ошибки и ошибки сервера остаются как есть.
+func classify(err error) error {
+	var apiErr *tgbotapi.Error
+	if !errors.As(err, &apiErr) {
+		return err
+	}
+	switch {
+	case apiErr.Code == 403,
+		apiErr.Code == 400 && strings.Contains(apiErr.Message, "chat not found"):
+		return fmt.Errorf("%w: %s", ErrUnreachable, apiErr.Message)
+	case apiErr.Code == 429:
+		return &RetryAfterError{After: time.Duration(apiErr.RetryAfter) * time.Second}
+	case apiErr.Code == 400:
+		return fmt.Errorf("%w: %s", ErrRejected, apiErr.Message)
+	}
+	return err
+}
+
+// Log пишет сообщения в лог вместо отправки (локальная разработка без токена бота).
+type Log struct{}
+
+func (Log) Notify(telegramID, text string) error {
+	log.Printf("notify %s: %s", telegramID, text)
+	return nil
+}
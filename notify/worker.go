@@ -0,0 +1,113 @@
+package notify
+
+import (
+	"errors"
+	"log"
+	"math/rand"
+	"poppins/domain"
+	"poppins/repository"
+	"time"
+)
+
+// Worker доставляет уведомления из outbox. Неудачная попытка повторяется с
+// экспоненциальной задержкой; после MaxAttempts попыток, при отказе Telegram
+// или блокировке бота пользователем уведомление уходит в dead.
+// Несколько обработчиков могут работать параллельно.
+type Worker struct {
+	Repo        *repository.OutboxRepo
+	Notifier    Notifier
+	MaxAttempts int
+	// BaseBackoff — задержка перед второй попыткой; каждая следующая вдвое
+	// дольше, но не больше MaxBackoff.
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+	Batch       int
+	// Lease — сколько уведомление закреплено за обработчиком во время отправки.
+	Lease time.Duration
+}
+
+func NewWorker(repo *repository.OutboxRepo, n Notifier, maxAttempts int) *Worker {
+	return &Worker{
+		Repo:        repo,
+		Notifier:    n,
+		MaxAttempts: maxAttempts,
+		BaseBackoff: 30 * time.Second,
+		MaxBackoff:  6 * time.Hour,
+		Batch:       50,
+		Lease:       time.Minute,
+	}
+}
+
+// Run опрашивает outbox каждые every; полная пачка забирается сразу, без ожидания.
+func (w *Worker) Run(every time.Duration) {
+	for {
+		n, err := w.DeliverBatch()
+		if err != nil {
+			log.Printf("outbox: %v", err)
+		}
+		if err != nil || n < w.Batch {
+			time.Sleep(every)
+		}
+	}
+}
+
+// DeliverBatch отправляет одну пачку уведомлений и возвращает её размер.
+func (w *Worker) DeliverBatch() (int, error) {
+	batch, err := w.Repo.Claim(w.Batch, w.Lease)
+	if err != nil {
+		return 0, err
+	}
+	for _, n := range batch {
+		w.deliver(n)
+	}
+	return len(batch), nil
+}
+
+func (w *Worker) deliver(n *domain.Notification) {
+	err := w.Notifier.Notify(n.TelegramID, n.Text)
+	var retryAfter *RetryAfterError
+	switch {
+	case err == nil:
+		err = w.Repo.MarkSent(n.ID)
+	case errors.Is(err, ErrUnreachable):
+		log.Printf("outbox: %s is unreachable, dropping notifications: %v", n.TelegramID, err)
+		if err = w.Repo.SetBotBlocked(n.TelegramID, true); errors.Is(err, repository.ErrNotFound) {
+			err = w.Repo.Dead(n.ID, "recipient unreachable")
+		}
+	case errors.Is(err, ErrRejected):
+		log.Printf("outbox: notification %d rejected: %v", n.ID, err)
+		err = w.Repo.Dead(n.ID, err.Error())
+	case n.Attempts >= w.MaxAttempts:
+		log.Printf("outbox: notification %d failed after %d attempts: %v", n.ID, n.Attempts, err)
+		err = w.Repo.Dead(n.ID, err.Error())
+	case errors.As(err, &retryAfter):
+		err = w.Repo.Retry(n.ID, time.Now().Add(retryAfter.After), err.Error())
+	default:
+		err = w.Repo.Retry(n.ID, time.Now().Add(w.backoff(n.Attempts)), err.Error())
+	}
+	if err != nil {
+		log.Printf("outbox: update notification %d: %v", n.ID, err)
+	}
+}
+
+// backoff — задержка после attempt неудачных попыток, со случайным разбросом
+// ±20%, чтобы повторы не приходили одновременно.
+func (w *Worker) backoff(attempt int) time.Duration {
+	d := w.BaseBackoff
+	for i := 1; i < attempt && d < w.MaxBackoff; i++ {
+		d *= 2
+	}
+	d = min(d, w.MaxBackoff)
+	return d + time.Duration((rand.Float64()*0.4-0.2)*float64(d))
+}
+
+// PurgeLoop периодически удаляет доставленные уведомления старше retention.
+func (w *Worker) PurgeLoop(every, retention time.Duration) {
+	for range time.Tick(every) {
+		if n, err := w.Repo.PurgeSent(time.Now().Add(-retention)); err != nil {
+			log.Printf("purge outbox: %v", err)
+		} else if n > 0 {
+			log.Printf("purged %d delivered notifications", n)
+		}
+	}
+}
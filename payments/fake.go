@@ -0,0 +1,72 @@
+package payments
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"poppins/domain"
+)
+
+// Fake — провайдер для локальной разработки и тестов: счёт ничего не
+// списывает, оплату имитирует POST /payments/fake/webhook с телом
+// {"order_id": 1, "event": "paid", "amount": 19900, "currency": "RUB"}.
+type Fake struct {
+	// Secret сверяется с заголовком X-Fake-Secret.
+	Secret string
+}
+
+func NewFake(secret string) (*Fake, error) {
+	if secret == "" {
+		return nil, ErrNoSecret
+	}
+	return &Fake{Secret: secret}, nil
+}
+
+func (f *Fake) Name() string { return "fake" }
+
+func (f *Fake) CreateInvoice(o *domain.Order) (*Invoice, error) {
+	return &Invoice{URL: fmt.Sprintf("fake://pay/%d", o.ID)}, nil
+}
+
+// fakeEvent — тело уведомления фейкового провайдера.
+type fakeEvent struct {
+	OrderID  int64  `json:"order_id"`
+	Event    string `json:"event"`
+	Amount   int64  `json:"amount"`
+	Currency string `json:"currency"`
+	ChargeID string `json:"charge_id"`
+}
+
+func (f *Fake) ParseCallback(r *http.Request) (*Callback, error) {
+	if f.Secret == "" || subtle.ConstantTimeCompare([]byte(r.Header.Get("X-Fake-Secret")), []byte(f.Secret)) != 1 {
+		return nil, fmt.Errorf("bad secret: %w", ErrInvalidCallback)
+	}
+	var ev fakeEvent
+	if err := json.NewDecoder(r.Body).Decode(&ev); err != nil {
+		return nil, fmt.Errorf("decode event: %v: %w", err, ErrInvalidCallback)
+	}
+	switch ev.Event {
+	case CallbackConfirm, CallbackPaid, CallbackFailed:
+	default:
+		return nil, fmt.Errorf("unknown event %q: %w", ev.Event, ErrInvalidCallback)
+	}
+	if ev.ChargeID == "" && ev.Event == CallbackPaid {
+		ev.ChargeID = fmt.Sprintf("fake-%d", ev.OrderID)
+	}
+	return &Callback{
+		Kind: ev.Event, OrderID: ev.OrderID, Amount: ev.Amount,
+		Currency: ev.Currency, ChargeID: ev.ChargeID,
+	}, nil
+}
+
+func (f *Fake) Confirm(cb *Callback, ok bool, reason string) error {
+	log.Printf("fake payments: confirm order %d: ok=%v %s", cb.OrderID, ok, reason)
+	return nil
+}
+
+func (f *Fake) Refund(o *domain.Order) error {
+	log.Printf("fake payments: refund order %d (%d %s)", o.ID, o.Amount, o.Currency)
+	return nil
+}
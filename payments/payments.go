@@ -0,0 +1,62 @@
+// Package payments выставляет счета и принимает уведомления платёжных провайдеров.
+package payments
+
+import (
+	"errors"
+	"net/http"
+	"poppins/domain"
+)
+
+// Виды уведомлений провайдера.
+const (
+	// CallbackConfirm — провайдер спрашивает, можно ли принять оплату
+	// (pre-checkout); ответ — через Provider.Confirm.
+	CallbackConfirm = "confirm"
+	// CallbackPaid — оплата прошла.
+	CallbackPaid = "paid"
+	// CallbackFailed — оплата не прошла.
+	CallbackFailed = "failed"
+)
+
+var (
+	// ErrRefundUnsupported — провайдер не умеет возвращать деньги через API;
+	// возврат проводится в кабинете провайдера и отмечается вручную.
+	ErrRefundUnsupported = errors.New("refunds are not supported by the provider")
+	// ErrInvalidCallback — уведомление не прошло проверку подлинности или не разобрано.
+	ErrInvalidCallback = errors.New("invalid payment callback")
+	// ErrNoSecret — секрет уведомлений не задан: без него webhook принял бы
+	// поддельную оплату от кого угодно.
+	ErrNoSecret = errors.New("PAYMENTS_WEBHOOK_SECRET is required")
+)
+
+// Invoice — выставленный счёт. URL пустой, если счёт доставлен пользователю
+// самим провайдером (например, сообщением в Telegram).
+type Invoice struct {
+	URL string `json:"url,omitempty"`
+}
+
+// Callback — разобранное уведомление провайдера.
+type Callback struct {
+	Kind     string
+	OrderID  int64
+	Amount   int64
+	Currency string
+	ChargeID string
+	// Ref — идентификатор запроса подтверждения у провайдера.
+	Ref string
+}
+
+// Provider — платёжный провайдер.
+type Provider interface {
+	// Name — имя провайдера в заказах и в пути webhook.
+	Name() string
+	// CreateInvoice выставляет счёт на заказ.
+	CreateInvoice(o *domain.Order) (*Invoice, error)
+	// ParseCallback проверяет и разбирает уведомление. nil без ошибки —
+	// уведомление не относится к платежам и игнорируется.
+	ParseCallback(r *http.Request) (*Callback, error)
+	// Confirm отвечает на запрос подтверждения оплаты.
+	Confirm(cb *Callback, ok bool, reason string) error
+	// Refund возвращает деньги за оплаченный заказ.
+	Refund(o *domain.Order) error
+}
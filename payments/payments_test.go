@@ -0,0 +1,92 @@
+package payments
+
+import (
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNewRequiresSecret(t *testing.T) {
+	if _, err := NewFake(""); !errors.Is(err, ErrNoSecret) {
+		t.Errorf("NewFake(\"\") error = %v, want ErrNoSecret", err)
+	}
+	if _, err := NewTelegram("token", "provider", ""); !errors.Is(err, ErrNoSecret) {
+		t.Errorf("NewTelegram with empty secret error = %v, want ErrNoSecret", err)
+	}
+}
+
+func TestFakeParseCallback(t *testing.T) {
+	const body = `{"order_id": 7, "event": "paid", "amount": 19900, "currency": "RUB"}`
+	tests := []struct {
+		name    string
+		secret  string
+		header  string
+		wantErr bool
+	}{
+		{"valid secret", "s3cret", "s3cret", false},
+		{"no header", "s3cret", "", true},
+		{"wrong secret", "s3cret", "guess", true},
+		{"prefix of secret", "s3cret", "s3c", true},
+		{"provider without secret", "", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("POST", "/payments/fake/webhook", strings.NewReader(body))
+			if tt.header != "" {
+				r.Header.Set("X-Fake-Secret", tt.header)
+			}
+			cb, err := (&Fake{Secret: tt.secret}).ParseCallback(r)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidCallback) {
+					t.Fatalf("error = %v, want ErrInvalidCallback", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cb.Kind != CallbackPaid || cb.OrderID != 7 || cb.Amount != 19900 || cb.Currency != "RUB" {
+				t.Errorf("callback = %+v", cb)
+			}
+		})
+	}
+}
+
+func TestTelegramParseCallback(t *testing.T) {
+	const body = `{"update_id": 1, "message": {"message_id": 2, "date": 0, "chat": {"id": 3, "type": "private"},
+		"successful_payment": {"currency": "RUB", "total_amount": 19900, "invoice_payload": "7",
+		"telegram_payment_charge_id": "tg-1", "provider_payment_charge_id": "p-1"}}}`
+	tests := []struct {
+		name    string
+		secret  string
+		header  string
+		wantErr bool
+	}{
+		{"valid secret", "s3cret", "s3cret", false},
+		{"no header", "s3cret", "", true},
+		{"wrong secret", "s3cret", "S3CRET", true},
+		{"provider without secret", "", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("POST", "/payments/telegram/webhook", strings.NewReader(body))
+			if tt.header != "" {
+				r.Header.Set("X-Telegram-Bot-Api-Secret-Token", tt.header)
+			}
+			cb, err := (&Telegram{Secret: tt.secret}).ParseCallback(r)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidCallback) {
+					t.Fatalf("error = %v, want ErrInvalidCallback", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cb.Kind != CallbackPaid || cb.OrderID != 7 || cb.ChargeID != "tg-1" || cb.Amount != 19900 {
+				t.Errorf("callback = %+v", cb)
+			}
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package payments
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"poppins/domain"
+	"strconv"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Telegram принимает оплату через Telegram Payments: счёт приходит
+// пользователю сообщением от бота. Бот получает pre_checkout_query и
+// successful_payment в своих обновлениях и пересылает их как есть
+// на POST /payments/telegram/webhook.
+type Telegram struct {
+	Bot           *tgbotapi.BotAPI
+	ProviderToken string
+	// Secret сверяется с заголовком X-Telegram-Bot-Api-Secret-Token.
+	Secret string
+}
+
+func NewTelegram(botToken, providerToken, secret string) (*Telegram, error) {
+	if secret == "" {
+		return nil, ErrNoSecret
+	}
+	bot, err := tgbotapi.NewBotAPI(botToken)
+	if err != nil {
+		return nil, fmt.Errorf("telegram bot: %w", err)
+	}
+	return &Telegram{Bot: bot, ProviderToken: providerToken, Secret: secret}, nil
+}
+
+func (t *Telegram) Name() string { return "telegram" }
+
+func (t *Telegram) CreateInvoice(o *domain.Order) (*Invoice, error) {
+	chatID, err := strconv.ParseInt(o.TelegramID, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid telegram id %q: %w", o.TelegramID, err)
+	}
+	inv := tgbotapi.NewInvoice(chatID, o.Title, fmt.Sprintf("Заказ №%d", o.ID),
+		strconv.FormatInt(o.ID, 10), t.ProviderToken, "", o.Currency,
+		[]tgbotapi.LabeledPrice{{Label: o.Title, Amount: int(o.Amount)}})
+	// Без пустого списка библиотека отправляет suggested_tip_amounts=null,
+	// и Telegram отклоняет счёт
+	inv.SuggestedTipAmounts = []int{}
+	if _, err := t.Bot.Send(inv); err != nil {
+		return nil, fmt.Errorf("send invoice: %w", err)
+	}
+	return &Invoice{}, nil
+}
+
+func (t *Telegram) ParseCallback(r *http.Request) (*Callback, error) {
+	if t.Secret == "" ||
+		subtle.ConstantTimeCompare([]byte(r.Header.Get("X-Telegram-Bot-Api-Secret-Token")), []byte(t.Secret)) != 1 {
+		return nil, fmt.Errorf("bad secret token: %w", ErrInvalidCallback)
+	}
+	var upd tgbotapi.Update
+	if err := json.NewDecoder(r.Body).Decode(&upd); err != nil {
+		return nil, fmt.Errorf("decode update: %v: %w", err, ErrInvalidCallback)
+	}
+
+	switch {
+	case upd.PreCheckoutQuery != nil:
+		q := upd.PreCheckoutQuery
+		id, err := strconv.ParseInt(q.InvoicePayload, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invoice payload %q: %w", q.InvoicePayload, ErrInvalidCallback)
+		}
+		return &Callback{
+			Kind: CallbackConfirm, OrderID: id, Ref: q.ID,
+			Amount: int64(q.TotalAmount), Currency: q.Currency,
+		}, nil
+	case upd.Message != nil && upd.Message.SuccessfulPayment != nil:
+		p := upd.Message.SuccessfulPayment
+		id, err := strconv.ParseInt(p.InvoicePayload, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invoice payload %q: %w", p.InvoicePayload, ErrInvalidCallback)
+		}
+		return &Callback{
+			Kind: CallbackPaid, OrderID: id, ChargeID: p.TelegramPaymentChargeID,
+			Amount: int64(p.TotalAmount), Currency: p.Currency,
+		}, nil
+	}
+	return nil, nil
+}
+
+// Confirm отвечает на pre_checkout_query. Telegram ждёт ответ не дольше 10 секунд.
+func (t *Telegram) Confirm(cb *Callback, ok bool, reason string) error {
+	_, err := t.Bot.Request(tgbotapi.PreCheckoutConfig{
+		PreCheckoutQueryID: cb.Ref, OK: ok, ErrorMessage: reason,
+	})
+	return err
+}
+
+// Refund: возвраты Telegram Payments проводятся в кабинете платёжного провайдера.
+func (t *Telegram) Refund(o *domain.Order) error {
+	return ErrRefundUnsupported
+}
@@ -0,0 +1,268 @@
+// Package ratelimit ограничивает частоту запросов по пользователю и по IP.
+//
+// Используется token bucket в форме GCRA: вместо числа жетонов хранится
+// теоретическое время прихода следующего запроса (TAT), что позволяет
+// обновлять ведро одним атомарным запросом к Postgres.
+package ratelimit
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"poppins/repository"
+)
+
+// Политики ограничений.
+const (
+	Create  = "create"
+	Edit    = "edit"
+	Search  = "search"
+	Contact = "contact"
+)
+
+// UserKeys — ключи ведер пользователя telegramID во всех политиках, в том
+// же виде, что пишет Middleware.
+func UserKeys(telegramID string) []string {
+	policies := []string{Create, Edit, Search, Contact}
+	keys := make([]string, 0, len(policies))
+	for _, p := range policies {
+		keys = append(keys, p+":user:"+telegramID)
+	}
+	return keys
+}
+
+// Limit — Burst запросов за Period; ведро пополняется равномерно.
+type Limit struct {
+	Burst  int
+	Period time.Duration
+}
+
+// ParseLimit разбирает лимит вида "10/1h". Пустая строка или "off" — без ограничения.
+func ParseLimit(s string) (Limit, error) {
+	if s == "" || s == "off" {
+		return Limit{}, nil
+	}
+	n, d, ok := strings.Cut(s, "/")
+	if !ok {
+		return Limit{}, fmt.Errorf("rate limit %q: want N/duration", s)
+	}
+	burst, err := strconv.Atoi(n)
+	if err != nil || burst <= 0 {
+		return Limit{}, fmt.Errorf("rate limit %q: invalid count", s)
+	}
+	period, err := time.ParseDuration(d)
+	if err != nil || period <= 0 {
+		return Limit{}, fmt.Errorf("rate limit %q: invalid period", s)
+	}
+	return Limit{Burst: burst, Period: period}, nil
+}
+
+func (l Limit) enabled() bool { return l.Burst > 0 && l.Period > 0 }
+
+// interval — время восстановления одного жетона.
+func (l Limit) interval() time.Duration { return l.Period / time.Duration(l.Burst) }
+
+// Policy — лимиты одного вида запросов для пользователя и для IP.
+type Policy struct {
+	Name string
+	User Limit
+	IP   Limit
+}
+
+// Decision — результат проверки одного ведра.
+type Decision struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	Reset      time.Duration
+	RetryAfter time.Duration
+}
+
+// Limiter проверяет запросы по политикам.
+type Limiter struct {
+	Repo     *repository.RateLimitRepo
+	Policies map[string]Policy
+	// TrustedIPs не ограничиваются по IP (например, сервер бота, через
+	// который идут запросы всех пользователей). Лимит по пользователю остаётся.
+	TrustedIPs map[string]bool
+	// TrustForwarded берёт IP клиента из X-Forwarded-For (API за прокси).
+	TrustForwarded bool
+}
+
+func NewLimiter(repo *repository.RateLimitRepo, policies []Policy, trustedIPs []string, trustForwarded bool) *Limiter {
+	l := &Limiter{
+		Repo:           repo,
+		Policies:       make(map[string]Policy, len(policies)),
+		TrustedIPs:     make(map[string]bool, len(trustedIPs)),
+		TrustForwarded: trustForwarded,
+	}
+	for _, p := range policies {
+		l.Policies[p.Name] = p
+	}
+	for _, ip := range trustedIPs {
+		l.TrustedIPs[ip] = true
+	}
+	return l
+}
+
+// take проверяет ведро key с лимитом lim.
+func (l *Limiter) take(key string, lim Limit, now time.Time) (Decision, error) {
+	interval := lim.interval()
+	window := lim.Period
+	tat, allowed, err := l.Repo.Take(key, interval, window, now)
+	if err != nil {
+		return Decision{}, err
+	}
+	d := Decision{Allowed: allowed, Limit: lim.Burst}
+	if ahead := tat.Sub(now); ahead > 0 {
+		d.Reset = ahead
+		d.Remaining = int((window - ahead) / interval)
+	} else {
+		d.Remaining = lim.Burst
+	}
+	if d.Remaining < 0 {
+		d.Remaining = 0
+	}
+	if !allowed {
+		// Пройти можно, когда TAT+interval снова уложится в окно
+		d.RetryAfter = tat.Add(interval).Sub(now.Add(window))
+	}
+	return d, nil
+}
+
+// ClientIP возвращает IP клиента запроса.
+func (l *Limiter) ClientIP(r *http.Request) string {
+	if l.TrustForwarded {
+		if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
+			first, _, _ := strings.Cut(xff, ",")
+			return strings.TrimSpace(first)
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// maxFormSize — предел multipart-формы, которую userKey разбирает ради
+// telegram_id; совпадает с пределом формы в AdHandler.Create.
+const maxFormSize = 20 << 20
+
+// userKey — пользователь, от имени которого выполняется запрос: telegramId
+// в пути, telegram_id в query или в форме. Тело формы ограничивается до
+// разбора, иначе лимитер читал бы запрос любого размера. Ошибка разбора
+// возвращается: запрос без ключа пользователя обошёл бы его лимит.
+func userKey(w http.ResponseWriter, r *http.Request) (string, error) {
+	if id := mux.Vars(r)["telegramId"]; id != "" {
+		return id, nil
+	}
+	if id := r.URL.Query().Get("telegram_id"); id != "" {
+		return id, nil
+	}
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		r.Body = http.MaxBytesReader(w, r.Body, maxFormSize)
+		if err := r.ParseMultipartForm(maxFormSize); err != nil {
+			return "", err
+		}
+		return r.FormValue("telegram_id"), nil
+	}
+	return "", nil
+}
+
+// Middleware ограничивает запросы политикой name. Заголовки RateLimit-*
+// описывают самое строгое из проверенных ведер. При ошибке хранилища
+// запрос пропускается: недоступный лимитер не должен ронять API.
+func (l *Limiter) Middleware(name string) func(http.Handler) http.Handler {
+	p, ok := l.Policies[name]
+	if !ok {
+		panic("ratelimit: unknown policy " + name)
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			now := time.Now()
+			var worst *Decision
+			check := func(key string, lim Limit) bool {
+				if !lim.enabled() {
+					return true
+				}
+				d, err := l.take(p.Name+":"+key, lim, now)
+				if err != nil {
+					log.Printf("rate limit %s: %v", key, err)
+					return true
+				}
+				if worst == nil || !d.Allowed || (worst.Allowed && d.Remaining < worst.Remaining) {
+					worst = &d
+				}
+				return d.Allowed
+			}
+
+			user, err := userKey(w, r)
+			var tooLarge *http.MaxBytesError
+			switch {
+			case errors.As(err, &tooLarge):
+				http.Error(w, "request body too large", http.StatusRequestEntityTooLarge)
+				return
+			case err != nil:
+				http.Error(w, "cannot parse form: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+
+			allowed := true
+			if user != "" {
+				allowed = check("user:"+user, p.User)
+			}
+			if ip := l.ClientIP(r); allowed && !l.TrustedIPs[ip] {
+				allowed = check("ip:"+ip, p.IP)
+			}
+
+			if worst != nil {
+				setHeaders(w, worst)
+			}
+			if !allowed {
+				http.Error(w, "rate limit exceeded", http.StatusTooManyRequests)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// setHeaders выставляет RateLimit-* (draft-ietf-httpapi-ratelimit-headers)
+// и Retry-After для отклонённых запросов.
+func setHeaders(w http.ResponseWriter, d *Decision) {
+	h := w.Header()
+	h.Set("RateLimit-Limit", strconv.Itoa(d.Limit))
+	h.Set("RateLimit-Remaining", strconv.Itoa(d.Remaining))
+	h.Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(d.Reset)))
+	if !d.Allowed {
+		h.Set("Retry-After", strconv.Itoa(ceilSeconds(d.RetryAfter)))
+	}
+}
+
+func ceilSeconds(d time.Duration) int {
+	if d <= 0 {
+		return 0
+	}
+	return int(math.Ceil(d.Seconds()))
+}
+
+// PurgeLoop периодически удаляет восстановившиеся ведра.
+func (l *Limiter) PurgeLoop(every time.Duration) {
+	for range time.Tick(every) {
+		if n, err := l.Repo.PurgeExpired(time.Now()); err != nil {
+			log.Printf("purge rate limit buckets: %v", err)
+		} else if n > 0 {
+			log.Printf("purged %d rate limit buckets", n)
+		}
+	}
+}
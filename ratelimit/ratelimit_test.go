@@ -0,0 +1,60 @@
+package ratelimit
+
+import (
+	"bytes"
+	"errors"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestUserKey(t *testing.T) {
+	form := func(telegramID string, padding int) (*bytes.Buffer, string) {
+		var buf bytes.Buffer
+		mw := multipart.NewWriter(&buf)
+		mw.WriteField("telegram_id", telegramID)
+		fw, _ := mw.CreateFormFile("photo", "photo.png")
+		fw.Write(make([]byte, padding))
+		mw.Close()
+		return &buf, mw.FormDataContentType()
+	}
+	tests := []struct {
+		name        string
+		url         string
+		body        func() (*bytes.Buffer, string)
+		want        string
+		wantErr     bool
+		wantTooLong bool
+	}{
+		{name: "query", url: "/ads?telegram_id=7", want: "7"},
+		{name: "no user", url: "/ads"},
+		{name: "form", url: "/ads", body: func() (*bytes.Buffer, string) { return form("9", 1024) }, want: "9"},
+		{name: "form too large", url: "/ads", body: func() (*bytes.Buffer, string) { return form("9", maxFormSize+1) }, wantErr: true, wantTooLong: true},
+		{name: "malformed form", url: "/ads", body: func() (*bytes.Buffer, string) {
+			return bytes.NewBufferString("--x\r\nnot a part"), "multipart/form-data; boundary=x"
+		}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("POST", tt.url, strings.NewReader(""))
+			if tt.body != nil {
+				body, contentType := tt.body()
+				r = httptest.NewRequest("POST", tt.url, body)
+				r.Header.Set("Content-Type", contentType)
+			}
+			got, err := userKey(httptest.NewRecorder(), r)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, want error %v", err, tt.wantErr)
+			}
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) != tt.wantTooLong {
+				t.Errorf("error = %v, want MaxBytesError %v", err, tt.wantTooLong)
+			}
+			if got != tt.want {
+				t.Errorf("userKey = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -2,20 +2,113 @@ package repository
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
+	"math/rand"
 	"poppins/domain"
+	"strings"
 	"time"
+
+	"github.com/lib/pq"
 )
 
+// adColumns — общий список колонок объявления вместе с данными автора.
+// Порядок должен совпадать с scanAd.
+const adColumns = `
+            a.id,
+            a.user_id,
+            u.telegram_id,
+            u.name,
+            u.phone,
+            a.title,
+            a.description,
+            a.price,
+            a.photos_urls,
+            a.address,
+            a.category,
+            a.status,
+            a.moderation_reason,
+            a.risk_score,
+            a.duplicate_of,
+            a.photo_hash,
+            a.text_hash,
+            a.archived,
+            a.created_at,
+            a.updated_at`
+
+// adDest — указатели на поля объявления в порядке adColumns.
+func adDest(ad *domain.Advertisement) []interface{} {
+	return []interface{}{
+		&ad.ID,
+		&ad.UserID,
+		&ad.TelegramID,
+		&ad.UserName,
+		&ad.UserPhone,
+		&ad.Title,
+		&ad.Description,
+		&ad.Price,
+		&ad.PhotosUrls,
+		&ad.Address,
+		&ad.Category,
+		&ad.Status,
+		&ad.ModerationReason,
+		&ad.RiskScore,
+		&ad.DuplicateOf,
+		&ad.PhotoHash,
+		&ad.TextHash,
+		&ad.Archived,
+		&ad.CreatedAt,
+		&ad.UpdatedAt,
+	}
+}
+
+func scanAd(row rowScanner) (*domain.Advertisement, error) {
+	ad := &domain.Advertisement{}
+	if err := row.Scan(adDest(ad)...); err != nil {
+		return nil, err
+	}
+	return ad, nil
+}
+
+// AdFilter — параметры поиска объявлений.
+type AdFilter struct {
+	Keyword  string
+	MaxPrice int64
+	Category string
+	// Viewer — telegram_id смотрящего: свои объявления он видит даже
+	// под теневым баном.
+	Viewer string
+}
+
+// Match проверяет объявление из события по тем же условиям, что и Search,
+// кроме статуса: ownerHidden — владелец под баном или теневым баном.
+func (f AdFilter) Match(ad *domain.AdEventData, ownerHidden bool) bool {
+	if ownerHidden && ad.TelegramID != f.Viewer {
+		return false
+	}
+	if f.Keyword != "" && !strings.Contains(strings.ToLower(ad.Title), strings.ToLower(f.Keyword)) {
+		return false
+	}
+	if f.MaxPrice > 0 && ad.Price > f.MaxPrice {
+		return false
+	}
+	return f.Category == "" || ad.Category == f.Category
+}
+
 type AdRepo struct {
 	DB *sql.DB
+	// OrganicPerPromoted — сколько обычных объявлений в поиске идёт между
+	// продвигаемыми; 0 — продвигаемые не поднимаются.
+	OrganicPerPromoted int
 }
 
-func NewAdRepo(db *sql.DB) *AdRepo {
-	return &AdRepo{DB: db}
+func NewAdRepo(db *sql.DB, organicPerPromoted int) *AdRepo {
+	return &AdRepo{DB: db, OrganicPerPromoted: organicPerPromoted}
 }
 
-func (r *AdRepo) Create(ad *domain.Advertisement) error {
+// Create сохраняет объявление. Если review не nil, объявление создаётся
+// неопубликованным и в той же транзакции ставится в очередь модерации.
+func (r *AdRepo) Create(ad *domain.Advertisement, review *domain.ModerationTask) error {
 	var userID int64
 	if err := r.DB.QueryRow(
 		`SELECT id FROM users WHERE telegram_id = $1`, ad.TelegramID,
@@ -28,48 +121,62 @@ func (r *AdRepo) Create(ad *domain.Advertisement) error {
 	ad.CreatedAt = now
 	ad.UpdatedAt = now
 	ad.Archived = false
+	ad.Status = domain.AdStatusActive
+	if review != nil {
+		ad.Status = domain.AdStatusPending
+	}
 
-	return r.DB.QueryRow(
-		`INSERT INTO advertisements
-           (user_id, title, description, price, photos_urls, address, archived, created_at, updated_at)
-         VALUES
-           ($1,      $2,    $3,          $4,    $5,          $6,      $7,       $8,         $9)
-         RETURNING id`,
-		ad.UserID,
-		ad.Title,
-		ad.Description,
-		ad.Price,
-		ad.PhotosUrls,
-		ad.Address,
-		ad.Archived,
-		ad.CreatedAt,
-		ad.UpdatedAt,
-	).Scan(&ad.ID)
+	return WithTx(r.DB, func(tx *sql.Tx) error {
+		if err := tx.QueryRow(
+			`INSERT INTO advertisements
+               (user_id, title, description, price, photos_urls, address, category, status, risk_score,
+                duplicate_of, photo_hash, text_hash, archived, created_at, updated_at)
+             VALUES
+               ($1,      $2,    $3,          $4,    $5,          $6,      $7,       $8,     $9,
+                $10,          $11,        $12,       $13,      $14,        $15)
+             RETURNING id`,
+			ad.UserID,
+			ad.Title,
+			ad.Description,
+			ad.Price,
+			ad.PhotosUrls,
+			ad.Address,
+			ad.Category,
+			ad.Status,
+			ad.RiskScore,
+			ad.DuplicateOf,
+			ad.PhotoHash,
+			ad.TextHash,
+			ad.Archived,
+			ad.CreatedAt,
+			ad.UpdatedAt,
+		).Scan(&ad.ID); err != nil {
+			return err
+		}
+		if review != nil {
+			if err := insertModerationTaskTx(tx, ad.ID, review.Trigger, review.Notes); err != nil {
+				return err
+			}
+		}
+		return emitAdEventTx(tx, domain.EventAdCreated, ad)
+	})
 }
 
 // GetByTelegramID возвращает список объявлений для пользователя с данным telegram_id.
-func (r *AdRepo) GetByTelegramID(telegramID string) ([]*domain.Advertisement, error) {
+// Владелец (viewer == telegramID) видит все неархивные объявления, остальные —
+// только опубликованные и только если владелец не забанен.
+func (r *AdRepo) GetByTelegramID(telegramID, viewer string) ([]*domain.Advertisement, error) {
 	// 1) джоин с таблицей users по telegram_id
 	rows, err := r.DB.Query(
-		`SELECT 
-            a.id,
-            a.user_id,
-            u.telegram_id,
-            u.name,
-            u.phone,
-            a.title,
-            a.description,
-            a.price,
-            a.photos_urls,
-            a.address,
-            a.archived,
-            a.created_at,
-            a.updated_at
+		`SELECT`+adColumns+`
          FROM advertisements a
          JOIN users u ON a.user_id = u.id
          WHERE u.telegram_id = $1
-           AND a.archived = FALSE`,
-		telegramID,
+           AND a.archived = FALSE
+           AND (u.telegram_id = $2 OR a.status = 'active')
+           AND `+fmt.Sprintf(visibleOwner, "$2")+`
+         ORDER BY a.created_at DESC`,
+		telegramID, viewer,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("query ads by telegram_id: %w", err)
@@ -78,23 +185,8 @@ func (r *AdRepo) GetByTelegramID(telegramID string) ([]*domain.Advertisement, er
 
 	var ads []*domain.Advertisement
 	for rows.Next() {
-		ad := &domain.Advertisement{}
-		// сканируем telegram_id из таблицы users
-		if err := rows.Scan(
-			&ad.ID,
-			&ad.UserID,
-			&ad.TelegramID,
-			&ad.UserName,
-			&ad.UserPhone,
-			&ad.Title,
-			&ad.Description,
-			&ad.Price,
-			&ad.PhotosUrls,
-			&ad.Address,
-			&ad.Archived,
-			&ad.CreatedAt,
-			&ad.UpdatedAt,
-		); err != nil {
+		ad, err := scanAd(rows)
+		if err != nil {
 			return nil, fmt.Errorf("scan ad row: %w", err)
 		}
 		ads = append(ads, ad)
@@ -105,115 +197,51 @@ func (r *AdRepo) GetByTelegramID(telegramID string) ([]*domain.Advertisement, er
 	return ads, nil
 }
 
-func (r *AdRepo) GetByIDAndTelegram(adID int64, telegramID string) (*domain.Advertisement, error) {
-	ad := &domain.Advertisement{}
-	err := r.DB.QueryRow(
-		`SELECT 
-            a.id,
-            a.user_id,
-            u.telegram_id,
-            u.name,
-            u.phone,
-            a.title,
-            a.description,
-            a.price,
-            a.photos_urls,
-            a.address,
-            a.archived,
-            a.created_at,
-            a.updated_at
+// GetForViewer возвращает неархивное объявление для viewer: владелец видит
+// его в любом статусе, остальные — только опубликованное и только если
+// владелец не забанен.
+func (r *AdRepo) GetForViewer(adID int64, viewer string) (*domain.Advertisement, error) {
+	ad, err := scanAd(r.DB.QueryRow(
+		`SELECT`+adColumns+`
          FROM advertisements a
          JOIN users u ON a.user_id = u.id
          WHERE a.id = $1
-           AND u.telegram_id = $2
-           AND a.archived = FALSE`,
-		adID, telegramID,
-	).Scan(
-		&ad.ID,
-		&ad.UserID,
-		&ad.TelegramID,
-		&ad.UserName,
-		&ad.UserPhone,
-		&ad.Title,
-		&ad.Description,
-		&ad.Price,
-		&ad.PhotosUrls,
-		&ad.Address,
-		&ad.Archived,
-		&ad.CreatedAt,
-		&ad.UpdatedAt,
-	)
+           AND a.archived = FALSE
+           AND (u.telegram_id = $2 OR a.status = 'active')
+           AND `+fmt.Sprintf(visibleOwner, "$2"),
+		adID, viewer,
+	))
 	if err != nil {
-		return nil, fmt.Errorf("get ad by id & telegram: %w", err)
+		return nil, fmt.Errorf("get ad for viewer: %w", err)
 	}
 	return ad, nil
 }
 
-func (r *AdRepo) Search(keyword string, maxPrice int64) ([]*domain.Advertisement, error) {
-	// 1) Базовый запрос с джойном на users, чтобы подтянуть имя и телефон
+func (r *AdRepo) Search(f AdFilter) ([]*domain.Advertisement, error) {
+	// 1) Базовый запрос с джойном на users, чтобы подтянуть имя и телефон,
+	//    и действующими пакетами продвижения
 	query := `
-        SELECT
-            a.id,
-            a.user_id,
-            u.telegram_id,
-            u.name,            -- имя пользователя
-            u.phone,           -- телефон пользователя
-            a.title,
-            a.description,
-            a.price,
-            a.photos_urls,
-            a.address,
-            a.archived,
-            a.created_at,
-            a.updated_at
+        SELECT` + adColumns + `, pr.packages
         FROM advertisements a
         JOIN users u ON a.user_id = u.id
-        WHERE a.archived = FALSE
-    `
-	args := []interface{}{}
-	i := 1
-
-	// 2) Добавляем фильтрацию по ключевому слову
-	if keyword != "" {
-		query += fmt.Sprintf(" AND a.title ILIKE $%d", i)
-		args = append(args, "%"+keyword+"%")
-		i++
-	}
-	// 3) И по максимальной цене
-	if maxPrice > 0 {
-		query += fmt.Sprintf(" AND a.price <= $%d", i)
-		args = append(args, maxPrice)
-		i++
-	}
-
-	query += " ORDER BY a.created_at DESC"
+        ` + activePromotions + `
+        WHERE `
+	// 2) Условия фильтра
+	where, args := f.where()
+	query += where + " ORDER BY a.created_at DESC"
 
-	// 4) Выполняем запрос
+	// 3) Выполняем запрос
 	rows, err := r.DB.Query(query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("search ads: %w", err)
 	}
 	defer rows.Close()
 
-	// 5) Сканируем результаты
+	// 4) Сканируем результаты
 	var ads []*domain.Advertisement
 	for rows.Next() {
 		ad := &domain.Advertisement{}
-		if err := rows.Scan(
-			&ad.ID,
-			&ad.UserID,
-			&ad.TelegramID,
-			&ad.UserName,  // <-- сюда
-			&ad.UserPhone, // <-- сюда
-			&ad.Title,
-			&ad.Description,
-			&ad.Price,
-			&ad.PhotosUrls,
-			&ad.Address,
-			&ad.Archived,
-			&ad.CreatedAt,
-			&ad.UpdatedAt,
-		); err != nil {
+		if err := rows.Scan(append(adDest(ad), pq.Array(&ad.Promotions))...); err != nil {
 			return nil, fmt.Errorf("scan ad row: %w", err)
 		}
 		ads = append(ads, ad)
@@ -221,24 +249,338 @@ func (r *AdRepo) Search(keyword string, maxPrice int64) ([]*domain.Advertisement
 	if err := rows.Err(); err != nil {
 		return nil, fmt.Errorf("iterate ad rows: %w", err)
 	}
-	return ads, nil
+
+	// 5) Расставляем продвигаемые объявления
+	return rankPromoted(ads, f.Category != "", r.OrganicPerPromoted), nil
 }
 
-func (r *AdRepo) Update(ad *domain.Advertisement) error {
-	ad.UpdatedAt = time.Now()
-	return r.DB.QueryRow(
-		`UPDATE advertisements SET title=$1, description=$2, price=$3, photos_urls=$4, address=$5, updated_at=$7
-         WHERE id=$8 RETURNING user_id, created_at`,
-		ad.Title, ad.Description, ad.Price, ad.PhotosUrls, ad.Address, ad.Archived, ad.UpdatedAt, ad.ID,
-	).Scan(&ad.UserID, &ad.CreatedAt)
+// where — условия выдачи поиска по фильтру: опубликованные объявления,
+// скрытые только от посторонних объявления забаненных, ключевое слово, цена
+// и категория. Параметры нумеруются с $1.
+func (f AdFilter) where() (string, []interface{}) {
+	// Объявления забаненных видят только они сами
+	where := "a.archived = FALSE AND a.status = 'active' AND " + fmt.Sprintf(visibleOwner, "$1")
+	args := []interface{}{f.Viewer}
+	if f.Keyword != "" {
+		args = append(args, "%"+f.Keyword+"%")
+		where += fmt.Sprintf(" AND a.title ILIKE $%d", len(args))
+	}
+	if f.MaxPrice > 0 {
+		args = append(args, f.MaxPrice)
+		where += fmt.Sprintf(" AND a.price <= $%d", len(args))
+	}
+	if f.Category != "" {
+		args = append(args, f.Category)
+		where += fmt.Sprintf(" AND a.category = $%d", len(args))
+	}
+	return where, args
 }
 
-func (r *AdRepo) Delete(id int64) error {
-	_, err := r.DB.Exec(`DELETE FROM advertisements WHERE id=$1`, id)
-	return err
+// Each передаёт fn объявления по фильтру поиска по одному, по мере чтения из
+// БД, без продвижения и в порядке ID, — для выгрузок, которые не помещаются
+// в память. Ошибка fn прекращает чтение и возвращается.
+func (r *AdRepo) Each(f AdFilter, fn func(*domain.Advertisement) error) error {
+	where, args := f.where()
+	rows, err := r.DB.Query(
+		`SELECT`+adColumns+`
+         FROM advertisements a
+         JOIN users u ON a.user_id = u.id
+         WHERE `+where+`
+         ORDER BY a.id`,
+		args...,
+	)
+	if err != nil {
+		return fmt.Errorf("query ads: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		ad, err := scanAd(rows)
+		if err != nil {
+			return fmt.Errorf("scan ad row: %w", err)
+		}
+		if err := fn(ad); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// activePromotions — джойн pr.packages: действующие пакеты продвижения объявления a.
+const activePromotions = `LEFT JOIN LATERAL (
+            SELECT array_agg(DISTINCT p.package) AS packages
+            FROM ad_promotions p
+            WHERE p.ad_id = a.id AND p.cancelled_at IS NULL
+              AND p.starts_at <= now() AND p.ends_at > now()
+        ) pr ON TRUE`
+
+// maxPinned — сколько закреплённых объявлений показывается над выдачей категории.
+const maxPinned = 3
+
+// rankPromoted строит выдачу из отсортированных по дате объявлений:
+// при поиске по категории сверху идут закреплённые в ней (не больше
+// maxPinned), дальше продвигаемые чередуются с обычными — одно на
+// organicPerPromoted обычных. Порядок продвигаемых перемешивается, чтобы
+// первая позиция доставалась всем по очереди. Лишние закреплённые
+// участвуют в ротации наравне с поднятыми.
+func rankPromoted(ads []*domain.Advertisement, byCategory bool, organicPerPromoted int) []*domain.Advertisement {
+	if organicPerPromoted <= 0 {
+		return ads
+	}
+	var pinned, promoted, organic []*domain.Advertisement
+	for _, ad := range ads {
+		switch {
+		case byCategory && ad.HasPromotion(domain.PromotionPinned) && len(pinned) < maxPinned:
+			pinned = append(pinned, ad)
+		case ad.HasPromotion(domain.PromotionTop) || ad.HasPromotion(domain.PromotionPinned):
+			promoted = append(promoted, ad)
+		default:
+			organic = append(organic, ad)
+		}
+	}
+	if len(pinned) == 0 && len(promoted) == 0 {
+		return ads
+	}
+	rand.Shuffle(len(promoted), func(i, j int) { promoted[i], promoted[j] = promoted[j], promoted[i] })
+
+	out := make([]*domain.Advertisement, 0, len(ads))
+	for _, ad := range pinned {
+		ad.Promoted = true
+		out = append(out, ad)
+	}
+	for len(promoted) > 0 || len(organic) > 0 {
+		if len(promoted) > 0 {
+			promoted[0].Promoted = true
+			out = append(out, promoted[0])
+			promoted = promoted[1:]
+		}
+		n := min(organicPerPromoted, len(organic))
+		out = append(out, organic[:n]...)
+		organic = organic[n:]
+	}
+	return out
+}
+
+// FindDuplicate ищет среди неархивных объявлений, созданных после since,
+// ближайшее к ad по хешу фото или текста. Дубликаты дубликатов не
+// рассматриваются, чтобы ссылка всегда вела на оригинал. nil — похожих нет.
+func (r *AdRepo) FindDuplicate(ad *domain.Advertisement, since time.Time, photoThreshold, textThreshold int) (*domain.DuplicateMatch, error) {
+	m := &domain.DuplicateMatch{}
+	var photoDist sql.NullInt64
+	err := r.DB.QueryRow(
+		`SELECT d.id, u.telegram_id, d.photo_dist, COALESCE(d.text_dist, 64)
+         FROM (
+             SELECT a.id, a.user_id,
+                    length(replace(((a.photo_hash # $1::bigint)::bit(64))::text, '0', '')) AS photo_dist,
+                    length(replace(((a.text_hash # $2::bigint)::bit(64))::text, '0', '')) AS text_dist
+             FROM advertisements a
+             WHERE a.archived = FALSE
+               AND a.status <> 'rejected'
+               AND a.duplicate_of IS NULL
+               AND a.created_at >= $3
+               AND a.id <> $4
+         ) d
+         JOIN users u ON u.id = d.user_id
+         WHERE d.photo_dist <= $5 OR d.text_dist <= $6
+         ORDER BY LEAST(COALESCE(d.photo_dist, 64), COALESCE(d.text_dist, 64)), d.id
+         LIMIT 1`,
+		ad.PhotoHash, ad.TextHash, since, ad.ID, photoThreshold, textThreshold,
+	).Scan(&m.AdID, &m.TelegramID, &photoDist, &m.TextDistance)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("find duplicate: %w", err)
+	}
+	if photoDist.Valid {
+		d := int(photoDist.Int64)
+		m.PhotoDistance = &d
+	}
+	m.SameSeller = m.TelegramID == ad.TelegramID
+	return m, nil
+}
+
+// MutateOwned в одной транзакции блокирует объявление adID, проверяет, что
+// actor им владеет (или является администратором), выполняет mutate и
+// публикует для подписчиков событие event.
+// Возвращает ErrNotFound, если объявления нет, и ErrForbidden, если оно чужое.
+func (r *AdRepo) MutateOwned(
+	adID int64,
+	actor domain.Actor,
+	event string,
+	mutate func(tx *sql.Tx, ad *domain.Advertisement) error,
+) (*domain.Advertisement, error) {
+	var ad *domain.Advertisement
+	err := WithTx(r.DB, func(tx *sql.Tx) error {
+		var err error
+		ad, err = scanAd(tx.QueryRow(
+			`SELECT`+adColumns+`
+             FROM advertisements a
+             JOIN users u ON a.user_id = u.id
+             WHERE a.id = $1
+             FOR UPDATE OF a`,
+			adID,
+		))
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("lock ad: %w", err)
+		}
+		if !actor.Owns(ad.TelegramID) {
+			return ErrForbidden
+		}
+		if err := mutate(tx, ad); err != nil {
+			return err
+		}
+		return emitAdEventTx(tx, event, ad)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ad, nil
 }
 
-func (r *AdRepo) Archive(id int64) error {
-	_, err := r.DB.Exec(`UPDATE advertisements SET archived=true, updated_at=$2 WHERE id=$1`, id, time.Now())
+// UpdateTx переносит редактируемые поля patch в заблокированное объявление cur
+// и сохраняет их. Вызывается из MutateOwned; пустой PhotosUrls оставляет текущее фото.
+func (r *AdRepo) UpdateTx(tx *sql.Tx, cur, patch *domain.Advertisement) error {
+	cur.Title = patch.Title
+	cur.Description = patch.Description
+	cur.Price = patch.Price
+	cur.Address = patch.Address
+	cur.RiskScore = patch.RiskScore
+	cur.TextHash = patch.TextHash
+	if patch.Category != "" {
+		cur.Category = patch.Category
+	}
+	if patch.PhotosUrls != "" && patch.PhotosUrls != cur.PhotosUrls {
+		cur.PhotosUrls = patch.PhotosUrls
+		cur.PhotoHash = patch.PhotoHash
+	}
+	cur.UpdatedAt = time.Now()
+	_, err := tx.Exec(
+		`UPDATE advertisements
+         SET title=$1, description=$2, price=$3, photos_urls=$4, address=$5, category=$6, risk_score=$7,
+             text_hash=$8, photo_hash=$9, updated_at=$10
+         WHERE id=$11`,
+		cur.Title, cur.Description, cur.Price, cur.PhotosUrls, cur.Address, cur.Category, cur.RiskScore,
+		cur.TextHash, cur.PhotoHash, cur.UpdatedAt, cur.ID,
+	)
 	return err
 }
+
+// SubmitForModerationTx снимает объявление с публикации и ставит его в очередь
+// модерации, если оно там ещё не стоит. notes — пояснение для модератора.
+func (r *AdRepo) SubmitForModerationTx(tx *sql.Tx, ad *domain.Advertisement, trigger, notes string) error {
+	ad.Status = domain.AdStatusPending
+	ad.ModerationReason = ""
+	if _, err := tx.Exec(
+		`UPDATE advertisements SET status='pending', moderation_reason='' WHERE id=$1`, ad.ID,
+	); err != nil {
+		return err
+	}
+	return insertModerationTaskTx(tx, ad.ID, trigger, notes)
+}
+
+// Delete удаляет объявление, если actor им владеет, и возвращает удалённую запись.
+func (r *AdRepo) Delete(id int64, actor domain.Actor) (*domain.Advertisement, error) {
+	return r.MutateOwned(id, actor, domain.EventAdDeleted, func(tx *sql.Tx, ad *domain.Advertisement) error {
+		_, err := tx.Exec(`DELETE FROM advertisements WHERE id=$1`, ad.ID)
+		return err
+	})
+}
+
+// Archive помечает объявление архивным, если actor им владеет.
+func (r *AdRepo) Archive(id int64, actor domain.Actor) (*domain.Advertisement, error) {
+	return r.MutateOwned(id, actor, domain.EventAdArchived, func(tx *sql.Tx, ad *domain.Advertisement) error {
+		ad.Archived = true
+		ad.UpdatedAt = time.Now()
+		_, err := tx.Exec(
+			`UPDATE advertisements SET archived=true, updated_at=$2 WHERE id=$1`,
+			ad.ID, ad.UpdatedAt,
+		)
+		return err
+	})
+}
+
+// RevealContact выдаёт покупателю viewer контакт продавца опубликованного
+// объявления и записывает раскрытие в журнал; via — имя API-ключа.
+// Владелец получает свой контакт без записи в журнал.
+func (r *AdRepo) RevealContact(adID int64, viewer, via string) (*domain.Contact, error) {
+	var contact *domain.Contact
+	err := WithTx(r.DB, func(tx *sql.Tx) error {
+		var viewerID, sellerID int64
+		var name, phone, telegramID, preferred string
+		err := tx.QueryRow(
+			`SELECT vu.id, u.id, u.name, u.phone, u.telegram_id, u.preferred_contact
+             FROM users vu, advertisements a
+             JOIN users u ON u.id = a.user_id
+             WHERE vu.telegram_id = $1 AND a.id = $2
+               AND a.archived = FALSE
+               AND (u.telegram_id = $1 OR a.status = 'active')
+               AND `+fmt.Sprintf(visibleOwner, "$1"),
+			viewer, adID,
+		).Scan(&viewerID, &sellerID, &name, &phone, &telegramID, &preferred)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("get ad contact: %w", err)
+		}
+		contact = domain.NewContact(adID, name, phone, telegramID, preferred)
+		if viewerID == sellerID {
+			return nil
+		}
+		if _, err := tx.Exec(
+			`INSERT INTO contact_reveals (ad_id, viewer_user_id, via) VALUES ($1, $2, $3)`,
+			adID, viewerID, via,
+		); err != nil {
+			return fmt.Errorf("log contact reveal: %w", err)
+		}
+		return bumpDailyStatTx(tx, adID, statContactReveals)
+	})
+	return contact, err
+}
+
+// FillContactReveals проставляет объявлениям число раскрытий контакта.
+func (r *AdRepo) FillContactReveals(ads []*domain.Advertisement) error {
+	if len(ads) == 0 {
+		return nil
+	}
+	ids := make([]int64, len(ads))
+	byID := make(map[int64]*domain.Advertisement, len(ads))
+	for i, ad := range ads {
+		ids[i] = ad.ID
+		byID[ad.ID] = ad
+		var zero int64
+		ad.ContactReveals = &zero
+	}
+	rows, err := r.DB.Query(
+		`SELECT ad_id, COUNT(*) FROM contact_reveals WHERE ad_id = ANY($1) GROUP BY ad_id`,
+		pq.Array(ids),
+	)
+	if err != nil {
+		return fmt.Errorf("count contact reveals: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id, n int64
+		if err := rows.Scan(&id, &n); err != nil {
+			return err
+		}
+		*byID[id].ContactReveals = n
+	}
+	return rows.Err()
+}
+
+// OwnerTelegramID возвращает telegram_id владельца объявления.
+func (r *AdRepo) OwnerTelegramID(adID int64) (string, error) {
+	var telegramID string
+	err := r.DB.QueryRow(
+		`SELECT u.telegram_id FROM advertisements a JOIN users u ON u.id = a.user_id WHERE a.id = $1`,
+		adID,
+	).Scan(&telegramID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", ErrNotFound
+	}
+	return telegramID, err
+}
@@ -0,0 +1,99 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"poppins/domain"
+
+	"github.com/lib/pq"
+)
+
+type APIKeyRepo struct {
+	DB *sql.DB
+}
+
+func NewAPIKeyRepo(db *sql.DB) *APIKeyRepo {
+	return &APIKeyRepo{DB: db}
+}
+
+const apiKeyColumns = `id, name, prefix, scopes, created_at, last_used_at, revoked_at`
+
+func scanAPIKey(row rowScanner) (*domain.APIKey, error) {
+	k := &domain.APIKey{}
+	var lastUsed, revoked sql.NullTime
+	if err := row.Scan(
+		&k.ID, &k.Name, &k.Prefix, pq.Array(&k.Scopes), &k.CreatedAt, &lastUsed, &revoked,
+	); err != nil {
+		return nil, err
+	}
+	if lastUsed.Valid {
+		k.LastUsedAt = &lastUsed.Time
+	}
+	if revoked.Valid {
+		k.RevokedAt = &revoked.Time
+	}
+	return k, nil
+}
+
+// Create сохраняет новый ключ по его hash.
+func (r *APIKeyRepo) Create(k *domain.APIKey, hash string) error {
+	return r.DB.QueryRow(
+		`INSERT INTO api_keys (name, prefix, key_hash, scopes)
+         VALUES ($1, $2, $3, $4)
+         RETURNING id, created_at`,
+		k.Name, k.Prefix, hash, pq.Array(k.Scopes),
+	).Scan(&k.ID, &k.CreatedAt)
+}
+
+// GetActiveByHash возвращает неотозванный ключ с данным hash.
+func (r *APIKeyRepo) GetActiveByHash(hash string) (*domain.APIKey, error) {
+	return scanAPIKey(r.DB.QueryRow(
+		`SELECT `+apiKeyColumns+` FROM api_keys WHERE key_hash = $1 AND revoked_at IS NULL`,
+		hash,
+	))
+}
+
+// List возвращает все ключи, включая отозванные.
+func (r *APIKeyRepo) List() ([]*domain.APIKey, error) {
+	rows, err := r.DB.Query(`SELECT ` + apiKeyColumns + ` FROM api_keys ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("list api keys: %w", err)
+	}
+	defer rows.Close()
+
+	var keys []*domain.APIKey
+	for rows.Next() {
+		k, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan api key: %w", err)
+		}
+		keys = append(keys, k)
+	}
+	return keys, rows.Err()
+}
+
+// Revoke отзывает ключ. Повторный отзыв не меняет время отзыва.
+func (r *APIKeyRepo) Revoke(id int64) (*domain.APIKey, error) {
+	k, err := scanAPIKey(r.DB.QueryRow(
+		`UPDATE api_keys SET revoked_at = COALESCE(revoked_at, now())
+         WHERE id = $1
+         RETURNING `+apiKeyColumns,
+		id,
+	))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	return k, err
+}
+
+// TouchLastUsed обновляет last_used_at не чаще раза в минуту, чтобы не писать
+// в БД на каждый запрос.
+func (r *APIKeyRepo) TouchLastUsed(id int64) error {
+	_, err := r.DB.Exec(
+		`UPDATE api_keys SET last_used_at = now()
+         WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < now() - interval '1 minute')`,
+		id,
+	)
+	return err
+}
@@ -0,0 +1,326 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"poppins/domain"
+)
+
+// ErrBlocked — один из участников заблокировал переписку.
+var ErrBlocked = errors.New("conversation is blocked")
+
+type ConversationRepo struct {
+	DB *sql.DB
+}
+
+func NewConversationRepo(db *sql.DB) *ConversationRepo {
+	return &ConversationRepo{DB: db}
+}
+
+// MessageDelivery — что и кому переслать через бота после отправки сообщения.
+type MessageDelivery struct {
+	Message             *domain.Message
+	AdID                int64
+	AdTitle             string
+	RecipientTelegramID string
+	// Suppressed — отправитель под теневым баном: сообщение сохранено,
+	// но собеседник его не получит.
+	Suppressed bool
+}
+
+// participant — переписка, заблокированная для изменения, глазами участника.
+type participant struct {
+	conversationID int64
+	adID           int64
+	adTitle        string
+	userID         int64
+	role           string
+	peerTelegramID string
+	blockedByMe    bool
+	blockedByPeer  bool
+}
+
+// lockParticipantTx блокирует переписку и проверяет, что telegramID — её участник.
+func lockParticipantTx(tx *sql.Tx, conversationID int64, telegramID string) (*participant, error) {
+	var buyerID, sellerID int64
+	var buyerTG, sellerTG string
+	var buyerBlocked, sellerBlocked bool
+	p := &participant{conversationID: conversationID}
+	err := tx.QueryRow(
+		`SELECT c.ad_id, a.title, c.buyer_user_id, c.seller_user_id, bu.telegram_id, su.telegram_id,
+                c.buyer_blocked_at IS NOT NULL, c.seller_blocked_at IS NOT NULL
+         FROM conversations c
+         JOIN advertisements a ON a.id = c.ad_id
+         JOIN users bu ON bu.id = c.buyer_user_id
+         JOIN users su ON su.id = c.seller_user_id
+         WHERE c.id = $1
+         FOR UPDATE OF c`,
+		conversationID,
+	).Scan(&p.adID, &p.adTitle, &buyerID, &sellerID, &buyerTG, &sellerTG, &buyerBlocked, &sellerBlocked)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("lock conversation: %w", err)
+	}
+	switch telegramID {
+	case buyerTG:
+		p.userID, p.role, p.peerTelegramID = buyerID, domain.RoleBuyer, sellerTG
+		p.blockedByMe, p.blockedByPeer = buyerBlocked, sellerBlocked
+	case sellerTG:
+		p.userID, p.role, p.peerTelegramID = sellerID, domain.RoleSeller, buyerTG
+		p.blockedByMe, p.blockedByPeer = sellerBlocked, buyerBlocked
+	default:
+		return nil, ErrForbidden
+	}
+	return p, nil
+}
+
+// sendTx добавляет сообщение участника p в переписку и ставит его пересылку
+// собеседнику в очередь.
+func sendTx(tx *sql.Tx, p *participant, text string) (*MessageDelivery, error) {
+	if p.blockedByMe || p.blockedByPeer {
+		return nil, ErrBlocked
+	}
+	d := &MessageDelivery{
+		Message:             &domain.Message{ConversationID: p.conversationID, FromMe: true, Text: text},
+		AdID:                p.adID,
+		AdTitle:             p.adTitle,
+		RecipientTelegramID: p.peerTelegramID,
+	}
+	err := tx.QueryRow(
+		`INSERT INTO messages (conversation_id, sender_user_id, text, hidden)
+         VALUES ($1, $2, $3, EXISTS (
+             SELECT 1 FROM user_sanctions s
+             WHERE s.user_id = $2 AND s.kind = 'shadow_ban' AND `+activeSanction+`))
+         RETURNING id, hidden, created_at`,
+		p.conversationID, p.userID, text,
+	).Scan(&d.Message.ID, &d.Suppressed, &d.Message.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("insert message: %w", err)
+	}
+	if _, err := tx.Exec(
+		`UPDATE conversations SET last_message_at = $2 WHERE id = $1`,
+		p.conversationID, d.Message.CreatedAt,
+	); err != nil {
+		return nil, fmt.Errorf("touch conversation: %w", err)
+	}
+	if !d.Suppressed {
+		if err := enqueueTx(tx, d.RecipientTelegramID, domain.NotifyMessage, domain.RelayText(d.AdTitle, d.Message)); err != nil {
+			return nil, err
+		}
+	}
+	return d, nil
+}
+
+// Start открывает переписку покупателя buyerTelegramID по опубликованному
+// объявлению adID (или продолжает уже начатую) и отправляет первое сообщение.
+func (r *ConversationRepo) Start(adID int64, buyerTelegramID, text string) (*MessageDelivery, error) {
+	var d *MessageDelivery
+	err := WithTx(r.DB, func(tx *sql.Tx) error {
+		var buyerID, sellerID int64
+		err := tx.QueryRow(
+			`SELECT bu.id, a.user_id
+             FROM users bu, advertisements a
+             JOIN users u ON u.id = a.user_id
+             WHERE bu.telegram_id = $1 AND a.id = $2
+               AND a.status = 'active' AND NOT a.archived
+               AND `+fmt.Sprintf(visibleOwner, "$1"),
+			buyerTelegramID, adID,
+		).Scan(&buyerID, &sellerID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("find ad for conversation: %w", err)
+		}
+		if buyerID == sellerID {
+			return ErrForbidden
+		}
+
+		var conversationID int64
+		if err := tx.QueryRow(
+			`INSERT INTO conversations (ad_id, buyer_user_id, seller_user_id)
+             VALUES ($1, $2, $3)
+             ON CONFLICT (ad_id, buyer_user_id) DO UPDATE SET ad_id = EXCLUDED.ad_id
+             RETURNING id`,
+			adID, buyerID, sellerID,
+		).Scan(&conversationID); err != nil {
+			return fmt.Errorf("upsert conversation: %w", err)
+		}
+
+		p, err := lockParticipantTx(tx, conversationID, buyerTelegramID)
+		if err != nil {
+			return err
+		}
+		d, err = sendTx(tx, p, text)
+		return err
+	})
+	return d, err
+}
+
+// Send отправляет сообщение участника telegramID в переписку conversationID.
+func (r *ConversationRepo) Send(conversationID int64, telegramID, text string) (*MessageDelivery, error) {
+	var d *MessageDelivery
+	err := WithTx(r.DB, func(tx *sql.Tx) error {
+		p, err := lockParticipantTx(tx, conversationID, telegramID)
+		if err != nil {
+			return err
+		}
+		d, err = sendTx(tx, p, text)
+		return err
+	})
+	return d, err
+}
+
+// ListByTelegram возвращает переписки пользователя, свежие первыми.
+// Сообщения теневых забаненных собеседников не учитываются.
+func (r *ConversationRepo) ListByTelegram(telegramID string) ([]*domain.Conversation, error) {
+	rows, err := r.DB.Query(
+		`SELECT c.id, c.ad_id, a.title, c.buyer_user_id = me.id,
+                CASE WHEN c.buyer_user_id = me.id THEN c.buyer_blocked_at ELSE c.seller_blocked_at END IS NOT NULL,
+                CASE WHEN c.buyer_user_id = me.id THEN c.seller_blocked_at ELSE c.buyer_blocked_at END IS NOT NULL,
+                c.created_at, c.last_message_at,
+                (SELECT COUNT(*) FROM messages m
+                 WHERE m.conversation_id = c.id AND m.sender_user_id <> me.id
+                   AND NOT m.hidden AND m.read_at IS NULL),
+                lm.id, lm.sender_user_id = me.id, lm.text, lm.created_at, lm.read_at
+         FROM users me
+         JOIN conversations c ON me.id IN (c.buyer_user_id, c.seller_user_id)
+         JOIN advertisements a ON a.id = c.ad_id
+         LEFT JOIN LATERAL (
+             SELECT m.* FROM messages m
+             WHERE m.conversation_id = c.id AND (m.sender_user_id = me.id OR NOT m.hidden)
+             ORDER BY m.id DESC
+             LIMIT 1
+         ) lm ON TRUE
+         WHERE me.telegram_id = $1
+         ORDER BY c.last_message_at DESC`,
+		telegramID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []*domain.Conversation
+	for rows.Next() {
+		c := &domain.Conversation{}
+		var isBuyer bool
+		var msgID sql.NullInt64
+		var fromMe sql.NullBool
+		var text sql.NullString
+		var sentAt, readAt sql.NullTime
+		if err := rows.Scan(
+			&c.ID, &c.AdID, &c.AdTitle, &isBuyer, &c.BlockedByMe, &c.BlockedByPeer,
+			&c.CreatedAt, &c.LastMessageAt, &c.Unread,
+			&msgID, &fromMe, &text, &sentAt, &readAt,
+		); err != nil {
+			return nil, err
+		}
+		c.Role = domain.RoleSeller
+		if isBuyer {
+			c.Role = domain.RoleBuyer
+		}
+		if msgID.Valid {
+			c.LastMessage = &domain.Message{
+				ID: msgID.Int64, ConversationID: c.ID, FromMe: fromMe.Bool,
+				Text: text.String, CreatedAt: sentAt.Time,
+			}
+			if readAt.Valid {
+				c.LastMessage.ReadAt = &readAt.Time
+			}
+		}
+		list = append(list, c)
+	}
+	return list, rows.Err()
+}
+
+// Messages возвращает сообщения переписки для участника telegramID,
+// свежие первыми; beforeID > 0 — страница старше этого сообщения.
+func (r *ConversationRepo) Messages(conversationID int64, telegramID string, beforeID int64, limit int) ([]*domain.Message, error) {
+	var list []*domain.Message
+	err := WithTx(r.DB, func(tx *sql.Tx) error {
+		p, err := lockParticipantTx(tx, conversationID, telegramID)
+		if err != nil {
+			return err
+		}
+		rows, err := tx.Query(
+			`SELECT id, sender_user_id = $2, text, created_at, read_at
+             FROM messages
+             WHERE conversation_id = $1
+               AND (sender_user_id = $2 OR NOT hidden)
+               AND ($3 = 0 OR id < $3)
+             ORDER BY id DESC
+             LIMIT $4`,
+			conversationID, p.userID, beforeID, limit,
+		)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			m := &domain.Message{ConversationID: conversationID}
+			var readAt sql.NullTime
+			if err := rows.Scan(&m.ID, &m.FromMe, &m.Text, &m.CreatedAt, &readAt); err != nil {
+				return err
+			}
+			if readAt.Valid {
+				m.ReadAt = &readAt.Time
+			}
+			list = append(list, m)
+		}
+		return rows.Err()
+	})
+	return list, err
+}
+
+// MarkRead отмечает прочитанными сообщения собеседника до upToID включительно
+// (0 — все). Возвращает число отмеченных.
+func (r *ConversationRepo) MarkRead(conversationID int64, telegramID string, upToID int64) (int64, error) {
+	var n int64
+	err := WithTx(r.DB, func(tx *sql.Tx) error {
+		p, err := lockParticipantTx(tx, conversationID, telegramID)
+		if err != nil {
+			return err
+		}
+		res, err := tx.Exec(
+			`UPDATE messages SET read_at = now()
+             WHERE conversation_id = $1 AND sender_user_id <> $2
+               AND NOT hidden AND read_at IS NULL
+               AND ($3 = 0 OR id <= $3)`,
+			conversationID, p.userID, upToID,
+		)
+		if err != nil {
+			return fmt.Errorf("mark messages read: %w", err)
+		}
+		n, _ = res.RowsAffected()
+		return nil
+	})
+	return n, err
+}
+
+// SetBlocked блокирует или разблокирует собеседника для участника telegramID.
+func (r *ConversationRepo) SetBlocked(conversationID int64, telegramID string, blocked bool) error {
+	return WithTx(r.DB, func(tx *sql.Tx) error {
+		p, err := lockParticipantTx(tx, conversationID, telegramID)
+		if err != nil {
+			return err
+		}
+		column := "buyer_blocked_at"
+		if p.role == domain.RoleSeller {
+			column = "seller_blocked_at"
+		}
+		value := "NULL"
+		if blocked {
+			value = "COALESCE(" + column + ", now())"
+		}
+		if _, err := tx.Exec(
+			`UPDATE conversations SET `+column+` = `+value+` WHERE id = $1`, conversationID,
+		); err != nil {
+			return fmt.Errorf("block conversation: %w", err)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,139 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"poppins/domain"
+	"strconv"
+
+	"github.com/lib/pq"
+)
+
+// EventsChannel — канал LISTEN/NOTIFY, в который при коммите попадает ID
+// каждого нового события.
+const EventsChannel = "events"
+
+// emitEventTx записывает доменное событие в транзакции изменения, ставит
+// его доставку всем активным подпискам на этот тип и оповещает слушателей
+// канала EventsChannel. Подписчики узнают только о закоммиченных изменениях
+// и не пропускают ни одного.
+func emitEventTx(tx *sql.Tx, eventType string, data interface{}) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("marshal %s event: %w", eventType, err)
+	}
+	var id int64
+	if err := tx.QueryRow(
+		`INSERT INTO events (type, version, payload) VALUES ($1, $2, $3) RETURNING id`,
+		eventType, domain.EventVersion, payload,
+	).Scan(&id); err != nil {
+		return fmt.Errorf("emit %s event: %w", eventType, err)
+	}
+	if _, err := tx.Exec(
+		`INSERT INTO webhook_deliveries (subscription_id, event_id)
+         SELECT s.id, $2
+         FROM webhook_subscriptions s
+         WHERE s.active AND ($1 = ANY(s.events) OR '*' = ANY(s.events))`,
+		eventType, id,
+	); err != nil {
+		return fmt.Errorf("queue %s event deliveries: %w", eventType, err)
+	}
+	if _, err := tx.Exec(`SELECT pg_notify($1, $2)`, EventsChannel, strconv.FormatInt(id, 10)); err != nil {
+		return fmt.Errorf("notify %s event: %w", eventType, err)
+	}
+	return nil
+}
+
+// emitAdEventTx публикует событие eventType об объявлении ad.
+func emitAdEventTx(tx *sql.Tx, eventType string, ad *domain.Advertisement) error {
+	return emitEventTx(tx, eventType, domain.NewAdEventData(ad))
+}
+
+// emitAdUpdatedTx публикует ad.updated, перечитав объявление adID в транзакции.
+func emitAdUpdatedTx(tx *sql.Tx, adID int64) error {
+	ad, err := scanAd(tx.QueryRow(
+		`SELECT`+adColumns+`
+         FROM advertisements a
+         JOIN users u ON a.user_id = u.id
+         WHERE a.id = $1`,
+		adID,
+	))
+	if err != nil {
+		return fmt.Errorf("load ad for event: %w", err)
+	}
+	return emitAdEventTx(tx, domain.EventAdUpdated, ad)
+}
+
+type EventRepo struct {
+	DB *sql.DB
+}
+
+func NewEventRepo(db *sql.DB) *EventRepo {
+	return &EventRepo{DB: db}
+}
+
+// AdEvent — событие об объявлении для потока изменений.
+type AdEvent struct {
+	Event *domain.Event
+	Ad    domain.AdEventData
+	// OwnerHidden — владелец сейчас под баном или теневым баном.
+	OwnerHidden bool
+}
+
+const adEventQuery = `
+        SELECT e.id, e.type, e.version, e.created_at, e.payload,
+               EXISTS (
+                   SELECT 1 FROM users u
+                   JOIN user_sanctions s ON s.user_id = u.id
+                   WHERE u.telegram_id = e.payload->>'telegram_id' AND ` + activeSanction + `)
+        FROM events e
+        WHERE e.type LIKE 'ad.%' AND `
+
+func (r *EventRepo) queryAdEvents(where string, args ...interface{}) ([]*AdEvent, error) {
+	rows, err := r.DB.Query(adEventQuery+where, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list ad events: %w", err)
+	}
+	defer rows.Close()
+
+	var list []*AdEvent
+	for rows.Next() {
+		ev := &AdEvent{Event: &domain.Event{}}
+		e := ev.Event
+		if err := rows.Scan(&e.ID, &e.Type, &e.Version, &e.CreatedAt, &e.Data, &ev.OwnerHidden); err != nil {
+			return nil, fmt.Errorf("scan ad event: %w", err)
+		}
+		if err := json.Unmarshal(e.Data, &ev.Ad); err != nil {
+			return nil, fmt.Errorf("decode ad event %d: %w", e.ID, err)
+		}
+		list = append(list, ev)
+	}
+	return list, rows.Err()
+}
+
+// AdEventsAfter возвращает до limit событий об объявлениях с ID больше afterID
+// по порядку.
+func (r *EventRepo) AdEventsAfter(afterID int64, limit int) ([]*AdEvent, error) {
+	return r.queryAdEvents(`e.id > $1 ORDER BY e.id LIMIT $2`, afterID, limit)
+}
+
+// AdEventsByID возвращает события об объявлениях из ids по порядку;
+// события других типов пропускаются.
+func (r *EventRepo) AdEventsByID(ids []int64) ([]*AdEvent, error) {
+	return r.queryAdEvents(`e.id = ANY($1) ORDER BY e.id`, pq.Array(ids))
+}
+
+// FirstID возвращает ID самого старого хранимого события; 0 — журнал пуст.
+func (r *EventRepo) FirstID() (int64, error) {
+	var id sql.NullInt64
+	err := r.DB.QueryRow(`SELECT MIN(id) FROM events`).Scan(&id)
+	return id.Int64, err
+}
+
+// LastID возвращает ID самого нового события; 0 — журнал пуст.
+func (r *EventRepo) LastID() (int64, error) {
+	var id sql.NullInt64
+	err := r.DB.QueryRow(`SELECT MAX(id) FROM events`).Scan(&id)
+	return id.Int64, err
+}
@@ -0,0 +1,330 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"poppins/domain"
+	"time"
+)
+
+type ExportRepo struct {
+	DB *sql.DB
+}
+
+func NewExportRepo(db *sql.DB) *ExportRepo {
+	return &ExportRepo{DB: db}
+}
+
+const exportColumns = `
+            e.id,
+            u.telegram_id,
+            e.status,
+            e.attempts,
+            e.error,
+            e.object_name,
+            e.size,
+            e.created_at,
+            e.finished_at,
+            e.expires_at`
+
+func scanExport(row rowScanner) (*domain.DataExport, error) {
+	e := &domain.DataExport{}
+	var finishedAt, expiresAt sql.NullTime
+	if err := row.Scan(
+		&e.ID, &e.TelegramID, &e.Status, &e.Attempts, &e.Error, &e.ObjectName, &e.Size,
+		&e.CreatedAt, &finishedAt, &expiresAt,
+	); err != nil {
+		return nil, err
+	}
+	if finishedAt.Valid {
+		e.FinishedAt = &finishedAt.Time
+	}
+	if expiresAt.Valid {
+		e.ExpiresAt = &expiresAt.Time
+	}
+	return e, nil
+}
+
+// Request возвращает текущую выгрузку пользователя — собираемую или готовую
+// и ещё не истёкшую — либо ставит новую в очередь; created сообщает, что
+// выгрузка новая.
+func (r *ExportRepo) Request(telegramID string) (e *domain.DataExport, created bool, err error) {
+	err = WithTx(r.DB, func(tx *sql.Tx) error {
+		// Блокировка пользователя не даёт двум запросам поставить две выгрузки
+		var userID int64
+		err := tx.QueryRow(`SELECT id FROM users WHERE telegram_id = $1 FOR UPDATE`, telegramID).Scan(&userID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("lock user: %w", err)
+		}
+
+		e, err = scanExport(tx.QueryRow(
+			`SELECT`+exportColumns+`
+             FROM data_exports e
+             JOIN users u ON u.id = e.user_id
+             WHERE e.user_id = $1
+               AND (e.status IN ('pending', 'running') OR (e.status = 'ready' AND e.expires_at > now()))
+             ORDER BY e.id DESC
+             LIMIT 1`,
+			userID,
+		))
+		if err == nil || !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+
+		created = true
+		e, err = scanExport(tx.QueryRow(
+			`WITH ins AS (
+                 INSERT INTO data_exports (user_id) VALUES ($1) RETURNING *
+             )
+             SELECT`+exportColumns+` FROM ins e JOIN users u ON u.id = e.user_id`,
+			userID,
+		))
+		if err != nil {
+			return fmt.Errorf("insert data export: %w", err)
+		}
+		return nil
+	})
+	return e, created, err
+}
+
+// Claim берёт в работу одну выгрузку из очереди, засчитывает попытку и
+// закрепляет её за обработчиком на lease. Если обработчик упадёт, выгрузка
+// вернётся в очередь после lease. nil — очередь пуста.
+func (r *ExportRepo) Claim(lease time.Duration) (*domain.DataExport, error) {
+	e, err := scanExport(r.DB.QueryRow(
+		`WITH next AS (
+             SELECT id FROM data_exports
+             WHERE status IN ('pending', 'running') AND next_attempt_at <= now()
+             ORDER BY next_attempt_at
+             LIMIT 1
+             FOR UPDATE SKIP LOCKED
+         )
+         UPDATE data_exports e
+         SET status = 'running', attempts = e.attempts + 1,
+             next_attempt_at = now() + $1 * interval '1 millisecond'
+         FROM next, users u
+         WHERE e.id = next.id AND u.id = e.user_id
+         RETURNING`+exportColumns,
+		lease.Milliseconds(),
+	))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("claim data export: %w", err)
+	}
+	return e, nil
+}
+
+// Complete отмечает выгрузку готовой и в той же транзакции ставит
+// пользователю уведомление о ней.
+func (r *ExportRepo) Complete(id int64, objectName string, size int64, expiresAt time.Time) error {
+	return WithTx(r.DB, func(tx *sql.Tx) error {
+		e, err := scanExport(tx.QueryRow(
+			`UPDATE data_exports e
+             SET status = 'ready', object_name = $2, size = $3, expires_at = $4,
+                 finished_at = now(), error = ''
+             FROM users u
+             WHERE e.id = $1 AND e.status = 'running' AND u.id = e.user_id
+             RETURNING`+exportColumns,
+			id, objectName, size, expiresAt,
+		))
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("complete data export: %w", err)
+		}
+		return enqueueTx(tx, e.TelegramID, domain.NotifyExport, domain.ExportReadyText(e))
+	})
+}
+
+// Retry возвращает выгрузку в очередь; следующая попытка не раньше at.
+func (r *ExportRepo) Retry(id int64, at time.Time, lastError string) error {
+	_, err := r.DB.Exec(
+		`UPDATE data_exports SET status = 'pending', next_attempt_at = $2, error = $3
+         WHERE id = $1 AND status = 'running'`,
+		id, at, lastError)
+	return err
+}
+
+// Fail прекращает сборку выгрузки.
+func (r *ExportRepo) Fail(id int64, lastError string) error {
+	_, err := r.DB.Exec(
+		`UPDATE data_exports SET status = 'failed', error = $2, finished_at = now() WHERE id = $1`,
+		id, lastError)
+	return err
+}
+
+// ListExpired возвращает до limit готовых выгрузок с истёкшим сроком хранения.
+func (r *ExportRepo) ListExpired(limit int) ([]*domain.DataExport, error) {
+	rows, err := r.DB.Query(
+		`SELECT`+exportColumns+`
+         FROM data_exports e
+         JOIN users u ON u.id = e.user_id
+         WHERE e.status = 'ready' AND e.expires_at <= now()
+         ORDER BY e.expires_at
+         LIMIT $1`,
+		limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("list expired data exports: %w", err)
+	}
+	defer rows.Close()
+
+	var list []*domain.DataExport
+	for rows.Next() {
+		e, err := scanExport(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan data export: %w", err)
+		}
+		list = append(list, e)
+	}
+	return list, rows.Err()
+}
+
+// MarkExpired отмечает, что архив выгрузки удалён из хранилища.
+func (r *ExportRepo) MarkExpired(id int64) error {
+	_, err := r.DB.Exec(`UPDATE data_exports SET status = 'expired', object_name = '' WHERE id = $1`, id)
+	return err
+}
+
+// Ads возвращает все объявления пользователя, включая архивные, снятые
+// модератором и ждущие проверки.
+func (r *ExportRepo) Ads(telegramID string) ([]*domain.Advertisement, error) {
+	rows, err := r.DB.Query(
+		`SELECT`+adColumns+`
+         FROM advertisements a
+         JOIN users u ON a.user_id = u.id
+         WHERE u.telegram_id = $1
+         ORDER BY a.id`,
+		telegramID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("query ads: %w", err)
+	}
+	defer rows.Close()
+
+	var ads []*domain.Advertisement
+	for rows.Next() {
+		ad, err := scanAd(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan ad row: %w", err)
+		}
+		ads = append(ads, ad)
+	}
+	return ads, rows.Err()
+}
+
+// Favorites возвращает всё избранное пользователя, в том числе снятые с
+// публикации объявления.
+func (r *ExportRepo) Favorites(telegramID string) ([]*domain.ExportFavorite, error) {
+	rows, err := r.DB.Query(
+		`SELECT a.id, a.title, f.created_at
+         FROM favorites f
+         JOIN users fu ON fu.id = f.user_id
+         JOIN advertisements a ON a.id = f.ad_id
+         WHERE fu.telegram_id = $1
+         ORDER BY f.created_at`,
+		telegramID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("query favorites: %w", err)
+	}
+	defer rows.Close()
+
+	var list []*domain.ExportFavorite
+	for rows.Next() {
+		f := &domain.ExportFavorite{}
+		if err := rows.Scan(&f.AdID, &f.AdTitle, &f.AddedAt); err != nil {
+			return nil, fmt.Errorf("scan favorite: %w", err)
+		}
+		list = append(list, f)
+	}
+	return list, rows.Err()
+}
+
+// Conversations возвращает переписки пользователя со всеми сообщениями в
+// том виде, в каком он сам их видит: скрытые сообщения собеседника не попадают.
+func (r *ExportRepo) Conversations(telegramID string) ([]*domain.ExportConversation, error) {
+	rows, err := r.DB.Query(
+		`SELECT c.id, c.ad_id, a.title,
+                CASE WHEN c.buyer_user_id = u.id THEN 'buyer' ELSE 'seller' END,
+                c.created_at,
+                m.id, m.sender_user_id = u.id, m.text, m.created_at, m.read_at
+         FROM users u
+         JOIN conversations c ON u.id IN (c.buyer_user_id, c.seller_user_id)
+         JOIN advertisements a ON a.id = c.ad_id
+         LEFT JOIN messages m ON m.conversation_id = c.id AND (m.sender_user_id = u.id OR NOT m.hidden)
+         WHERE u.telegram_id = $1
+         ORDER BY c.id, m.id`,
+		telegramID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("query conversations: %w", err)
+	}
+	defer rows.Close()
+
+	var list []*domain.ExportConversation
+	var cur *domain.ExportConversation
+	for rows.Next() {
+		c := &domain.ExportConversation{Messages: []*domain.Message{}}
+		var msgID sql.NullInt64
+		var fromMe sql.NullBool
+		var text sql.NullString
+		var sentAt, readAt sql.NullTime
+		if err := rows.Scan(
+			&c.ID, &c.AdID, &c.AdTitle, &c.Role, &c.CreatedAt,
+			&msgID, &fromMe, &text, &sentAt, &readAt,
+		); err != nil {
+			return nil, fmt.Errorf("scan conversation: %w", err)
+		}
+		if cur == nil || cur.ID != c.ID {
+			cur = c
+			list = append(list, cur)
+		}
+		if !msgID.Valid {
+			continue
+		}
+		m := &domain.Message{
+			ID: msgID.Int64, ConversationID: cur.ID, FromMe: fromMe.Bool,
+			Text: text.String, CreatedAt: sentAt.Time,
+		}
+		if readAt.Valid {
+			m.ReadAt = &readAt.Time
+		}
+		cur.Messages = append(cur.Messages, m)
+	}
+	return list, rows.Err()
+}
+
+// ReviewsWritten возвращает отзывы, оставленные пользователем, с любым статусом.
+func (r *ExportRepo) ReviewsWritten(telegramID string) ([]*domain.Review, error) {
+	return r.reviews(`bu.telegram_id = $1`, telegramID)
+}
+
+// ReviewsReceived возвращает отзывы о пользователе как продавце, с любым статусом.
+func (r *ExportRepo) ReviewsReceived(telegramID string) ([]*domain.Review, error) {
+	return r.reviews(`su.telegram_id = $1`, telegramID)
+}
+
+func (r *ExportRepo) reviews(where string, args ...interface{}) ([]*domain.Review, error) {
+	rows, err := r.DB.Query(`SELECT`+reviewColumns+reviewFrom+` WHERE `+where+` ORDER BY rv.id`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query reviews: %w", err)
+	}
+	defer rows.Close()
+	var list []*domain.Review
+	for rows.Next() {
+		rv, err := scanReview(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, rv)
+	}
+	return list, rows.Err()
+}
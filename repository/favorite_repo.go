@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"poppins/domain"
+)
+
+type FavoriteRepo struct {
+	DB *sql.DB
+}
+
+func NewFavoriteRepo(db *sql.DB) *FavoriteRepo {
+	return &FavoriteRepo{DB: db}
+}
+
+// Add добавляет опубликованное объявление в избранное пользователя.
+// Повторное добавление ничего не меняет.
+func (r *FavoriteRepo) Add(telegramID string, adID int64) error {
+	return WithTx(r.DB, func(tx *sql.Tx) error {
+		var userID int64
+		err := tx.QueryRow(
+			`SELECT vu.id
+             FROM users vu, advertisements a
+             JOIN users u ON u.id = a.user_id
+             WHERE vu.telegram_id = $1 AND a.id = $2
+               AND a.status = 'active' AND a.archived = FALSE
+               AND `+fmt.Sprintf(visibleOwner, "$1"),
+			telegramID, adID,
+		).Scan(&userID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("find ad for favorite: %w", err)
+		}
+		res, err := tx.Exec(
+			`INSERT INTO favorites (user_id, ad_id) VALUES ($1, $2) ON CONFLICT DO NOTHING`,
+			userID, adID,
+		)
+		if err != nil {
+			return fmt.Errorf("insert favorite: %w", err)
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return nil
+		}
+		return bumpDailyStatTx(tx, adID, statFavorites)
+	})
+}
+
+// Remove убирает объявление из избранного.
+func (r *FavoriteRepo) Remove(telegramID string, adID int64) error {
+	_, err := r.DB.Exec(
+		`DELETE FROM favorites f
+         USING users u
+         WHERE u.id = f.user_id AND u.telegram_id = $1 AND f.ad_id = $2`,
+		telegramID, adID,
+	)
+	return err
+}
+
+// List возвращает избранные объявления пользователя, которые всё ещё
+// опубликованы, в порядке добавления (новые первыми).
+func (r *FavoriteRepo) List(telegramID string) ([]*domain.Advertisement, error) {
+	rows, err := r.DB.Query(
+		`SELECT`+adColumns+`
+         FROM favorites f
+         JOIN users fu ON fu.id = f.user_id
+         JOIN advertisements a ON a.id = f.ad_id
+         JOIN users u ON u.id = a.user_id
+         WHERE fu.telegram_id = $1
+           AND a.status = 'active' AND a.archived = FALSE
+           AND `+fmt.Sprintf(visibleOwner, "$1")+`
+         ORDER BY f.created_at DESC`,
+		telegramID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("query favorites: %w", err)
+	}
+	defer rows.Close()
+
+	var ads []*domain.Advertisement
+	for rows.Next() {
+		ad, err := scanAd(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan ad row: %w", err)
+		}
+		ads = append(ads, ad)
+	}
+	return ads, rows.Err()
+}
@@ -0,0 +1,113 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// IdempotencyRecord — сохранённый запрос с ключом идемпотентности.
+// StatusCode == 0, пока первый запрос ещё выполняется.
+type IdempotencyRecord struct {
+	Fingerprint string
+	StatusCode  int
+	Header      http.Header
+	Body        []byte
+	CreatedAt   time.Time
+}
+
+type IdempotencyRepo struct {
+	DB *sql.DB
+	// TTL — сколько хранится ответ.
+	TTL time.Duration
+	// StaleAfter — через сколько незавершённый запрос считается брошенным
+	// (экземпляр упал, не сохранив ответ), и ключ можно занять заново.
+	StaleAfter time.Duration
+}
+
+func NewIdempotencyRepo(db *sql.DB, ttl time.Duration) *IdempotencyRepo {
+	return &IdempotencyRepo{DB: db, TTL: ttl, StaleAfter: 5 * time.Minute}
+}
+
+// Reserve занимает ключ под новый запрос. Если ключ уже занят действующей
+// записью, возвращает её и reserved=false.
+func (r *IdempotencyRepo) Reserve(scope, key, fingerprint string) (rec *IdempotencyRecord, reserved bool, err error) {
+	now := time.Now()
+	res, err := r.DB.Exec(
+		`INSERT INTO idempotency_keys AS k (scope, key, fingerprint, created_at)
+         VALUES ($1, $2, $3, $4)
+         ON CONFLICT (scope, key) DO UPDATE
+             SET fingerprint = EXCLUDED.fingerprint, status_code = NULL,
+                 headers = NULL, body = NULL, created_at = EXCLUDED.created_at
+             WHERE k.created_at < $5
+                OR (k.status_code IS NULL AND k.created_at < $6)`,
+		scope, key, fingerprint, now, now.Add(-r.TTL), now.Add(-r.StaleAfter),
+	)
+	if err != nil {
+		return nil, false, fmt.Errorf("reserve idempotency key: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 1 {
+		return nil, true, nil
+	}
+
+	rec = &IdempotencyRecord{}
+	var status sql.NullInt64
+	var headers []byte
+	err = r.DB.QueryRow(
+		`SELECT fingerprint, status_code, headers, body, created_at
+         FROM idempotency_keys WHERE scope = $1 AND key = $2`,
+		scope, key,
+	).Scan(&rec.Fingerprint, &status, &headers, &rec.Body, &rec.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		// Запись удалили между запросами — пробуем ещё раз
+		return r.Reserve(scope, key, fingerprint)
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("load idempotency key: %w", err)
+	}
+	rec.StatusCode = int(status.Int64)
+	if len(headers) > 0 {
+		if err := json.Unmarshal(headers, &rec.Header); err != nil {
+			return nil, false, fmt.Errorf("decode idempotency headers: %w", err)
+		}
+	}
+	return rec, false, nil
+}
+
+// Complete сохраняет ответ на запрос.
+func (r *IdempotencyRepo) Complete(scope, key string, status int, header http.Header, body []byte) error {
+	h, err := json.Marshal(header)
+	if err != nil {
+		return err
+	}
+	_, err = r.DB.Exec(
+		`UPDATE idempotency_keys SET status_code = $3, headers = $4, body = $5
+         WHERE scope = $1 AND key = $2`,
+		scope, key, status, h, body,
+	)
+	return err
+}
+
+// Release освобождает ключ, если ответ сохранять не нужно (ошибка сервера),
+// чтобы повтор выполнился заново.
+func (r *IdempotencyRepo) Release(scope, key string) error {
+	_, err := r.DB.Exec(
+		`DELETE FROM idempotency_keys WHERE scope = $1 AND key = $2 AND status_code IS NULL`,
+		scope, key,
+	)
+	return err
+}
+
+// PurgeExpired удаляет ответы старше TTL.
+func (r *IdempotencyRepo) PurgeExpired() (int64, error) {
+	res, err := r.DB.Exec(
+		`DELETE FROM idempotency_keys WHERE created_at < $1`, time.Now().Add(-r.TTL),
+	)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
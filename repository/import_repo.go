@@ -0,0 +1,210 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"poppins/domain"
+	"time"
+)
+
+type ImportRepo struct {
+	DB *sql.DB
+}
+
+func NewImportRepo(db *sql.DB) *ImportRepo {
+	return &ImportRepo{DB: db}
+}
+
+const importColumns = `
+            j.id,
+            u.telegram_id,
+            j.status,
+            j.format,
+            j.filename,
+            j.source_object,
+            j.total,
+            j.created,
+            j.failed,
+            j.error,
+            j.attempts,
+            j.created_at,
+            j.finished_at`
+
+func scanImport(row rowScanner) (*domain.ImportJob, error) {
+	j := &domain.ImportJob{}
+	var finishedAt sql.NullTime
+	if err := row.Scan(
+		&j.ID, &j.TelegramID, &j.Status, &j.Format, &j.Filename, &j.SourceObject,
+		&j.Total, &j.Created, &j.Failed, &j.Error, &j.Attempts, &j.CreatedAt, &finishedAt,
+	); err != nil {
+		return nil, err
+	}
+	if finishedAt.Valid {
+		j.FinishedAt = &finishedAt.Time
+	}
+	return j, nil
+}
+
+// Create ставит импорт в очередь; файл уже должен лежать в хранилище под
+// j.SourceObject.
+func (r *ImportRepo) Create(j *domain.ImportJob) error {
+	err := r.DB.QueryRow(
+		`INSERT INTO ad_imports (user_id, format, filename, source_object, total)
+         SELECT id, $2, $3, $4, $5 FROM users WHERE telegram_id = $1
+         RETURNING id, status, created_at`,
+		j.TelegramID, j.Format, j.Filename, j.SourceObject, j.Total,
+	).Scan(&j.ID, &j.Status, &j.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	return err
+}
+
+// Get возвращает импорт пользователя telegramID вместе с результатами строк.
+func (r *ImportRepo) Get(telegramID string, id int64) (*domain.ImportJob, error) {
+	j, err := scanImport(r.DB.QueryRow(
+		`SELECT`+importColumns+`
+         FROM ad_imports j
+         JOIN users u ON u.id = j.user_id
+         WHERE j.id = $1 AND u.telegram_id = $2`,
+		id, telegramID,
+	))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.DB.Query(
+		`SELECT line, status, ad_id, error FROM ad_import_rows WHERE import_id = $1 ORDER BY line`, id)
+	if err != nil {
+		return nil, fmt.Errorf("list import rows: %w", err)
+	}
+	defer rows.Close()
+	j.Rows = []*domain.ImportRowResult{}
+	for rows.Next() {
+		res := &domain.ImportRowResult{}
+		var adID sql.NullInt64
+		if err := rows.Scan(&res.Line, &res.Status, &adID, &res.Error); err != nil {
+			return nil, fmt.Errorf("scan import row: %w", err)
+		}
+		if adID.Valid {
+			res.AdID = &adID.Int64
+		}
+		j.Rows = append(j.Rows, res)
+	}
+	return j, rows.Err()
+}
+
+// Claim берёт в работу один импорт из очереди, засчитывает попытку и
+// закрепляет его за обработчиком на lease. nil — очередь пуста.
+func (r *ImportRepo) Claim(lease time.Duration) (*domain.ImportJob, error) {
+	j, err := scanImport(r.DB.QueryRow(
+		`WITH next AS (
+             SELECT id FROM ad_imports
+             WHERE status IN ('pending', 'running') AND next_attempt_at <= now()
+             ORDER BY next_attempt_at
+             LIMIT 1
+             FOR UPDATE SKIP LOCKED
+         )
+         UPDATE ad_imports j
+         SET status = 'running', attempts = j.attempts + 1,
+             next_attempt_at = now() + $1 * interval '1 millisecond'
+         FROM next, users u
+         WHERE j.id = next.id AND u.id = j.user_id
+         RETURNING`+importColumns,
+		lease.Milliseconds(),
+	))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("claim import: %w", err)
+	}
+	return j, nil
+}
+
+// Processed возвращает номера уже обработанных строк импорта: после сбоя
+// обработчика они не создаются повторно.
+func (r *ImportRepo) Processed(id int64) (map[int]bool, error) {
+	rows, err := r.DB.Query(`SELECT line FROM ad_import_rows WHERE import_id = $1`, id)
+	if err != nil {
+		return nil, fmt.Errorf("list import rows: %w", err)
+	}
+	defer rows.Close()
+	done := map[int]bool{}
+	for rows.Next() {
+		var line int
+		if err := rows.Scan(&line); err != nil {
+			return nil, err
+		}
+		done[line] = true
+	}
+	return done, rows.Err()
+}
+
+// RecordRow сохраняет результат строки и обновляет счётчики импорта.
+func (r *ImportRepo) RecordRow(id int64, res *domain.ImportRowResult) error {
+	return WithTx(r.DB, func(tx *sql.Tx) error {
+		ins, err := tx.Exec(
+			`INSERT INTO ad_import_rows (import_id, line, status, ad_id, error)
+             VALUES ($1, $2, $3, $4, $5)
+             ON CONFLICT (import_id, line) DO NOTHING`,
+			id, res.Line, res.Status, res.AdID, res.Error,
+		)
+		if err != nil {
+			return fmt.Errorf("insert import row: %w", err)
+		}
+		if n, _ := ins.RowsAffected(); n == 0 {
+			return nil
+		}
+		column := "failed"
+		if res.Status == domain.ImportRowCreated {
+			column = "created"
+		}
+		_, err = tx.Exec(`UPDATE ad_imports SET `+column+` = `+column+` + 1 WHERE id = $1`, id)
+		return err
+	})
+}
+
+// SetTotal сохраняет число строк в файле.
+func (r *ImportRepo) SetTotal(id int64, total int) error {
+	_, err := r.DB.Exec(`UPDATE ad_imports SET total = $2 WHERE id = $1`, id, total)
+	return err
+}
+
+// Retry возвращает импорт в очередь; следующая попытка не раньше at.
+func (r *ImportRepo) Retry(id int64, at time.Time, lastError string) error {
+	_, err := r.DB.Exec(
+		`UPDATE ad_imports SET status = 'pending', next_attempt_at = $2, error = $3
+         WHERE id = $1 AND status = 'running'`,
+		id, at, lastError)
+	return err
+}
+
+// Finish завершает импорт со статусом status (done или failed): в той же
+// транзакции пользователь получает итог, а исходный файл ставится на удаление.
+func (r *ImportRepo) Finish(id int64, status, lastError string) error {
+	return WithTx(r.DB, func(tx *sql.Tx) error {
+		j, err := scanImport(tx.QueryRow(
+			`UPDATE ad_imports j
+             SET status = $2, error = $3, finished_at = now()
+             FROM users u
+             WHERE j.id = $1 AND j.status = 'running' AND u.id = j.user_id
+             RETURNING`+importColumns,
+			id, status, lastError,
+		))
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("finish import: %w", err)
+		}
+		if err := enqueueStorageDeletionTx(tx, domain.StorageImport, j.SourceObject); err != nil {
+			return err
+		}
+		return enqueueTx(tx, j.TelegramID, domain.NotifyImport, domain.ImportDoneText(j))
+	})
+}
@@ -0,0 +1,200 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"poppins/domain"
+	"time"
+)
+
+type ModerationRepo struct {
+	DB *sql.DB
+	// ClaimTTL — сколько задача остаётся за модератором, взявшим её в работу.
+	ClaimTTL time.Duration
+}
+
+func NewModerationRepo(db *sql.DB, claimTTL time.Duration) *ModerationRepo {
+	return &ModerationRepo{DB: db, ClaimTTL: claimTTL}
+}
+
+const moderationTaskColumns = `
+            t.id,
+            t.ad_id,
+            t.status,
+            t.trigger,
+            t.notes,
+            t.claimed_by,
+            t.claimed_at,
+            t.reason_code,
+            t.comment,
+            t.resolved_by,
+            t.resolved_at,
+            t.created_at`
+
+func scanModerationTask(row rowScanner, withAd bool) (*domain.ModerationTask, error) {
+	t := &domain.ModerationTask{}
+	var claimedAt, resolvedAt sql.NullTime
+	dest := []interface{}{
+		&t.ID, &t.AdID, &t.Status, &t.Trigger, &t.Notes, &t.ClaimedBy, &claimedAt,
+		&t.ReasonCode, &t.Comment, &t.ResolvedBy, &resolvedAt, &t.CreatedAt,
+	}
+	ad := &domain.Advertisement{}
+	if withAd {
+		dest = append(dest, adDest(ad)...)
+	}
+	if err := row.Scan(dest...); err != nil {
+		return nil, err
+	}
+	if claimedAt.Valid {
+		t.ClaimedAt = &claimedAt.Time
+	}
+	if resolvedAt.Valid {
+		t.ResolvedAt = &resolvedAt.Time
+	}
+	if withAd {
+		t.Ad = ad
+	}
+	return t, nil
+}
+
+// insertModerationTaskTx ставит объявление в очередь. Если открытая задача
+// уже есть, новая не создаётся.
+func insertModerationTaskTx(tx *sql.Tx, adID int64, trigger, notes string) error {
+	_, err := tx.Exec(
+		`INSERT INTO moderation_tasks (ad_id, trigger, notes)
+         VALUES ($1, $2, $3)
+         ON CONFLICT (ad_id) WHERE status IN ('pending', 'claimed') DO NOTHING`,
+		adID, trigger, notes,
+	)
+	if err != nil {
+		return fmt.Errorf("enqueue moderation task: %w", err)
+	}
+	return nil
+}
+
+// List возвращает задачи в порядке поступления. Пустой status — все открытые.
+func (r *ModerationRepo) List(status string, limit int) ([]*domain.ModerationTask, error) {
+	rows, err := r.DB.Query(
+		`SELECT`+moderationTaskColumns+`,`+adColumns+`
+         FROM moderation_tasks t
+         JOIN advertisements a ON a.id = t.ad_id
+         JOIN users u ON u.id = a.user_id
+         WHERE ($1 = '' AND t.status IN ('pending', 'claimed')) OR t.status = $1
+         ORDER BY t.created_at
+         LIMIT $2`,
+		status, limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("list moderation tasks: %w", err)
+	}
+	defer rows.Close()
+
+	var tasks []*domain.ModerationTask
+	for rows.Next() {
+		t, err := scanModerationTask(rows, true)
+		if err != nil {
+			return nil, fmt.Errorf("scan moderation task: %w", err)
+		}
+		tasks = append(tasks, t)
+	}
+	return tasks, rows.Err()
+}
+
+// lockTaskTx блокирует задачу и проверяет, что moderator может по ней работать:
+// задача открыта и не занята другим модератором (или его claim истёк).
+func (r *ModerationRepo) lockTaskTx(tx *sql.Tx, taskID int64, moderator string) (*domain.ModerationTask, error) {
+	t, err := scanModerationTask(tx.QueryRow(
+		`SELECT`+moderationTaskColumns+` FROM moderation_tasks t WHERE t.id = $1 FOR UPDATE`,
+		taskID,
+	), false)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("lock moderation task: %w", err)
+	}
+	switch t.Status {
+	case domain.ModerationPending:
+	case domain.ModerationClaimed:
+		stale := t.ClaimedAt == nil || time.Since(*t.ClaimedAt) > r.ClaimTTL
+		if t.ClaimedBy != moderator && !stale {
+			return nil, fmt.Errorf("task claimed by %s: %w", t.ClaimedBy, ErrConflict)
+		}
+	default:
+		return nil, fmt.Errorf("task already %s: %w", t.Status, ErrConflict)
+	}
+	return t, nil
+}
+
+// Claim закрепляет задачу за модератором на ClaimTTL.
+func (r *ModerationRepo) Claim(taskID int64, moderator string) (*domain.ModerationTask, error) {
+	var task *domain.ModerationTask
+	err := WithTx(r.DB, func(tx *sql.Tx) error {
+		t, err := r.lockTaskTx(tx, taskID, moderator)
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		t.Status = domain.ModerationClaimed
+		t.ClaimedBy = moderator
+		t.ClaimedAt = &now
+		task = t
+		_, err = tx.Exec(
+			`UPDATE moderation_tasks SET status='claimed', claimed_by=$2, claimed_at=$3 WHERE id=$1`,
+			t.ID, moderator, now,
+		)
+		return err
+	})
+	return task, err
+}
+
+// Resolve фиксирует решение модератора и в той же транзакции публикует
+// или отклоняет объявление и ставит уведомление продавцу в очередь.
+// Возвращает задачу вместе с объявлением.
+func (r *ModerationRepo) Resolve(taskID int64, moderator string, approve bool, reasonCode, comment string) (*domain.ModerationTask, error) {
+	var task *domain.ModerationTask
+	err := WithTx(r.DB, func(tx *sql.Tx) error {
+		t, err := r.lockTaskTx(tx, taskID, moderator)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		t.Status, t.ReasonCode, t.Comment = domain.ModerationApproved, "", comment
+		adStatus := domain.AdStatusActive
+		if !approve {
+			t.Status, t.ReasonCode = domain.ModerationRejected, reasonCode
+			adStatus = domain.AdStatusRejected
+		}
+		t.ResolvedBy = moderator
+		t.ResolvedAt = &now
+
+		if _, err := tx.Exec(
+			`UPDATE moderation_tasks
+             SET status=$2, reason_code=$3, comment=$4, resolved_by=$5, resolved_at=$6
+             WHERE id=$1`,
+			t.ID, t.Status, t.ReasonCode, t.Comment, moderator, now,
+		); err != nil {
+			return err
+		}
+
+		t.Ad, err = scanAd(tx.QueryRow(
+			`UPDATE advertisements a
+             SET status=$2, moderation_reason=$3
+             FROM users u
+             WHERE a.id=$1 AND u.id = a.user_id
+             RETURNING`+adColumns,
+			t.AdID, adStatus, t.ReasonCode,
+		))
+		if err != nil {
+			return fmt.Errorf("update ad status: %w", err)
+		}
+		task = t
+		if err := emitAdEventTx(tx, domain.EventAdUpdated, t.Ad); err != nil {
+			return err
+		}
+		return enqueueTx(tx, t.Ad.TelegramID, domain.NotifyModeration, domain.ModerationOutcomeText(t))
+	})
+	return task, err
+}
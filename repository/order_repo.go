@@ -0,0 +1,242 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"poppins/domain"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type OrderRepo struct {
+	DB *sql.DB
+}
+
+func NewOrderRepo(db *sql.DB) *OrderRepo {
+	return &OrderRepo{DB: db}
+}
+
+// OrderChange — данные перехода статуса заказа.
+type OrderChange struct {
+	// Source — кто перевёл заказ: провайдер, пользователь, администратор.
+	Source string
+	// ChargeID — идентификатор платежа у провайдера.
+	ChargeID string
+	// Amount и Currency — сумма из уведомления об оплате; при переходе в
+	// paid должны совпасть с заказом.
+	Amount   int64
+	Currency string
+}
+
+const orderColumns = `
+            o.id,
+            u.telegram_id,
+            o.ad_id,
+            o.product,
+            o.title,
+            o.amount,
+            o.currency,
+            o.provider,
+            o.status,
+            o.provider_charge_id,
+            o.created_at,
+            o.updated_at,
+            o.paid_at,
+            o.refunded_at`
+
+func scanOrder(row rowScanner) (*domain.Order, error) {
+	o := &domain.Order{}
+	var adID sql.NullInt64
+	var paidAt, refundedAt sql.NullTime
+	if err := row.Scan(
+		&o.ID, &o.TelegramID, &adID, &o.Product, &o.Title, &o.Amount, &o.Currency, &o.Provider,
+		&o.Status, &o.ProviderChargeID, &o.CreatedAt, &o.UpdatedAt, &paidAt, &refundedAt,
+	); err != nil {
+		return nil, err
+	}
+	if adID.Valid {
+		o.AdID = &adID.Int64
+	}
+	if paidAt.Valid {
+		o.PaidAt = &paidAt.Time
+	}
+	if refundedAt.Valid {
+		o.RefundedAt = &refundedAt.Time
+	}
+	return o, nil
+}
+
+func getOrderTx(tx *sql.Tx, id int64) (*domain.Order, error) {
+	o, err := scanOrder(tx.QueryRow(
+		`SELECT`+orderColumns+` FROM orders o JOIN users u ON u.id = o.user_id
+         WHERE o.id = $1 FOR UPDATE OF o`, id,
+	))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	return o, err
+}
+
+// Create сохраняет новый заказ пользователя o.TelegramID.
+func (r *OrderRepo) Create(o *domain.Order) error {
+	err := r.DB.QueryRow(
+		`INSERT INTO orders (user_id, ad_id, product, title, amount, currency, provider)
+         SELECT u.id, $2, $3, $4, $5, $6, $7 FROM users u WHERE u.telegram_id = $1
+         RETURNING id, status, created_at, updated_at`,
+		o.TelegramID, o.AdID, o.Product, o.Title, o.Amount, o.Currency, o.Provider,
+	).Scan(&o.ID, &o.Status, &o.CreatedAt, &o.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	return err
+}
+
+// Get возвращает заказ по ID.
+func (r *OrderRepo) Get(id int64) (*domain.Order, error) {
+	o, err := scanOrder(r.DB.QueryRow(
+		`SELECT`+orderColumns+` FROM orders o JOIN users u ON u.id = o.user_id WHERE o.id = $1`, id,
+	))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	return o, err
+}
+
+// ListByTelegram возвращает заказы пользователя, новые первыми.
+func (r *OrderRepo) ListByTelegram(telegramID string) ([]*domain.Order, error) {
+	rows, err := r.DB.Query(
+		`SELECT`+orderColumns+` FROM orders o JOIN users u ON u.id = o.user_id
+         WHERE u.telegram_id = $1
+         ORDER BY o.created_at DESC`,
+		telegramID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("query orders: %w", err)
+	}
+	defer rows.Close()
+	var list []*domain.Order
+	for rows.Next() {
+		o, err := scanOrder(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, o)
+	}
+	return list, rows.Err()
+}
+
+// Transition переводит заказ в статус to. Повторный переход в текущий статус
+// ничего не меняет и возвращает changed=false — так повторные уведомления
+// провайдера безопасны. Недопустимый переход возвращает ErrConflict.
+// Оплата подключает купленную услугу, возврат — отключает, в той же транзакции;
+// о них покупатель получает уведомление.
+func (r *OrderRepo) Transition(id int64, to string, ch OrderChange) (o *domain.Order, changed bool, err error) {
+	err = WithTx(r.DB, func(tx *sql.Tx) error {
+		cur, err := getOrderTx(tx, id)
+		if err != nil {
+			return err
+		}
+		o = cur
+		if cur.Status == to {
+			return nil
+		}
+		if !domain.CanTransition(cur.Status, to) {
+			return fmt.Errorf("order is %s, cannot become %s: %w", cur.Status, to, ErrConflict)
+		}
+
+		source := ch.Source
+		switch to {
+		case domain.OrderPaid:
+			if err := checkPayment(cur, ch); err != nil {
+				return err
+			}
+			if _, err := tx.Exec(
+				`UPDATE orders SET paid_at = now(), provider_charge_id = $2 WHERE id = $1`,
+				id, ch.ChargeID,
+			); err != nil {
+				return fmt.Errorf("mark order paid: %w", err)
+			}
+			note, err := fulfillOrderTx(tx, cur)
+			if err != nil {
+				return err
+			}
+			if note != "" {
+				source += "; " + note
+			}
+		case domain.OrderRefunded:
+			if _, err := tx.Exec(
+				`UPDATE orders SET refunded_at = now() WHERE id = $1`, id,
+			); err != nil {
+				return fmt.Errorf("mark order refunded: %w", err)
+			}
+			if _, err := tx.Exec(
+				`UPDATE ad_promotions SET cancelled_at = COALESCE(cancelled_at, now())
+                 WHERE created_by = $1`,
+				orderSource(id),
+			); err != nil {
+				return fmt.Errorf("cancel order promotions: %w", err)
+			}
+		}
+
+		if _, err := tx.Exec(
+			`UPDATE orders SET status = $2, updated_at = now() WHERE id = $1`, id, to,
+		); err != nil {
+			return fmt.Errorf("update order status: %w", err)
+		}
+		if _, err := tx.Exec(
+			`INSERT INTO order_events (order_id, from_status, to_status, source) VALUES ($1, $2, $3, $4)`,
+			id, cur.Status, to, source,
+		); err != nil {
+			return fmt.Errorf("insert order event: %w", err)
+		}
+		changed = true
+		if o, err = getOrderTx(tx, id); err != nil {
+			return err
+		}
+		if text := domain.OrderStatusText(o); text != "" {
+			return enqueueTx(tx, o.TelegramID, domain.NotifyOrder, text)
+		}
+		return nil
+	})
+	return o, changed, err
+}
+
+// checkPayment сверяет оплаченную сумму и валюту с заказом: уведомление без
+// суммы заказ не оплачивает.
+func checkPayment(o *domain.Order, ch OrderChange) error {
+	if ch.Amount != o.Amount || !strings.EqualFold(ch.Currency, o.Currency) {
+		return fmt.Errorf("paid %d %s, expected %d %s: %w",
+			ch.Amount, ch.Currency, o.Amount, o.Currency, ErrConflict)
+	}
+	return nil
+}
+
+// orderSource — created_by услуг, подключённых заказом id.
+func orderSource(id int64) string {
+	return "order:" + strconv.FormatInt(id, 10)
+}
+
+// fulfillOrderTx подключает оплаченную услугу. Если объявление успело
+// исчезнуть, оплата всё равно фиксируется, а note объясняет, почему услуга
+// не подключена (деньги возвращает администратор).
+func fulfillOrderTx(tx *sql.Tx, o *domain.Order) (note string, err error) {
+	pkgCode, ok := strings.CutPrefix(o.Product, "promotion:")
+	if !ok {
+		return "", fmt.Errorf("unknown product %q", o.Product)
+	}
+	pkg, ok := domain.PromotionPackages[pkgCode]
+	if !ok {
+		return "", fmt.Errorf("unknown promotion package %q", pkgCode)
+	}
+	if o.AdID == nil {
+		return "ad was deleted, promotion not applied", nil
+	}
+	now := time.Now()
+	_, err = createPromotionTx(tx, *o.AdID, pkg.Code, now, now.Add(pkg.Duration), orderSource(o.ID))
+	if errors.Is(err, ErrNotFound) {
+		return "ad is unavailable, promotion not applied", nil
+	}
+	return "", err
+}
@@ -0,0 +1,35 @@
+package repository
+
+import (
+	"errors"
+	"poppins/domain"
+	"testing"
+)
+
+func TestCheckPayment(t *testing.T) {
+	order := &domain.Order{Amount: 19900, Currency: "RUB"}
+	tests := []struct {
+		name    string
+		change  OrderChange
+		wantErr bool
+	}{
+		{"exact amount", OrderChange{Amount: 19900, Currency: "RUB"}, false},
+		{"currency case", OrderChange{Amount: 19900, Currency: "rub"}, false},
+		{"no amount", OrderChange{}, true},
+		{"no amount with currency", OrderChange{Currency: "RUB"}, true},
+		{"underpaid", OrderChange{Amount: 100, Currency: "RUB"}, true},
+		{"overpaid", OrderChange{Amount: 20000, Currency: "RUB"}, true},
+		{"other currency", OrderChange{Amount: 19900, Currency: "USD"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkPayment(order, tt.change)
+			if tt.wantErr && !errors.Is(err, ErrConflict) {
+				t.Errorf("error = %v, want ErrConflict", err)
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
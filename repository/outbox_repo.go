@@ -0,0 +1,231 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"poppins/domain"
+	"time"
+)
+
+// errBotBlocked — last_error уведомлений пользователя, заблокировавшего бота.
+const errBotBlocked = "bot blocked by user"
+
+// enqueueTx ставит уведомление в outbox в транзакции изменения, о котором оно
+// сообщает: откат изменения отменяет и уведомление, а закоммиченное
+// изменение не останется без него. Пользователю, заблокировавшему бота,
+// уведомление сразу записывается недоставленным.
+func enqueueTx(tx *sql.Tx, telegramID, kind, text string) error {
+	_, err := tx.Exec(
+		`INSERT INTO outbox (telegram_id, kind, text, status, last_error)
+         SELECT $1, $2, $3,
+                CASE WHEN b.blocked THEN 'dead' ELSE 'pending' END,
+                CASE WHEN b.blocked THEN $4 ELSE '' END
+         FROM (SELECT EXISTS (
+                   SELECT 1 FROM users WHERE telegram_id = $1 AND bot_blocked_at IS NOT NULL
+               ) AS blocked) b`,
+		telegramID, kind, text, errBotBlocked,
+	)
+	if err != nil {
+		return fmt.Errorf("enqueue notification: %w", err)
+	}
+	return nil
+}
+
+type OutboxRepo struct {
+	DB *sql.DB
+}
+
+func NewOutboxRepo(db *sql.DB) *OutboxRepo {
+	return &OutboxRepo{DB: db}
+}
+
+const notificationColumns = `
+            o.id,
+            o.telegram_id,
+            o.kind,
+            o.text,
+            o.status,
+            o.attempts,
+            o.next_attempt_at,
+            o.last_error,
+            o.created_at,
+            o.sent_at`
+
+func scanNotification(row rowScanner) (*domain.Notification, error) {
+	n := &domain.Notification{}
+	var sentAt sql.NullTime
+	if err := row.Scan(
+		&n.ID, &n.TelegramID, &n.Kind, &n.Text, &n.Status, &n.Attempts,
+		&n.NextAttemptAt, &n.LastError, &n.CreatedAt, &sentAt,
+	); err != nil {
+		return nil, err
+	}
+	if sentAt.Valid {
+		n.SentAt = &sentAt.Time
+	}
+	return n, nil
+}
+
+func scanNotifications(rows *sql.Rows) ([]*domain.Notification, error) {
+	defer rows.Close()
+	var list []*domain.Notification
+	for rows.Next() {
+		n, err := scanNotification(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan notification: %w", err)
+		}
+		list = append(list, n)
+	}
+	return list, rows.Err()
+}
+
+// Claim выбирает до limit уведомлений, готовых к отправке, засчитывает
+// попытку и откладывает их на lease, чтобы другие обработчики их не взяли.
+// Если обработчик упадёт, уведомления вернутся в работу после lease.
+// Пользователю уведомления уходят по порядку: пока старое не доставлено или
+// не отброшено, следующие ждут.
+func (r *OutboxRepo) Claim(limit int, lease time.Duration) ([]*domain.Notification, error) {
+	rows, err := r.DB.Query(
+		`WITH next AS (
+             SELECT o.id FROM outbox o
+             WHERE o.status = 'pending' AND o.next_attempt_at <= now()
+               AND NOT EXISTS (
+                   SELECT 1 FROM outbox p
+                   WHERE p.telegram_id = o.telegram_id AND p.status = 'pending' AND p.id < o.id)
+             ORDER BY o.id
+             LIMIT $1
+             FOR UPDATE SKIP LOCKED
+         ), upd AS (
+             UPDATE outbox o
+             SET attempts = o.attempts + 1, next_attempt_at = now() + $2 * interval '1 millisecond'
+             FROM next
+             WHERE o.id = next.id
+             RETURNING o.*
+         )
+         SELECT`+notificationColumns+` FROM upd o ORDER BY o.id`,
+		limit, lease.Milliseconds(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("claim notifications: %w", err)
+	}
+	return scanNotifications(rows)
+}
+
+// MarkSent отмечает уведомление доставленным.
+func (r *OutboxRepo) MarkSent(id int64) error {
+	_, err := r.DB.Exec(
+		`UPDATE outbox SET status = 'sent', sent_at = now(), last_error = '' WHERE id = $1`, id)
+	return err
+}
+
+// Retry откладывает следующую попытку доставки до at.
+func (r *OutboxRepo) Retry(id int64, at time.Time, lastError string) error {
+	_, err := r.DB.Exec(
+		`UPDATE outbox SET next_attempt_at = $2, last_error = $3 WHERE id = $1 AND status = 'pending'`,
+		id, at, lastError)
+	return err
+}
+
+// Dead прекращает доставку уведомления.
+func (r *OutboxRepo) Dead(id int64, lastError string) error {
+	_, err := r.DB.Exec(
+		`UPDATE outbox SET status = 'dead', last_error = $2 WHERE id = $1`, id, lastError)
+	return err
+}
+
+// SetBotBlocked отмечает, что пользователь заблокировал бота (или снова его
+// запустил). При блокировке все ожидающие уведомления пользователя
+// прекращаются; после разблокировки новые уведомления доставляются как обычно.
+func (r *OutboxRepo) SetBotBlocked(telegramID string, blocked bool) error {
+	return WithTx(r.DB, func(tx *sql.Tx) error {
+		var res sql.Result
+		var err error
+		if blocked {
+			res, err = tx.Exec(
+				`UPDATE users SET bot_blocked_at = COALESCE(bot_blocked_at, now()) WHERE telegram_id = $1`,
+				telegramID)
+		} else {
+			res, err = tx.Exec(`UPDATE users SET bot_blocked_at = NULL WHERE telegram_id = $1`, telegramID)
+		}
+		if err != nil {
+			return fmt.Errorf("update bot_blocked_at: %w", err)
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return ErrNotFound
+		}
+		if !blocked {
+			return nil
+		}
+		if _, err := tx.Exec(
+			`UPDATE outbox SET status = 'dead', last_error = $2
+             WHERE telegram_id = $1 AND status = 'pending'`,
+			telegramID, errBotBlocked,
+		); err != nil {
+			return fmt.Errorf("drop pending notifications: %w", err)
+		}
+		return nil
+	})
+}
+
+// List возвращает уведомления со статусом status, новые первыми.
+func (r *OutboxRepo) List(status string, limit int) ([]*domain.Notification, error) {
+	rows, err := r.DB.Query(
+		`SELECT`+notificationColumns+`
+         FROM outbox o
+         WHERE o.status = $1
+         ORDER BY o.id DESC
+         LIMIT $2`,
+		status, limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("list notifications: %w", err)
+	}
+	return scanNotifications(rows)
+}
+
+// Requeue возвращает недоставленное уведомление в очередь с новым счётчиком
+// попыток. Уведомления пользователю, заблокировавшему бота, не возвращаются.
+func (r *OutboxRepo) Requeue(id int64) (*domain.Notification, error) {
+	var n *domain.Notification
+	err := WithTx(r.DB, func(tx *sql.Tx) error {
+		var status string
+		var blocked bool
+		err := tx.QueryRow(
+			`SELECT o.status, EXISTS (
+                 SELECT 1 FROM users u WHERE u.telegram_id = o.telegram_id AND u.bot_blocked_at IS NOT NULL)
+             FROM outbox o WHERE o.id = $1 FOR UPDATE OF o`,
+			id,
+		).Scan(&status, &blocked)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("lock notification: %w", err)
+		}
+		if status != domain.NotificationDead {
+			return fmt.Errorf("notification is %s: %w", status, ErrConflict)
+		}
+		if blocked {
+			return fmt.Errorf("recipient blocked the bot: %w", ErrConflict)
+		}
+		n, err = scanNotification(tx.QueryRow(
+			`UPDATE outbox o
+             SET status = 'pending', attempts = 0, next_attempt_at = now(), last_error = ''
+             WHERE o.id = $1
+             RETURNING`+notificationColumns,
+			id,
+		))
+		return err
+	})
+	return n, err
+}
+
+// PurgeSent удаляет доставленные уведомления старше before.
+func (r *OutboxRepo) PurgeSent(before time.Time) (int64, error) {
+	res, err := r.DB.Exec(`DELETE FROM outbox WHERE status = 'sent' AND sent_at < $1`, before)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,110 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"poppins/domain"
+	"time"
+)
+
+type PromotionRepo struct {
+	DB *sql.DB
+}
+
+func NewPromotionRepo(db *sql.DB) *PromotionRepo {
+	return &PromotionRepo{DB: db}
+}
+
+const promotionColumns = `
+            p.id,
+            p.ad_id,
+            p.package,
+            p.starts_at,
+            p.ends_at,
+            p.created_by,
+            p.created_at,
+            p.cancelled_at,
+            (p.cancelled_at IS NULL AND p.starts_at <= now() AND p.ends_at > now()) AS active`
+
+func scanPromotion(row rowScanner) (*domain.Promotion, error) {
+	p := &domain.Promotion{}
+	var cancelledAt sql.NullTime
+	if err := row.Scan(
+		&p.ID, &p.AdID, &p.Package, &p.StartsAt, &p.EndsAt, &p.CreatedBy, &p.CreatedAt,
+		&cancelledAt, &p.Active,
+	); err != nil {
+		return nil, err
+	}
+	if cancelledAt.Valid {
+		p.CancelledAt = &cancelledAt.Time
+	}
+	return p, nil
+}
+
+// createPromotionTx подключает пакет pkg неархивному объявлению adID.
+func createPromotionTx(tx *sql.Tx, adID int64, pkg string, startsAt, endsAt time.Time, createdBy string) (*domain.Promotion, error) {
+	p, err := scanPromotion(tx.QueryRow(
+		`INSERT INTO ad_promotions AS p (ad_id, package, starts_at, ends_at, created_by)
+         SELECT a.id, $2, $3, $4, $5 FROM advertisements a
+         WHERE a.id = $1 AND a.archived = FALSE
+         RETURNING`+promotionColumns,
+		adID, pkg, startsAt, endsAt, createdBy,
+	))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("insert promotion: %w", err)
+	}
+	return p, nil
+}
+
+// Create подключает объявлению пакет продвижения на период [startsAt, endsAt).
+func (r *PromotionRepo) Create(adID int64, pkg string, startsAt, endsAt time.Time, createdBy string) (*domain.Promotion, error) {
+	var p *domain.Promotion
+	err := WithTx(r.DB, func(tx *sql.Tx) error {
+		var err error
+		p, err = createPromotionTx(tx, adID, pkg, startsAt, endsAt, createdBy)
+		return err
+	})
+	return p, err
+}
+
+// ListByAd возвращает продвижения объявления, новые первыми.
+func (r *PromotionRepo) ListByAd(adID int64) ([]*domain.Promotion, error) {
+	rows, err := r.DB.Query(
+		`SELECT`+promotionColumns+`
+         FROM ad_promotions p
+         WHERE p.ad_id = $1
+         ORDER BY p.starts_at DESC`,
+		adID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("query promotions: %w", err)
+	}
+	defer rows.Close()
+	var list []*domain.Promotion
+	for rows.Next() {
+		p, err := scanPromotion(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, p)
+	}
+	return list, rows.Err()
+}
+
+// Cancel досрочно отключает продвижение id объявления adID.
+func (r *PromotionRepo) Cancel(adID, id int64) (*domain.Promotion, error) {
+	p, err := scanPromotion(r.DB.QueryRow(
+		`UPDATE ad_promotions p SET cancelled_at = COALESCE(p.cancelled_at, now())
+         WHERE p.id = $1 AND p.ad_id = $2
+         RETURNING`+promotionColumns,
+		id, adID,
+	))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	return p, err
+}
@@ -0,0 +1,54 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+type RateLimitRepo struct {
+	DB *sql.DB
+}
+
+func NewRateLimitRepo(db *sql.DB) *RateLimitRepo {
+	return &RateLimitRepo{DB: db}
+}
+
+// Take атомарно пытается занять место в ведре key по алгоритму GCRA: запрос
+// проходит, если после сдвига TAT на interval он не уходит дальше now+window.
+// Отклонённый запрос состояние не меняет. Возвращает TAT после запроса
+// (или текущий, если запрос отклонён).
+func (r *RateLimitRepo) Take(key string, interval, window time.Duration, now time.Time) (tat time.Time, allowed bool, err error) {
+	err = r.DB.QueryRow(
+		`INSERT INTO rate_limit_buckets AS b (key, tat)
+         VALUES ($1, $2::timestamptz + $3 * interval '1 microsecond')
+         ON CONFLICT (key) DO UPDATE
+             SET tat = GREATEST(b.tat, $2::timestamptz) + $3 * interval '1 microsecond'
+             WHERE GREATEST(b.tat, $2::timestamptz) + $3 * interval '1 microsecond'
+                   <= $2::timestamptz + $4 * interval '1 microsecond'
+         RETURNING tat`,
+		key, now, interval.Microseconds(), window.Microseconds(),
+	).Scan(&tat)
+	if err == nil {
+		return tat, true, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return time.Time{}, false, fmt.Errorf("take rate limit token: %w", err)
+	}
+	if err := r.DB.QueryRow(
+		`SELECT tat FROM rate_limit_buckets WHERE key = $1`, key,
+	).Scan(&tat); err != nil {
+		return time.Time{}, false, fmt.Errorf("read rate limit bucket: %w", err)
+	}
+	return tat, false, nil
+}
+
+// PurgeExpired удаляет полностью восстановившиеся ведра.
+func (r *RateLimitRepo) PurgeExpired(now time.Time) (int64, error) {
+	res, err := r.DB.Exec(`DELETE FROM rate_limit_buckets WHERE tat < $1`, now)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,248 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"poppins/domain"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type ReportRepo struct {
+	DB *sql.DB
+}
+
+func NewReportRepo(db *sql.DB) *ReportRepo {
+	return &ReportRepo{DB: db}
+}
+
+const reportColumns = `
+            rp.id,
+            rp.ad_id,
+            ru.telegram_id,
+            rp.reason,
+            rp.text,
+            rp.status,
+            rp.outcome,
+            rp.comment,
+            rp.resolved_by,
+            rp.resolved_at,
+            rp.created_at`
+
+func scanReport(row rowScanner, withAd bool) (*domain.Report, error) {
+	rep := &domain.Report{}
+	var resolvedAt sql.NullTime
+	dest := []interface{}{
+		&rep.ID, &rep.AdID, &rep.ReporterTelegramID, &rep.Reason, &rep.Text, &rep.Status,
+		&rep.Outcome, &rep.Comment, &rep.ResolvedBy, &resolvedAt, &rep.CreatedAt,
+	}
+	ad := &domain.Advertisement{}
+	if withAd {
+		dest = append(dest, adDest(ad)...)
+	}
+	if err := row.Scan(dest...); err != nil {
+		return nil, err
+	}
+	if resolvedAt.Valid {
+		rep.ResolvedAt = &resolvedAt.Time
+	}
+	if withAd {
+		rep.Ad = ad
+	}
+	return rep, nil
+}
+
+// Create сохраняет жалобу. Один пользователь жалуется на объявление один раз:
+// повтор возвращает ErrConflict. Если открытых жалоб от разных пользователей
+// набралось hideThreshold, объявление в той же транзакции скрывается;
+// hidden сообщает, что это произошло сейчас.
+func (r *ReportRepo) Create(rep *domain.Report, hideThreshold int) (hidden bool, err error) {
+	err = WithTx(r.DB, func(tx *sql.Tx) error {
+		var reporterID int64
+		var sellerTelegramID string
+		if err := tx.QueryRow(
+			`SELECT ru.id, su.telegram_id
+             FROM users ru, advertisements a
+             JOIN users su ON su.id = a.user_id
+             WHERE ru.telegram_id = $1 AND a.id = $2
+             FOR UPDATE OF a`,
+			rep.ReporterTelegramID, rep.AdID,
+		).Scan(&reporterID, &sellerTelegramID); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrNotFound
+			}
+			return fmt.Errorf("lock reported ad: %w", err)
+		}
+		if sellerTelegramID == rep.ReporterTelegramID {
+			return ErrForbidden
+		}
+
+		err := tx.QueryRow(
+			`INSERT INTO ad_reports (ad_id, reporter_user_id, reason, text)
+             VALUES ($1, $2, $3, $4)
+             RETURNING id, status, created_at`,
+			rep.AdID, reporterID, rep.Reason, rep.Text,
+		).Scan(&rep.ID, &rep.Status, &rep.CreatedAt)
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			return fmt.Errorf("ad already reported by this user: %w", ErrConflict)
+		}
+		if err != nil {
+			return fmt.Errorf("insert report: %w", err)
+		}
+
+		if hideThreshold <= 0 {
+			return nil
+		}
+		res, err := tx.Exec(
+			`UPDATE advertisements SET status = 'hidden'
+             WHERE id = $1 AND status = 'active'
+               AND (SELECT COUNT(DISTINCT reporter_user_id) FROM ad_reports
+                    WHERE ad_id = $1 AND status = 'open') >= $2`,
+			rep.AdID, hideThreshold,
+		)
+		if err != nil {
+			return fmt.Errorf("hide reported ad: %w", err)
+		}
+		n, _ := res.RowsAffected()
+		hidden = n > 0
+		if !hidden {
+			return nil
+		}
+		return emitAdUpdatedTx(tx, rep.AdID)
+	})
+	return hidden, err
+}
+
+// List возвращает жалобы вместе с объявлениями; пустой status — открытые.
+func (r *ReportRepo) List(status string, limit int) ([]*domain.Report, error) {
+	if status == "" {
+		status = domain.ReportOpen
+	}
+	rows, err := r.DB.Query(
+		`SELECT`+reportColumns+`,`+adColumns+`
+         FROM ad_reports rp
+         JOIN users ru ON ru.id = rp.reporter_user_id
+         JOIN advertisements a ON a.id = rp.ad_id
+         JOIN users u ON u.id = a.user_id
+         WHERE rp.status = $1
+         ORDER BY rp.created_at
+         LIMIT $2`,
+		status, limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("list reports: %w", err)
+	}
+	defer rows.Close()
+
+	var reports []*domain.Report
+	for rows.Next() {
+		rep, err := scanReport(rows, true)
+		if err != nil {
+			return nil, fmt.Errorf("scan report: %w", err)
+		}
+		reports = append(reports, rep)
+	}
+	return reports, rows.Err()
+}
+
+// Resolve закрывает жалобу reportID и все остальные открытые жалобы на то же
+// объявление одним решением, применяет его к объявлению и ставит уведомления
+// участникам в очередь. Возвращает закрытые жалобы (Ad заполнен у каждой).
+func (r *ReportRepo) Resolve(reportID int64, moderator, outcome, comment string) ([]*domain.Report, error) {
+	var resolved []*domain.Report
+	err := WithTx(r.DB, func(tx *sql.Tx) error {
+		var adID int64
+		var status string
+		err := tx.QueryRow(
+			`SELECT ad_id, status FROM ad_reports WHERE id = $1`, reportID,
+		).Scan(&adID, &status)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("get report: %w", err)
+		}
+		if status != domain.ReportOpen {
+			return fmt.Errorf("report already %s: %w", status, ErrConflict)
+		}
+
+		// Объявление блокируем первым, чтобы не гоняться с новыми жалобами.
+		var adStatus string
+		if err := tx.QueryRow(
+			`SELECT status FROM advertisements WHERE id = $1 FOR UPDATE`, adID,
+		).Scan(&adStatus); err != nil {
+			return fmt.Errorf("lock ad: %w", err)
+		}
+		statusChanged := true
+		switch {
+		case outcome == domain.ReportAdRemoved:
+			_, err = tx.Exec(
+				`UPDATE advertisements SET status = 'rejected', moderation_reason = 'reported' WHERE id = $1`, adID)
+		case adStatus == domain.AdStatusHidden:
+			_, err = tx.Exec(`UPDATE advertisements SET status = 'active' WHERE id = $1`, adID)
+		default:
+			statusChanged = false
+		}
+		if err != nil {
+			return fmt.Errorf("apply report outcome: %w", err)
+		}
+
+		rows, err := tx.Query(
+			`WITH upd AS (
+                 UPDATE ad_reports
+                 SET status = 'resolved', outcome = $2, comment = $3, resolved_by = $4, resolved_at = $5
+                 WHERE ad_id = $1 AND status = 'open'
+                 RETURNING *
+             )
+             SELECT`+reportColumns+`,`+adColumns+`
+             FROM upd rp
+             JOIN users ru ON ru.id = rp.reporter_user_id
+             JOIN advertisements a ON a.id = rp.ad_id
+             JOIN users u ON u.id = a.user_id
+             ORDER BY rp.id`,
+			adID, outcome, comment, moderator, time.Now(),
+		)
+		if err != nil {
+			return fmt.Errorf("resolve reports: %w", err)
+		}
+		defer rows.Close()
+		for rows.Next() {
+			rep, err := scanReport(rows, true)
+			if err != nil {
+				return fmt.Errorf("scan report: %w", err)
+			}
+			resolved = append(resolved, rep)
+		}
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		if statusChanged {
+			if err := emitAdUpdatedTx(tx, adID); err != nil {
+				return err
+			}
+		}
+		return enqueueReportOutcomeTx(tx, resolved, outcome, comment)
+	})
+	return resolved, err
+}
+
+// enqueueReportOutcomeTx сообщает пожаловавшимся, что жалоба рассмотрена,
+// а продавцу — о снятии объявления или предупреждении.
+func enqueueReportOutcomeTx(tx *sql.Tx, resolved []*domain.Report, outcome, comment string) error {
+	if len(resolved) == 0 {
+		return nil
+	}
+	ad := resolved[0].Ad
+	for _, rep := range resolved {
+		if err := enqueueTx(tx, rep.ReporterTelegramID, domain.NotifyReport, domain.ReportResolvedText(ad.Title)); err != nil {
+			return err
+		}
+	}
+	if text := domain.ReportOutcomeText(ad.Title, outcome, comment); text != "" {
+		return enqueueTx(tx, ad.TelegramID, domain.NotifyReport, text)
+	}
+	return nil
+}
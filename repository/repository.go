@@ -0,0 +1,37 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrNotFound — запрошенная запись не существует.
+	ErrNotFound = errors.New("not found")
+	// ErrForbidden — запись существует, но принадлежит другому пользователю.
+	ErrForbidden = errors.New("forbidden")
+	// ErrConflict — запись в состоянии, не допускающем операцию.
+	ErrConflict = errors.New("conflict")
+)
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// WithTx выполняет fn в транзакции: коммитит при успехе, откатывает при ошибке.
+func WithTx(db *sql.DB, fn func(tx *sql.Tx) error) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,256 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"poppins/domain"
+
+	"github.com/lib/pq"
+)
+
+type ReviewRepo struct {
+	DB *sql.DB
+}
+
+func NewReviewRepo(db *sql.DB) *ReviewRepo {
+	return &ReviewRepo{DB: db}
+}
+
+const reviewColumns = `
+            rv.id,
+            rv.ad_id,
+            rv.ad_title,
+            su.telegram_id,
+            bu.name,
+            bu.telegram_id,
+            rv.rating,
+            rv.text,
+            rv.reply,
+            rv.replied_at,
+            rv.status,
+            rv.flagged_at,
+            rv.flag_reason,
+            rv.moderation_note,
+            rv.created_at`
+
+const reviewFrom = `
+         FROM reviews rv
+         JOIN users su ON su.id = rv.seller_user_id
+         JOIN users bu ON bu.id = rv.buyer_user_id`
+
+func scanReview(row rowScanner) (*domain.Review, error) {
+	rv := &domain.Review{}
+	var adID sql.NullInt64
+	var repliedAt, flaggedAt sql.NullTime
+	if err := row.Scan(
+		&rv.ID, &adID, &rv.AdTitle, &rv.SellerTelegramID, &rv.BuyerName, &rv.BuyerTelegramID,
+		&rv.Rating, &rv.Text, &rv.Reply, &repliedAt, &rv.Status, &flaggedAt, &rv.FlagReason,
+		&rv.ModerationNote, &rv.CreatedAt,
+	); err != nil {
+		return nil, err
+	}
+	if adID.Valid {
+		rv.AdID = &adID.Int64
+	}
+	if repliedAt.Valid {
+		rv.RepliedAt = &repliedAt.Time
+	}
+	if flaggedAt.Valid {
+		rv.FlaggedAt = &flaggedAt.Time
+	}
+	return rv, nil
+}
+
+// getReviewTx перечитывает отзыв, блокируя его.
+func getReviewTx(tx *sql.Tx, id int64) (*domain.Review, error) {
+	rv, err := scanReview(tx.QueryRow(
+		`SELECT`+reviewColumns+reviewFrom+` WHERE rv.id = $1 FOR UPDATE OF rv`, id,
+	))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	return rv, err
+}
+
+// Create сохраняет отзыв покупателя buyerTelegramID по объявлению adID со
+// статусом status. Оставить отзыв может только покупатель, который связывался
+// с продавцом по этому объявлению (писал ему или открывал контакт), и только
+// один раз. О published-отзыве продавец получает уведомление.
+func (r *ReviewRepo) Create(adID int64, buyerTelegramID string, rating int, text, status string) (*domain.Review, error) {
+	var rv *domain.Review
+	err := WithTx(r.DB, func(tx *sql.Tx) error {
+		var buyerID, sellerID int64
+		var title string
+		var contacted bool
+		err := tx.QueryRow(
+			`SELECT bu.id, a.user_id, a.title,
+                    EXISTS (SELECT 1 FROM contact_reveals cr
+                            WHERE cr.ad_id = a.id AND cr.viewer_user_id = bu.id)
+                 OR EXISTS (SELECT 1 FROM conversations c
+                            WHERE c.ad_id = a.id AND c.buyer_user_id = bu.id)
+             FROM users bu, advertisements a
+             WHERE bu.telegram_id = $1 AND a.id = $2`,
+			buyerTelegramID, adID,
+		).Scan(&buyerID, &sellerID, &title, &contacted)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("find ad for review: %w", err)
+		}
+		if buyerID == sellerID {
+			return fmt.Errorf("cannot review own ad: %w", ErrForbidden)
+		}
+		if !contacted {
+			return fmt.Errorf("only buyers who contacted the seller can leave a review: %w", ErrForbidden)
+		}
+
+		var id int64
+		err = tx.QueryRow(
+			`INSERT INTO reviews (ad_id, ad_title, seller_user_id, buyer_user_id, rating, text, status)
+             VALUES ($1, $2, $3, $4, $5, $6, $7)
+             RETURNING id`,
+			adID, title, sellerID, buyerID, rating, text, status,
+		).Scan(&id)
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			return fmt.Errorf("ad already reviewed by this user: %w", ErrConflict)
+		}
+		if err != nil {
+			return fmt.Errorf("insert review: %w", err)
+		}
+		if rv, err = getReviewTx(tx, id); err != nil {
+			return err
+		}
+		if rv.Status != domain.ReviewPublished {
+			return nil
+		}
+		return enqueueTx(tx, rv.SellerTelegramID, domain.NotifyReview, domain.NewReviewText(rv))
+	})
+	return rv, err
+}
+
+// ListBySeller возвращает опубликованные отзывы о продавце, новые первыми.
+func (r *ReviewRepo) ListBySeller(sellerTelegramID string, limit int) ([]*domain.Review, error) {
+	return r.list(
+		`SELECT`+reviewColumns+reviewFrom+`
+         WHERE su.telegram_id = $1 AND rv.status = 'published'
+         ORDER BY rv.created_at DESC
+         LIMIT $2`,
+		sellerTelegramID, limit,
+	)
+}
+
+// ListForModeration возвращает отзывы, ждущие модератора: отложенные
+// правилами и отмеченные пользователями, старые первыми.
+func (r *ReviewRepo) ListForModeration(limit int) ([]*domain.Review, error) {
+	return r.list(
+		`SELECT`+reviewColumns+reviewFrom+`
+         WHERE rv.status = 'pending'
+            OR (rv.status = 'published' AND rv.flagged_at IS NOT NULL)
+         ORDER BY COALESCE(rv.flagged_at, rv.created_at)
+         LIMIT $1`,
+		limit,
+	)
+}
+
+func (r *ReviewRepo) list(query string, args ...interface{}) ([]*domain.Review, error) {
+	rows, err := r.DB.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query reviews: %w", err)
+	}
+	defer rows.Close()
+	var list []*domain.Review
+	for rows.Next() {
+		rv, err := scanReview(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, rv)
+	}
+	return list, rows.Err()
+}
+
+// Reply сохраняет ответ продавца на опубликованный отзыв и сообщает о нём
+// покупателю; ответ можно изменить.
+func (r *ReviewRepo) Reply(id int64, sellerTelegramID, reply string) (*domain.Review, error) {
+	var rv *domain.Review
+	err := WithTx(r.DB, func(tx *sql.Tx) error {
+		cur, err := getReviewTx(tx, id)
+		if err != nil {
+			return err
+		}
+		if cur.SellerTelegramID != sellerTelegramID {
+			return ErrForbidden
+		}
+		if cur.Status != domain.ReviewPublished {
+			return fmt.Errorf("review is %s: %w", cur.Status, ErrConflict)
+		}
+		if _, err := tx.Exec(
+			`UPDATE reviews SET reply = $2, replied_at = now() WHERE id = $1`, id, reply,
+		); err != nil {
+			return fmt.Errorf("update review reply: %w", err)
+		}
+		if rv, err = getReviewTx(tx, id); err != nil {
+			return err
+		}
+		return enqueueTx(tx, rv.BuyerTelegramID, domain.NotifyReview, domain.ReviewReplyText(rv))
+	})
+	return rv, err
+}
+
+// Flag отмечает отзыв как оскорбительный или недостоверный для модератора.
+// Автор свой отзыв отметить не может; повторная отметка ничего не меняет.
+func (r *ReviewRepo) Flag(id int64, telegramID, reason string) (*domain.Review, error) {
+	var rv *domain.Review
+	err := WithTx(r.DB, func(tx *sql.Tx) error {
+		cur, err := getReviewTx(tx, id)
+		if err != nil {
+			return err
+		}
+		if cur.BuyerTelegramID == telegramID {
+			return ErrForbidden
+		}
+		if cur.Status != domain.ReviewPublished {
+			return ErrNotFound
+		}
+		if _, err := tx.Exec(
+			`UPDATE reviews SET flagged_at = now(), flag_reason = $2
+             WHERE id = $1 AND flagged_at IS NULL`,
+			id, reason,
+		); err != nil {
+			return fmt.Errorf("flag review: %w", err)
+		}
+		rv, err = getReviewTx(tx, id)
+		return err
+	})
+	return rv, err
+}
+
+// Moderate публикует или скрывает отзыв, снимая отметку пользователей.
+func (r *ReviewRepo) Moderate(id int64, moderator string, publish bool, note string) (*domain.Review, error) {
+	status := domain.ReviewHidden
+	if publish {
+		status = domain.ReviewPublished
+	}
+	var rv *domain.Review
+	err := WithTx(r.DB, func(tx *sql.Tx) error {
+		if _, err := getReviewTx(tx, id); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(
+			`UPDATE reviews
+             SET status = $2, flagged_at = NULL, moderation_note = $3,
+                 moderated_by = $4, moderated_at = now()
+             WHERE id = $1`,
+			id, status, note, moderator,
+		); err != nil {
+			return fmt.Errorf("moderate review: %w", err)
+		}
+		var err error
+		rv, err = getReviewTx(tx, id)
+		return err
+	})
+	return rv, err
+}
@@ -0,0 +1,157 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"poppins/domain"
+	"time"
+)
+
+type SanctionRepo struct {
+	DB *sql.DB
+}
+
+func NewSanctionRepo(db *sql.DB) *SanctionRepo {
+	return &SanctionRepo{DB: db}
+}
+
+// activeSanction — условие действующей санкции s.
+const activeSanction = `s.lifted_at IS NULL AND (s.expires_at IS NULL OR s.expires_at > now())`
+
+// visibleOwner — условие для запросов с джойном users u: объявления забаненных
+// и теневых забаненных пользователей не видны никому, кроме них самих.
+// Параметр — telegram_id смотрящего (может быть пустым).
+const visibleOwner = `(u.telegram_id = %s OR NOT EXISTS (
+              SELECT 1 FROM user_sanctions s
+              WHERE s.user_id = u.id AND ` + activeSanction + `))`
+
+const sanctionColumns = `
+            s.id,
+            u.telegram_id,
+            s.kind,
+            s.reason,
+            s.expires_at,
+            s.created_by,
+            s.created_at,
+            s.lifted_at,
+            s.lifted_by,
+            s.lift_reason,
+            (` + activeSanction + `) AS active`
+
+func scanSanction(row rowScanner) (*domain.Sanction, error) {
+	s := &domain.Sanction{}
+	var expiresAt, liftedAt sql.NullTime
+	if err := row.Scan(
+		&s.ID, &s.TelegramID, &s.Kind, &s.Reason, &expiresAt, &s.CreatedBy,
+		&s.CreatedAt, &liftedAt, &s.LiftedBy, &s.LiftReason, &s.Active,
+	); err != nil {
+		return nil, err
+	}
+	if expiresAt.Valid {
+		s.ExpiresAt = &expiresAt.Time
+	}
+	if liftedAt.Valid {
+		s.LiftedAt = &liftedAt.Time
+	}
+	return s, nil
+}
+
+// Create накладывает санкцию на пользователя telegramID. О бане пользователь
+// получает уведомление, о теневом бане — нет.
+func (r *SanctionRepo) Create(s *domain.Sanction) error {
+	return WithTx(r.DB, func(tx *sql.Tx) error {
+		err := tx.QueryRow(
+			`INSERT INTO user_sanctions (user_id, kind, reason, expires_at, created_by)
+             SELECT id, $2, $3, $4, $5 FROM users WHERE telegram_id = $1
+             RETURNING id, created_at`,
+			s.TelegramID, s.Kind, s.Reason, s.ExpiresAt, s.CreatedBy,
+		).Scan(&s.ID, &s.CreatedAt)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("create sanction: %w", err)
+		}
+		s.Active = s.ExpiresAt == nil || s.ExpiresAt.After(time.Now())
+		if s.Kind != domain.SanctionBan {
+			return nil
+		}
+		return enqueueTx(tx, s.TelegramID, domain.NotifySanction, domain.BanText(s))
+	})
+}
+
+// ListByTelegram возвращает всю историю санкций пользователя, новые первыми.
+func (r *SanctionRepo) ListByTelegram(telegramID string) ([]*domain.Sanction, error) {
+	rows, err := r.DB.Query(
+		`SELECT`+sanctionColumns+`
+         FROM user_sanctions s
+         JOIN users u ON u.id = s.user_id
+         WHERE u.telegram_id = $1
+         ORDER BY s.created_at DESC`,
+		telegramID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("list sanctions: %w", err)
+	}
+	defer rows.Close()
+
+	var list []*domain.Sanction
+	for rows.Next() {
+		s, err := scanSanction(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan sanction: %w", err)
+		}
+		list = append(list, s)
+	}
+	return list, rows.Err()
+}
+
+// ActiveBan возвращает действующий бан пользователя (не теневой) или nil.
+func (r *SanctionRepo) ActiveBan(telegramID string) (*domain.Sanction, error) {
+	s, err := scanSanction(r.DB.QueryRow(
+		`SELECT`+sanctionColumns+`
+         FROM user_sanctions s
+         JOIN users u ON u.id = s.user_id
+         WHERE u.telegram_id = $1 AND s.kind = 'ban' AND `+activeSanction+`
+         ORDER BY s.expires_at DESC NULLS FIRST
+         LIMIT 1`,
+		telegramID,
+	))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return s, err
+}
+
+// Lift снимает санкцию id пользователя telegramID и сообщает о снятии бана.
+func (r *SanctionRepo) Lift(telegramID string, id int64, liftedBy, reason string) (*domain.Sanction, error) {
+	var s *domain.Sanction
+	err := WithTx(r.DB, func(tx *sql.Tx) error {
+		var err error
+		s, err = scanSanction(tx.QueryRow(
+			`WITH upd AS (
+                 UPDATE user_sanctions s
+                 SET lifted_at = now(), lifted_by = $3, lift_reason = $4
+                 FROM users u
+                 WHERE s.id = $2 AND s.user_id = u.id AND u.telegram_id = $1 AND s.lifted_at IS NULL
+                 RETURNING s.*
+             )
+             SELECT`+sanctionColumns+`
+             FROM upd s
+             JOIN users u ON u.id = s.user_id`,
+			telegramID, id, liftedBy, reason,
+		))
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNotFound
+		}
+		if err != nil || s.Kind != domain.SanctionBan {
+			return err
+		}
+		return enqueueTx(tx, s.TelegramID, domain.NotifySanction, domain.UnbanText)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
@@ -0,0 +1,143 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"poppins/domain"
+	"time"
+)
+
+// Счётчики ad_daily_stats.
+const (
+	statViews          = "views"
+	statFavorites      = "favorites"
+	statContactReveals = "contact_reveals"
+)
+
+// statsDay — текущий день статистики. Дни считаются по UTC, как и период
+// в StatsHandler.Seller, независимо от часового пояса сессии БД.
+const statsDay = `(now() AT TIME ZONE 'UTC')::date`
+
+// bumpDailyStatTx увеличивает дневной счётчик column объявления adID.
+func bumpDailyStatTx(tx *sql.Tx, adID int64, column string) error {
+	_, err := tx.Exec(
+		`INSERT INTO ad_daily_stats AS s (ad_id, day, `+column+`)
+         VALUES ($1, `+statsDay+`, 1)
+         ON CONFLICT (ad_id, day) DO UPDATE SET `+column+` = s.`+column+` + 1`,
+		adID,
+	)
+	if err != nil {
+		return fmt.Errorf("bump %s: %w", column, err)
+	}
+	return nil
+}
+
+type StatsRepo struct {
+	DB *sql.DB
+}
+
+func NewStatsRepo(db *sql.DB) *StatsRepo {
+	return &StatsRepo{DB: db}
+}
+
+// RecordView учитывает просмотр объявления пользователем viewer; повторные
+// просмотры того же пользователя в тот же день не считаются.
+func (r *StatsRepo) RecordView(adID int64, viewer string) error {
+	return WithTx(r.DB, func(tx *sql.Tx) error {
+		res, err := tx.Exec(
+			`INSERT INTO ad_views (ad_id, viewer, day) VALUES ($1, $2, `+statsDay+`)
+             ON CONFLICT DO NOTHING`,
+			adID, viewer,
+		)
+		if err != nil {
+			return fmt.Errorf("insert ad view: %w", err)
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return nil
+		}
+		return bumpDailyStatTx(tx, adID, statViews)
+	})
+}
+
+// Seller собирает показатели объявлений продавца telegramID за дни
+// с from по to включительно. Объявления без активности тоже попадают
+// в ответ с нулями.
+func (r *StatsRepo) Seller(telegramID string, from, to time.Time) (*domain.SellerStats, error) {
+	const day = "2006-01-02"
+	st := &domain.SellerStats{From: from.Format(day), To: to.Format(day)}
+
+	rows, err := r.DB.Query(
+		`SELECT a.id, a.title, a.status, a.archived,
+                (SELECT COUNT(*) FROM favorites f WHERE f.ad_id = a.id),
+                s.day, s.views, s.favorites, s.contact_reveals
+         FROM advertisements a
+         JOIN users u ON u.id = a.user_id
+         LEFT JOIN ad_daily_stats s ON s.ad_id = a.id AND s.day BETWEEN $2 AND $3
+         WHERE u.telegram_id = $1
+         ORDER BY a.created_at DESC, a.id, s.day`,
+		telegramID, st.From, st.To,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("query seller stats: %w", err)
+	}
+	defer rows.Close()
+
+	totals := map[string]*domain.DailyStat{}
+	var cur *domain.AdStats
+	for rows.Next() {
+		var a domain.AdStats
+		var d sql.NullTime
+		var views, favorites, reveals sql.NullInt64
+		if err := rows.Scan(
+			&a.AdID, &a.Title, &a.Status, &a.Archived, &a.InFavorites,
+			&d, &views, &favorites, &reveals,
+		); err != nil {
+			return nil, err
+		}
+		if cur == nil || cur.AdID != a.AdID {
+			cur = &a
+			cur.Daily = []domain.DailyStat{}
+			st.Ads = append(st.Ads, cur)
+		}
+		if !d.Valid {
+			continue
+		}
+		ds := domain.DailyStat{
+			Day: d.Time.Format(day), Views: views.Int64,
+			Favorites: favorites.Int64, ContactReveals: reveals.Int64,
+		}
+		cur.Daily = append(cur.Daily, ds)
+		cur.Views += ds.Views
+		cur.Favorites += ds.Favorites
+		cur.ContactReveals += ds.ContactReveals
+
+		t, ok := totals[ds.Day]
+		if !ok {
+			t = &domain.DailyStat{Day: ds.Day}
+			totals[ds.Day] = t
+		}
+		t.Views += ds.Views
+		t.Favorites += ds.Favorites
+		t.ContactReveals += ds.ContactReveals
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Сводный ряд по всем дням периода, включая дни без активности
+	st.Daily = []domain.DailyStat{}
+	for d := from; !d.After(to); d = d.AddDate(0, 0, 1) {
+		ds := domain.DailyStat{Day: d.Format(day)}
+		if t, ok := totals[ds.Day]; ok {
+			ds = *t
+		}
+		st.Daily = append(st.Daily, ds)
+		st.Total.Views += ds.Views
+		st.Total.Favorites += ds.Favorites
+		st.Total.ContactReveals += ds.ContactReveals
+	}
+	if st.Ads == nil {
+		st.Ads = []*domain.AdStats{}
+	}
+	return st, nil
+}
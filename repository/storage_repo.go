@@ -0,0 +1,132 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"poppins/domain"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// enqueueStorageDeletionTx ставит объект хранилища на удаление в транзакции,
+// удаляющей ссылку на него: после коммита объект не останется сиротой,
+// а при откате — не пропадёт.
+func enqueueStorageDeletionTx(tx *sql.Tx, kind, objectName string) error {
+	_, err := tx.Exec(`INSERT INTO storage_deletions (kind, object_name) VALUES ($1, $2)`, kind, objectName)
+	if err != nil {
+		return fmt.Errorf("enqueue storage deletion: %w", err)
+	}
+	return nil
+}
+
+// StorageDeletion — объект хранилища, ожидающий удаления.
+type StorageDeletion struct {
+	ID         int64
+	Kind       string
+	ObjectName string
+	Attempts   int
+}
+
+type StorageRepo struct {
+	DB *sql.DB
+}
+
+func NewStorageRepo(db *sql.DB) *StorageRepo {
+	return &StorageRepo{DB: db}
+}
+
+// Claim выбирает до limit объектов, готовых к удалению, засчитывает попытку
+// и откладывает их на lease, чтобы их не взял другой обработчик.
+func (r *StorageRepo) Claim(limit int, lease time.Duration) ([]*StorageDeletion, error) {
+	rows, err := r.DB.Query(
+		`WITH next AS (
+             SELECT id FROM storage_deletions
+             WHERE status = 'pending' AND next_attempt_at <= now()
+             ORDER BY id
+             LIMIT $1
+             FOR UPDATE SKIP LOCKED
+         )
+         UPDATE storage_deletions d
+         SET attempts = d.attempts + 1, next_attempt_at = now() + $2 * interval '1 millisecond'
+         FROM next
+         WHERE d.id = next.id
+         RETURNING d.id, d.kind, d.object_name, d.attempts`,
+		limit, lease.Milliseconds(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("claim storage deletions: %w", err)
+	}
+	defer rows.Close()
+
+	var list []*StorageDeletion
+	for rows.Next() {
+		d := &StorageDeletion{}
+		if err := rows.Scan(&d.ID, &d.Kind, &d.ObjectName, &d.Attempts); err != nil {
+			return nil, fmt.Errorf("scan storage deletion: %w", err)
+		}
+		list = append(list, d)
+	}
+	return list, rows.Err()
+}
+
+// Done убирает удалённый объект из очереди.
+func (r *StorageRepo) Done(id int64) error {
+	_, err := r.DB.Exec(`DELETE FROM storage_deletions WHERE id = $1`, id)
+	return err
+}
+
+// Retry откладывает следующую попытку удаления до at.
+func (r *StorageRepo) Retry(id int64, at time.Time, lastError string) error {
+	_, err := r.DB.Exec(
+		`UPDATE storage_deletions SET next_attempt_at = $2, last_error = $3 WHERE id = $1`, id, at, lastError)
+	return err
+}
+
+// Fail прекращает попытки удалить объект; он остаётся в таблице для разбора.
+func (r *StorageRepo) Fail(id int64, lastError string) error {
+	_, err := r.DB.Exec(
+		`UPDATE storage_deletions SET status = 'failed', last_error = $2 WHERE id = $1`, id, lastError)
+	return err
+}
+
+// OrphanPhotos возвращает фото из objects, на которые не ссылается ни одно
+// объявление и которые ещё не стоят в очереди на удаление.
+func (r *StorageRepo) OrphanPhotos(objects []string) ([]string, error) {
+	rows, err := r.DB.Query(
+		`SELECT o.name
+         FROM unnest($1::text[]) AS o(name)
+         LEFT JOIN advertisements a ON a.photos_urls = '/ads/' || o.name
+         WHERE a.id IS NULL
+           AND NOT EXISTS (
+               SELECT 1 FROM storage_deletions d
+               WHERE d.kind = $2 AND d.object_name = o.name AND d.status = 'pending'
+           )`,
+		pq.Array(objects), domain.StoragePhoto,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("find orphan photos: %w", err)
+	}
+	defer rows.Close()
+	var orphans []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		orphans = append(orphans, name)
+	}
+	return orphans, rows.Err()
+}
+
+// Enqueue ставит объекты вида kind на удаление.
+func (r *StorageRepo) Enqueue(kind string, objects []string) error {
+	return WithTx(r.DB, func(tx *sql.Tx) error {
+		for _, obj := range objects {
+			if err := enqueueStorageDeletionTx(tx, kind, obj); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -1,23 +1,47 @@
 package repository
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
 	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"poppins/domain"
+	"strings"
+
+	"github.com/lib/pq"
 )
 
 type UserRepo struct {
 	DB *sql.DB
+	// SubjectSecret — ключ HMAC для хеша telegram_id удалённых аккаунтов.
+	SubjectSecret []byte
+}
+
+func NewUserRepo(db *sql.DB, subjectSecret string) *UserRepo {
+	return &UserRepo{DB: db, SubjectSecret: []byte(subjectSecret)}
 }
 
-func NewUserRepo(db *sql.DB) *UserRepo {
-	return &UserRepo{DB: db}
+// subjectHash — HMAC-SHA256 от telegram_id: по журналу удалений можно
+// проверить, удалялся ли известный аккаунт, но нельзя перебрать ID без ключа.
+func (r *UserRepo) subjectHash(telegramId string) string {
+	mac := hmac.New(sha256.New, r.SubjectSecret)
+	mac.Write([]byte(telegramId))
+	return hex.EncodeToString(mac.Sum(nil))
 }
 
 func (r *UserRepo) Create(u *domain.User) error {
-	return r.DB.QueryRow(
-		`INSERT INTO users(telegram_id, name, phone, preferred_contact) VALUES($1,$2,$3, $4) RETURNING id,created_at`,
-		u.TelegramID, u.Name, u.Phone, u.PreferredContact,
-	).Scan(&u.ID, &u.CreatedAt)
+	return WithTx(r.DB, func(tx *sql.Tx) error {
+		if err := tx.QueryRow(
+			`INSERT INTO users(telegram_id, name, phone, preferred_contact) VALUES($1,$2,$3, $4) RETURNING id,created_at`,
+			u.TelegramID, u.Name, u.Phone, u.PreferredContact,
+		).Scan(&u.ID, &u.CreatedAt); err != nil {
+			return err
+		}
+		return emitEventTx(tx, domain.EventUserCreated, domain.NewUserEventData(u))
+	})
 }
 
 func (r *UserRepo) GetByID(telegramId string) (*domain.User, error) {
@@ -35,8 +59,15 @@ func (r *UserRepo) GetByID(telegramId string) (*domain.User, error) {
               FROM advertisements a
               WHERE a.user_id = u.id
                 AND a.archived = FALSE
-            ) AS ads_count
+            ) AS ads_count,
+            COALESCE(rv.rating, 0),
+            COALESCE(rv.cnt, 0)
         FROM users u
+        LEFT JOIN LATERAL (
+            SELECT ROUND(AVG(rating)::numeric, 2)::float8 AS rating, COUNT(*) AS cnt
+            FROM reviews
+            WHERE seller_user_id = u.id AND status = 'published'
+        ) rv ON TRUE
         WHERE u.telegram_id = $1
     `, telegramId).Scan(
 		&u.ID,
@@ -46,6 +77,8 @@ func (r *UserRepo) GetByID(telegramId string) (*domain.User, error) {
 		&u.PreferredContact,
 		&u.CreatedAt,
 		&u.AdsCount, // сюда сканим
+		&u.Rating,
+		&u.ReviewsCount,
 	)
 	if err != nil {
 		return nil, err
@@ -53,47 +86,235 @@ func (r *UserRepo) GetByID(telegramId string) (*domain.User, error) {
 	return u, nil
 }
 
-func (r *UserRepo) Delete(telegramId string) (*domain.User, error) {
-	u, err := r.GetByID(telegramId)
+// Delete удаляет аккаунт со всеми данными в одной транзакции: объявления
+// (подписчики получают ad.deleted), переписки, избранное, отзывы, заказы,
+// выгрузки и очередь уведомлений. Фото объявлений и архивы выгрузок ставятся
+// на удаление из хранилища фоновым обработчиком. Из журнала событий
+// вычищаются имя пользователя и содержимое его объявлений, ведра
+// rateLimitKeys удаляются. Возвращает квитанцию об удалении.
+func (r *UserRepo) Delete(telegramId string, rateLimitKeys []string) (*domain.DeletionReceipt, error) {
+	if len(r.SubjectSecret) == 0 {
+		return nil, errors.New("user deletion requires a subject secret")
+	}
+	rc := &domain.DeletionReceipt{}
+	err := WithTx(r.DB, func(tx *sql.Tx) error {
+		u := &domain.User{}
+		err := tx.QueryRow(
+			`SELECT id, telegram_id, COALESCE(name, ''), COALESCE(preferred_contact, ''), created_at
+             FROM users WHERE telegram_id = $1 FOR UPDATE`,
+			telegramId,
+		).Scan(&u.ID, &u.TelegramID, &u.Name, &u.PreferredContact, &u.CreatedAt)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("lock user: %w", err)
+		}
+
+		if err := tx.QueryRow(
+			`SELECT
+                 (SELECT COUNT(*) FROM favorites WHERE user_id = $1),
+                 (SELECT COUNT(*) FROM conversations c
+                  WHERE $1 IN (c.buyer_user_id, c.seller_user_id)
+                     OR c.ad_id IN (SELECT id FROM advertisements WHERE user_id = $1)),
+                 (SELECT COUNT(*) FROM messages WHERE sender_user_id = $1),
+                 (SELECT COUNT(*) FROM reviews WHERE $1 IN (seller_user_id, buyer_user_id)),
+                 (SELECT COUNT(*) FROM orders WHERE user_id = $1),
+                 (SELECT COUNT(*) FROM outbox WHERE telegram_id = $2)`,
+			u.ID, telegramId,
+		).Scan(&rc.Favorites, &rc.Conversations, &rc.Messages, &rc.Reviews, &rc.Orders, &rc.Notifications); err != nil {
+			return fmt.Errorf("count user data: %w", err)
+		}
+
+		if err := deleteUserAdsTx(tx, u, rc); err != nil {
+			return err
+		}
+		if err := deleteUserExportsTx(tx, u, rc); err != nil {
+			return err
+		}
+
+		if _, err := tx.Exec(`DELETE FROM outbox WHERE telegram_id = $1`, telegramId); err != nil {
+			return fmt.Errorf("purge user data: %w", err)
+		}
+		if _, err := tx.Exec(`DELETE FROM rate_limit_buckets WHERE key = ANY($1)`, pq.Array(rateLimitKeys)); err != nil {
+			return fmt.Errorf("purge rate limits: %w", err)
+		}
+		// История санкций остаётся для аудита, но уже без ссылки на пользователя
+		subjectHash := r.subjectHash(telegramId)
+		if _, err := tx.Exec(
+			`UPDATE user_sanctions SET subject_hash = $2 WHERE user_id = $1`, u.ID, subjectHash,
+		); err != nil {
+			return fmt.Errorf("detach sanctions: %w", err)
+		}
+		if _, err := tx.Exec(`DELETE FROM users WHERE id = $1`, u.ID); err != nil {
+			return fmt.Errorf("delete user: %w", err)
+		}
+		if err := emitEventTx(tx, domain.EventUserDeleted, domain.NewUserEventData(u)); err != nil {
+			return err
+		}
+
+		// Подписчикам остаются только идентификаторы: telegram_id нужен им,
+		// чтобы удалить свои копии данных
+		if _, err := tx.Exec(
+			`UPDATE events
+             SET payload = payload || CASE WHEN type LIKE 'user.%'
+                     THEN '{"name": "", "preferred_contact": ""}'::jsonb
+                     ELSE '{"title": "", "description": "", "address": "", "photos_urls": ""}'::jsonb
+                 END
+             WHERE payload->>'telegram_id' = $1`,
+			telegramId,
+		); err != nil {
+			return fmt.Errorf("scrub events: %w", err)
+		}
+
+		receipt, err := json.Marshal(rc)
+		if err != nil {
+			return err
+		}
+		return tx.QueryRow(
+			`INSERT INTO account_deletions (subject_hash, receipt) VALUES ($1, $2) RETURNING id, created_at`,
+			subjectHash, receipt,
+		).Scan(&rc.ID, &rc.DeletedAt)
+	})
 	if err != nil {
 		return nil, err
 	}
-	_, err = r.DB.Exec(`DELETE FROM users WHERE telegram_id=$1`, telegramId)
-	return u, err
+	return rc, nil
 }
 
-// UpdateName обновляет только поле name
-func (r *UserRepo) UpdateName(telegramId string, newName string) (*domain.User, error) {
-	_, err := r.DB.Exec(
-		`UPDATE users SET name = $1 WHERE telegram_id = $2`,
-		newName, telegramId,
+// deleteUserAdsTx удаляет все объявления пользователя и ставит на удаление
+// загруженные им фото. Фото, загруженные другим пользователем (объявление
+// ссылается на чужой объект), не трогаются: Create кладёт объекты под именем
+// ads/<telegram_id>_<nanos><ext>.
+func deleteUserAdsTx(tx *sql.Tx, u *domain.User, rc *domain.DeletionReceipt) error {
+	rows, err := tx.Query(
+		`SELECT`+adColumns+`
+         FROM advertisements a
+         JOIN users u ON a.user_id = u.id
+         WHERE a.user_id = $1
+         ORDER BY a.id`,
+		u.ID,
 	)
 	if err != nil {
-		return nil, err
+		return fmt.Errorf("query user ads: %w", err)
 	}
-	return r.GetByID(telegramId)
+	var ads []*domain.Advertisement
+	for rows.Next() {
+		ad, err := scanAd(rows)
+		if err != nil {
+			rows.Close()
+			return fmt.Errorf("scan ad row: %w", err)
+		}
+		ads = append(ads, ad)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	photos := map[string]bool{}
+	for _, ad := range ads {
+		obj := strings.TrimPrefix(ad.PhotosUrls, "/ads/")
+		if strings.HasPrefix(obj, "ads/"+u.TelegramID+"_") && !photos[obj] {
+			photos[obj] = true
+			if err := enqueueStorageDeletionTx(tx, domain.StoragePhoto, obj); err != nil {
+				return err
+			}
+		}
+		if err := emitAdEventTx(tx, domain.EventAdDeleted, ad); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.Exec(`DELETE FROM advertisements WHERE user_id = $1`, u.ID); err != nil {
+		return fmt.Errorf("delete user ads: %w", err)
+	}
+	rc.Ads, rc.Photos = int64(len(ads)), int64(len(photos))
+	return nil
 }
 
-// UpdatePhone обновляет только поле phone
-func (r *UserRepo) UpdatePhone(telegramId string, newPhone string) (*domain.User, error) {
-	_, err := r.DB.Exec(
-		`UPDATE users SET phone = $1 WHERE telegram_id = $2`,
-		newPhone, telegramId,
-	)
+// deleteUserExportsTx ставит на удаление архивы выгрузок и файлы импортов
+// пользователя; сами записи удалятся вместе с ним.
+func deleteUserExportsTx(tx *sql.Tx, u *domain.User, rc *domain.DeletionReceipt) error {
+	exports, err := queryObjectsTx(tx,
+		`SELECT object_name FROM data_exports WHERE user_id = $1 AND status = 'ready' AND object_name <> ''`, u.ID)
+	if err != nil {
+		return fmt.Errorf("query user exports: %w", err)
+	}
+	for _, obj := range exports {
+		if err := enqueueStorageDeletionTx(tx, domain.StorageExport, obj); err != nil {
+			return err
+		}
+	}
+	rc.Exports = int64(len(exports))
+
+	// Файлы незавершённых импортов: завершённые уже поставлены на удаление
+	sources, err := queryObjectsTx(tx,
+		`SELECT source_object FROM ad_imports WHERE user_id = $1 AND status IN ('pending', 'running')`, u.ID)
+	if err != nil {
+		return fmt.Errorf("query user imports: %w", err)
+	}
+	for _, obj := range sources {
+		if err := enqueueStorageDeletionTx(tx, domain.StorageImport, obj); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// queryObjectsTx возвращает имена объектов хранилища, выбранные запросом.
+func queryObjectsTx(tx *sql.Tx, query string, args ...interface{}) ([]string, error) {
+	rows, err := tx.Query(query, args...)
 	if err != nil {
 		return nil, err
 	}
-	return r.GetByID(telegramId)
+	defer rows.Close()
+	var objects []string
+	for rows.Next() {
+		var obj string
+		if err := rows.Scan(&obj); err != nil {
+			return nil, err
+		}
+		objects = append(objects, obj)
+	}
+	return objects, rows.Err()
 }
 
-// UpdatePreferredContact обновляет только поле preferred_contact
-func (r *UserRepo) UpdatePreferredContact(telegramId string, newContact string) (*domain.User, error) {
-	_, err := r.DB.Exec(
-		`UPDATE users SET preferred_contact = $1 WHERE telegram_id = $2`,
-		newContact, telegramId,
-	)
+// updateField меняет одно поле пользователя и публикует user.updated.
+// column подставляется в запрос как есть и должен быть константой.
+func (r *UserRepo) updateField(telegramId, column, value string) (*domain.User, error) {
+	err := WithTx(r.DB, func(tx *sql.Tx) error {
+		u := &domain.User{}
+		err := tx.QueryRow(
+			`UPDATE users SET `+column+` = $1 WHERE telegram_id = $2
+             RETURNING telegram_id, COALESCE(name, ''), COALESCE(preferred_contact, ''), created_at`,
+			value, telegramId,
+		).Scan(&u.TelegramID, &u.Name, &u.PreferredContact, &u.CreatedAt)
+		if errors.Is(err, sql.ErrNoRows) {
+			// Отсутствие пользователя сообщит GetByID
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		return emitEventTx(tx, domain.EventUserUpdated, domain.NewUserEventData(u))
+	})
 	if err != nil {
 		return nil, err
 	}
 	return r.GetByID(telegramId)
 }
+
+// UpdateName обновляет только поле name
+func (r *UserRepo) UpdateName(telegramId string, newName string) (*domain.User, error) {
+	return r.updateField(telegramId, "name", newName)
+}
+
+// UpdatePhone обновляет только поле phone
+func (r *UserRepo) UpdatePhone(telegramId string, newPhone string) (*domain.User, error) {
+	return r.updateField(telegramId, "phone", newPhone)
+}
+
+// UpdatePreferredContact обновляет только поле preferred_contact
+func (r *UserRepo) UpdatePreferredContact(telegramId string, newContact string) (*domain.User, error) {
+	return r.updateField(telegramId, "preferred_contact", newContact)
+}
@@ -0,0 +1,311 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"poppins/domain"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type WebhookRepo struct {
+	DB *sql.DB
+}
+
+func NewWebhookRepo(db *sql.DB) *WebhookRepo {
+	return &WebhookRepo{DB: db}
+}
+
+const webhookSubscriptionColumns = `
+            s.id,
+            s.url,
+            s.events,
+            s.description,
+            s.active,
+            s.secret,
+            s.created_by,
+            s.created_at,
+            s.updated_at`
+
+func scanWebhookSubscription(row rowScanner) (*domain.WebhookSubscription, error) {
+	s := &domain.WebhookSubscription{}
+	if err := row.Scan(
+		&s.ID, &s.URL, pq.Array(&s.Events), &s.Description, &s.Active, &s.Secret,
+		&s.CreatedBy, &s.CreatedAt, &s.UpdatedAt,
+	); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+const webhookDeliveryColumns = `
+            d.id,
+            d.subscription_id,
+            d.event_id,
+            e.type,
+            d.status,
+            d.attempts,
+            d.next_attempt_at,
+            d.last_status_code,
+            d.last_error,
+            d.created_at,
+            d.delivered_at`
+
+func scanWebhookDelivery(row rowScanner) (*domain.WebhookDelivery, error) {
+	d := &domain.WebhookDelivery{}
+	var deliveredAt sql.NullTime
+	if err := row.Scan(
+		&d.ID, &d.SubscriptionID, &d.EventID, &d.EventType, &d.Status, &d.Attempts,
+		&d.NextAttemptAt, &d.LastStatusCode, &d.LastError, &d.CreatedAt, &deliveredAt,
+	); err != nil {
+		return nil, err
+	}
+	if deliveredAt.Valid {
+		d.DeliveredAt = &deliveredAt.Time
+	}
+	return d, nil
+}
+
+// CreateSubscription сохраняет подписку; секрет задаёт вызывающий.
+func (r *WebhookRepo) CreateSubscription(s *domain.WebhookSubscription) error {
+	return r.DB.QueryRow(
+		`INSERT INTO webhook_subscriptions (url, events, description, active, secret, created_by)
+         VALUES ($1, $2, $3, $4, $5, $6)
+         RETURNING id, created_at, updated_at`,
+		s.URL, pq.Array(s.Events), s.Description, s.Active, s.Secret, s.CreatedBy,
+	).Scan(&s.ID, &s.CreatedAt, &s.UpdatedAt)
+}
+
+// ListSubscriptions возвращает все подписки.
+func (r *WebhookRepo) ListSubscriptions() ([]*domain.WebhookSubscription, error) {
+	rows, err := r.DB.Query(`SELECT` + webhookSubscriptionColumns + ` FROM webhook_subscriptions s ORDER BY s.id`)
+	if err != nil {
+		return nil, fmt.Errorf("list webhook subscriptions: %w", err)
+	}
+	defer rows.Close()
+
+	var list []*domain.WebhookSubscription
+	for rows.Next() {
+		s, err := scanWebhookSubscription(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan webhook subscription: %w", err)
+		}
+		list = append(list, s)
+	}
+	return list, rows.Err()
+}
+
+// GetSubscription возвращает подписку id.
+func (r *WebhookRepo) GetSubscription(id int64) (*domain.WebhookSubscription, error) {
+	s, err := scanWebhookSubscription(r.DB.QueryRow(
+		`SELECT`+webhookSubscriptionColumns+` FROM webhook_subscriptions s WHERE s.id = $1`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	return s, err
+}
+
+// UpdateSubscription сохраняет адрес, типы событий, описание, активность и
+// секрет подписки. Отключённая подписка не получает новых событий, а
+// ожидающие доставки ждут её включения.
+func (r *WebhookRepo) UpdateSubscription(s *domain.WebhookSubscription) error {
+	err := r.DB.QueryRow(
+		`UPDATE webhook_subscriptions
+         SET url = $2, events = $3, description = $4, active = $5, secret = $6, updated_at = now()
+         WHERE id = $1
+         RETURNING updated_at`,
+		s.ID, s.URL, pq.Array(s.Events), s.Description, s.Active, s.Secret,
+	).Scan(&s.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	return err
+}
+
+// DeleteSubscription удаляет подписку вместе с журналом доставок.
+func (r *WebhookRepo) DeleteSubscription(id int64) error {
+	res, err := r.DB.Exec(`DELETE FROM webhook_subscriptions WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// DeliveryJob — доставка, взятая в работу, со всем нужным для отправки.
+type DeliveryJob struct {
+	Delivery *domain.WebhookDelivery
+	URL      string
+	Secret   string
+	Event    *domain.Event
+}
+
+// ClaimDeliveries выбирает до limit доставок, готовых к отправке, засчитывает
+// попытку и откладывает их на lease, чтобы их не взял другой обработчик.
+// Доставки отключённых подписок пропускаются.
+func (r *WebhookRepo) ClaimDeliveries(limit int, lease time.Duration) ([]*DeliveryJob, error) {
+	rows, err := r.DB.Query(
+		`WITH next AS (
+             SELECT d.id FROM webhook_deliveries d
+             JOIN webhook_subscriptions s ON s.id = d.subscription_id
+             WHERE d.status = 'pending' AND d.next_attempt_at <= now() AND s.active
+             ORDER BY d.next_attempt_at
+             LIMIT $1
+             FOR UPDATE OF d SKIP LOCKED
+         ), upd AS (
+             UPDATE webhook_deliveries d
+             SET attempts = d.attempts + 1, next_attempt_at = now() + $2 * interval '1 millisecond'
+             FROM next
+             WHERE d.id = next.id
+             RETURNING d.*
+         )
+         SELECT`+webhookDeliveryColumns+`, s.url, s.secret, e.version, e.created_at, e.payload
+         FROM upd d
+         JOIN webhook_subscriptions s ON s.id = d.subscription_id
+         JOIN events e ON e.id = d.event_id
+         ORDER BY d.id`,
+		limit, lease.Milliseconds(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("claim webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	var jobs []*DeliveryJob
+	for rows.Next() {
+		d := &domain.WebhookDelivery{}
+		j := &DeliveryJob{Delivery: d, Event: &domain.Event{}}
+		var deliveredAt sql.NullTime
+		if err := rows.Scan(
+			&d.ID, &d.SubscriptionID, &d.EventID, &d.EventType, &d.Status, &d.Attempts,
+			&d.NextAttemptAt, &d.LastStatusCode, &d.LastError, &d.CreatedAt, &deliveredAt,
+			&j.URL, &j.Secret, &j.Event.Version, &j.Event.CreatedAt, &j.Event.Data,
+		); err != nil {
+			return nil, fmt.Errorf("scan webhook delivery: %w", err)
+		}
+		j.Event.ID, j.Event.Type = d.EventID, d.EventType
+		jobs = append(jobs, j)
+	}
+	return jobs, rows.Err()
+}
+
+// RecordAttempt пишет попытку в журнал и переводит доставку в status; для
+// pending следующая попытка будет не раньше next.
+func (r *WebhookRepo) RecordAttempt(deliveryID int64, a *domain.WebhookAttempt, status string, next time.Time) error {
+	return WithTx(r.DB, func(tx *sql.Tx) error {
+		if _, err := tx.Exec(
+			`INSERT INTO webhook_attempts (delivery_id, started_at, duration_ms, status_code, error, response_body)
+             VALUES ($1, $2, $3, $4, $5, $6)`,
+			deliveryID, a.StartedAt, a.DurationMs, a.StatusCode, a.Error, a.ResponseBody,
+		); err != nil {
+			return fmt.Errorf("insert webhook attempt: %w", err)
+		}
+		_, err := tx.Exec(
+			`UPDATE webhook_deliveries
+             SET status = $2, next_attempt_at = $3, last_status_code = $4, last_error = $5,
+                 delivered_at = CASE WHEN $2 = 'delivered' THEN now() END
+             WHERE id = $1`,
+			deliveryID, status, next, a.StatusCode, a.Error,
+		)
+		if err != nil {
+			return fmt.Errorf("update webhook delivery: %w", err)
+		}
+		return nil
+	})
+}
+
+// ListDeliveries возвращает журнал доставок подписки, новые первыми;
+// status фильтрует по статусу, если не пуст.
+func (r *WebhookRepo) ListDeliveries(subscriptionID int64, status string, limit int) ([]*domain.WebhookDelivery, error) {
+	rows, err := r.DB.Query(
+		`SELECT`+webhookDeliveryColumns+`
+         FROM webhook_deliveries d
+         JOIN events e ON e.id = d.event_id
+         WHERE d.subscription_id = $1 AND ($2 = '' OR d.status = $2)
+         ORDER BY d.id DESC
+         LIMIT $3`,
+		subscriptionID, status, limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("list webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	var list []*domain.WebhookDelivery
+	for rows.Next() {
+		d, err := scanWebhookDelivery(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan webhook delivery: %w", err)
+		}
+		list = append(list, d)
+	}
+	return list, rows.Err()
+}
+
+// GetDelivery возвращает доставку подписки вместе с журналом попыток.
+func (r *WebhookRepo) GetDelivery(subscriptionID, id int64) (*domain.WebhookDelivery, error) {
+	d, err := scanWebhookDelivery(r.DB.QueryRow(
+		`SELECT`+webhookDeliveryColumns+`
+         FROM webhook_deliveries d
+         JOIN events e ON e.id = d.event_id
+         WHERE d.id = $1 AND d.subscription_id = $2`,
+		id, subscriptionID,
+	))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.DB.Query(
+		`SELECT id, started_at, duration_ms, status_code, error, response_body
+         FROM webhook_attempts
+         WHERE delivery_id = $1
+         ORDER BY id`,
+		id,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("list webhook attempts: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		a := &domain.WebhookAttempt{}
+		if err := rows.Scan(&a.ID, &a.StartedAt, &a.DurationMs, &a.StatusCode, &a.Error, &a.ResponseBody); err != nil {
+			return nil, fmt.Errorf("scan webhook attempt: %w", err)
+		}
+		d.AttemptLog = append(d.AttemptLog, a)
+	}
+	return d, rows.Err()
+}
+
+// Redeliver ставит доставку в очередь заново с новым счётчиком попыток —
+// и неудавшуюся, и уже доставленную. Журнал прошлых попыток сохраняется.
+func (r *WebhookRepo) Redeliver(subscriptionID, id int64) (*domain.WebhookDelivery, error) {
+	res, err := r.DB.Exec(
+		`UPDATE webhook_deliveries
+         SET status = 'pending', attempts = 0, next_attempt_at = now(), delivered_at = NULL
+         WHERE id = $1 AND subscription_id = $2`,
+		id, subscriptionID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("redeliver webhook: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return nil, ErrNotFound
+	}
+	return r.GetDelivery(subscriptionID, id)
+}
+
+// PurgeEvents удаляет события старше before вместе с их доставками.
+func (r *WebhookRepo) PurgeEvents(before time.Time) (int64, error) {
+	res, err := r.DB.Exec(`DELETE FROM events WHERE created_at < $1`, before)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
@@ -2,36 +2,187 @@ package router
 
 import (
 	"net/http"
+	"poppins/auth"
 	"poppins/handlers"
+	"poppins/idempotency"
+	"poppins/ratelimit"
 
 	"github.com/gorilla/mux"
 )
 
-func NewRouter(uh *handlers.UserHandler, ah *handlers.AdHandler) *mux.Router {
+// Handlers — все HTTP-хендлеры приложения.
+type Handlers struct {
+	Users         *handlers.UserHandler
+	Ads           *handlers.AdHandler
+	APIKeys       *handlers.APIKeyHandler
+	Moderation    *handlers.ModerationHandler
+	Reports       *handlers.ReportHandler
+	Sanctions     *handlers.SanctionHandler
+	Conversations *handlers.ConversationHandler
+	Favorites     *handlers.FavoriteHandler
+	Stats         *handlers.StatsHandler
+	Reviews       *handlers.ReviewHandler
+	Promotions    *handlers.PromotionHandler
+	Payments      *handlers.PaymentHandler
+	Outbox        *handlers.OutboxHandler
+	Webhooks      *handlers.WebhookHandler
+	Stream        *handlers.StreamHandler
+	Feeds         *handlers.FeedHandler
+	Exports       *handlers.ExportHandler
+	Imports       *handlers.ImportHandler
+	Catalog       *handlers.CatalogHandler
+}
+
+func NewRouter(a *auth.Authenticator, rl *ratelimit.Limiter, idem *idempotency.Middleware, h Handlers) *mux.Router {
 	r := mux.NewRouter()
+	uh, ah := h.Users, h.Ads
+
+	// Каждый маршрут API требует scope ключа: чтение — read,
+	// действия от имени пользователей — bot, модерация — moderator,
+	// управление — admin.
+	read := scoped(a, auth.ScopeRead)
+	bot := scoped(a, auth.ScopeBot)
+	moderator := scoped(a, auth.ScopeModerator)
+	admin := scoped(a, auth.ScopeAdmin)
+
+	// Ограничения частоты проверяются после аутентификации
+	create := rl.Middleware(ratelimit.Create)
+	edit := rl.Middleware(ratelimit.Edit)
+	search := rl.Middleware(ratelimit.Search)
+	contact := rl.Middleware(ratelimit.Contact)
+	// Повтор запроса отдаётся из сохранённых ответов, не расходуя лимит
+	once := idem.Wrap
 
 	// User endpoints
-	r.HandleFunc("/users", uh.Create).Methods("POST")
-	r.HandleFunc("/users/{telegramId}", uh.Get).Methods("GET")
-	r.HandleFunc("/users/{telegramId}", uh.Delete).Methods("DELETE")
+	r.Handle("/users", bot(uh.Create, once)).Methods("POST")
+	r.Handle("/users/{telegramId}", read(uh.Get)).Methods("GET")
+	r.Handle("/users/{telegramId}", bot(uh.Delete, edit)).Methods("DELETE")
 
-	r.HandleFunc("/users/{telegramId}/name", uh.UpdateName).Methods("PATCH")
-	r.HandleFunc("/users/{telegramId}/phone", uh.UpdatePhone).Methods("PATCH")
-	r.HandleFunc("/users/{telegramId}/contact", uh.UpdateContact).Methods("PATCH")
+	r.Handle("/users/{telegramId}/name", bot(uh.UpdateName, edit)).Methods("PATCH")
+	r.Handle("/users/{telegramId}/phone", bot(uh.UpdatePhone, edit)).Methods("PATCH")
+	r.Handle("/users/{telegramId}/contact", bot(uh.UpdateContact, edit)).Methods("PATCH")
+	r.Handle("/users/{telegramId}/bot-status", bot(h.Outbox.SetBotStatus)).Methods("PUT")
+	r.Handle("/users/{telegramId}/export", bot(h.Exports.Get, edit)).Methods("GET")
 
 	// Список объявлений конкретного пользователя
-	r.HandleFunc("/users/{telegramId}/ads", ah.ListByTelegram).Methods("GET")
+	r.Handle("/users/{telegramId}/ads", read(ah.ListByTelegram)).Methods("GET")
+	r.Handle("/users/{telegramId}/ads/stats", bot(h.Stats.Seller)).Methods("GET")
+
+	// Избранное
+	fh := h.Favorites
+	r.Handle("/users/{telegramId}/favorites", read(fh.List)).Methods("GET")
+	r.Handle("/users/{telegramId}/favorites/{adId}", bot(fh.Add, edit)).Methods("PUT")
+	r.Handle("/users/{telegramId}/favorites/{adId}", bot(fh.Remove)).Methods("DELETE")
 
 	// Ad endpoints
-	r.HandleFunc("/ads", ah.Create).Methods("POST")
-	r.HandleFunc("/ads/{id}", ah.Get).Methods("GET")
-	r.HandleFunc("/ads", ah.Search).Methods("GET")
-	r.HandleFunc("/ads/{id}", ah.Update).Methods("PUT")
-	r.HandleFunc("/ads/{id}", ah.Delete).Methods("DELETE")
-	r.HandleFunc("/ads/{id}/archive", ah.Archive).Methods("PATCH")
+	r.Handle("/ads", bot(ah.Create, once, create)).Methods("POST")
+	r.Handle("/users/{telegramId}/imports", bot(h.Imports.Create, once, create)).Methods("POST")
+	r.Handle("/users/{telegramId}/imports/{id}", bot(h.Imports.Get)).Methods("GET")
+	// Поток изменений регистрируется раньше /ads/{id}
+	r.Handle("/ads/stream", read(h.Stream.Ads, search)).Methods("GET")
+	r.Handle("/ads/{id}", read(ah.Get)).Methods("GET")
+	r.Handle("/ads", read(ah.Search, search)).Methods("GET")
+	r.Handle("/ads/{id}", bot(ah.Update, edit)).Methods("PUT")
+	r.Handle("/ads/{id}", bot(ah.Delete, edit)).Methods("DELETE")
+	r.Handle("/ads/{id}/archive", bot(ah.Archive, edit)).Methods("PATCH")
+	r.Handle("/ads/{id}/reports", bot(h.Reports.Create, edit)).Methods("POST")
+	r.Handle("/ads/{id}/contact", bot(ah.RevealContact, contact)).Methods("POST")
+
+	// Promotion endpoints
+	ph := h.Promotions
+	r.Handle("/promotions/packages", read(ph.Packages)).Methods("GET")
+	r.Handle("/ads/{id}/promotions", read(ph.List)).Methods("GET")
+
+	// Payment endpoints
+	pay := h.Payments
+	r.Handle("/ads/{id}/orders", bot(pay.CreatePromotionOrder, once, edit)).Methods("POST")
+	r.Handle("/orders/{id}", bot(pay.Get)).Methods("GET")
+	r.Handle("/orders/{id}/cancel", bot(pay.Cancel, edit)).Methods("POST")
+	r.Handle("/users/{telegramId}/orders", bot(pay.ListByTelegram)).Methods("GET")
+	// Уведомления провайдера приходят без API-ключа, их подлинность
+	// проверяет сам провайдер
+	r.HandleFunc("/payments/{provider}/webhook", pay.Webhook).Methods("POST")
+
+	// Review endpoints
+	rvh := h.Reviews
+	r.Handle("/ads/{id}/reviews", bot(rvh.Create, edit)).Methods("POST")
+	r.Handle("/users/{telegramId}/reviews", read(rvh.ListBySeller)).Methods("GET")
+	r.Handle("/reviews/{id}/reply", bot(rvh.Reply, edit)).Methods("POST")
+	r.Handle("/reviews/{id}/flag", bot(rvh.Flag, edit)).Methods("POST")
+
+	// Conversation endpoints
+	ch := h.Conversations
+	r.Handle("/ads/{id}/conversations", bot(ch.Start, edit)).Methods("POST")
+	r.Handle("/users/{telegramId}/conversations", bot(ch.ListByTelegram)).Methods("GET")
+	r.Handle("/conversations/{id}/messages", bot(ch.Messages)).Methods("GET")
+	r.Handle("/conversations/{id}/messages", bot(ch.Send, edit)).Methods("POST")
+	r.Handle("/conversations/{id}/read", bot(ch.MarkRead)).Methods("POST")
+	r.Handle("/conversations/{id}/block", bot(ch.Block)).Methods("POST")
+	r.Handle("/conversations/{id}/block", bot(ch.Unblock)).Methods("DELETE")
+
+	// Moderation endpoints
+	mh := h.Moderation
+	r.Handle("/moderation/tasks", moderator(mh.List)).Methods("GET")
+	r.Handle("/moderation/tasks/{id}/claim", moderator(mh.Claim)).Methods("POST")
+	r.Handle("/moderation/tasks/{id}/approve", moderator(mh.Approve)).Methods("POST")
+	r.Handle("/moderation/tasks/{id}/reject", moderator(mh.Reject)).Methods("POST")
+	r.Handle("/moderation/reports", moderator(h.Reports.List)).Methods("GET")
+	r.Handle("/moderation/reports/{id}/resolve", moderator(h.Reports.Resolve)).Methods("POST")
+	r.Handle("/moderation/reviews", moderator(rvh.ListForModeration)).Methods("GET")
+	r.Handle("/moderation/reviews/{id}/publish", moderator(rvh.Publish)).Methods("POST")
+	r.Handle("/moderation/reviews/{id}/hide", moderator(rvh.Hide)).Methods("POST")
+
+	// Admin endpoints
+	kh := h.APIKeys
+	r.Handle("/admin/api-keys", admin(kh.Create)).Methods("POST")
+	r.Handle("/admin/api-keys", admin(kh.List)).Methods("GET")
+	r.Handle("/admin/api-keys/{id}", admin(kh.Revoke)).Methods("DELETE")
+
+	r.Handle("/admin/ads/{id}/promotions", admin(ph.Create)).Methods("POST")
+	r.Handle("/admin/ads/{id}/promotions/{promotionId}", admin(ph.Cancel)).Methods("DELETE")
+	r.Handle("/admin/orders/{id}/refund", admin(pay.Refund)).Methods("POST")
+
+	r.Handle("/admin/outbox", admin(h.Outbox.List)).Methods("GET")
+	r.Handle("/admin/outbox/{id}/requeue", admin(h.Outbox.Requeue)).Methods("POST")
+
+	wh := h.Webhooks
+	r.Handle("/admin/webhooks", admin(wh.Create)).Methods("POST")
+	r.Handle("/admin/webhooks", admin(wh.List)).Methods("GET")
+	r.Handle("/admin/webhooks/{id}", admin(wh.Get)).Methods("GET")
+	r.Handle("/admin/webhooks/{id}", admin(wh.Update)).Methods("PATCH")
+	r.Handle("/admin/webhooks/{id}", admin(wh.Delete)).Methods("DELETE")
+	r.Handle("/admin/webhooks/{id}/deliveries", admin(wh.Deliveries)).Methods("GET")
+	r.Handle("/admin/webhooks/{id}/deliveries/{deliveryId}", admin(wh.Delivery)).Methods("GET")
+	r.Handle("/admin/webhooks/{id}/deliveries/{deliveryId}/redeliver", admin(wh.Redeliver)).Methods("POST")
+
+	// Atom-ленты читают агрегаторы без ключа API; ограничен только поиск по IP
+	feeds := h.Feeds
+	r.Handle("/feeds/ads.atom", search(http.HandlerFunc(feeds.Search))).Methods("GET")
+	r.Handle("/users/{telegramId}/ads.atom", search(http.HandlerFunc(feeds.Seller))).Methods("GET")
+	// Полные фиды каталога выгружают все объявления, поэтому нужен ключ API
+	r.Handle("/feeds/ads.yml", read(h.Catalog.YML, search)).Methods("GET")
+	r.Handle("/feeds/avito.xml", read(h.Catalog.Avito, search)).Methods("GET")
+
+	sh := h.Sanctions
+	r.Handle("/admin/users/{telegramId}/sanctions", admin(sh.Create)).Methods("POST")
+	r.Handle("/admin/users/{telegramId}/sanctions", admin(sh.List)).Methods("GET")
+	r.Handle("/admin/users/{telegramId}/sanctions/{id}", admin(sh.Lift)).Methods("DELETE")
 
 	r.PathPrefix("/" + ah.Bucket + "/").
 		Handler(http.StripPrefix("/"+ah.Bucket+"/", http.FileServer(http.Dir("."))))
 
 	return r
 }
+
+// scoped оборачивает хендлеры проверкой scope API-ключа и дополнительными
+// middleware, которые выполняются после неё в указанном порядке.
+func scoped(a *auth.Authenticator, scope string) func(http.HandlerFunc, ...func(http.Handler) http.Handler) http.Handler {
+	require := a.Require(scope)
+	return func(h http.HandlerFunc, mws ...func(http.Handler) http.Handler) http.Handler {
+		var next http.Handler = h
+		for i := len(mws) - 1; i >= 0; i-- {
+			next = mws[i](next)
+		}
+		return require(next)
+	}
+}
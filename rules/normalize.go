@@ -0,0 +1,84 @@
+package rules
+
+import (
+	"strings"
+	"unicode"
+)
+
+// homoglyphs — латинские буквы, которыми подменяют кириллицу ("нaркотики").
+var homoglyphs = map[rune]rune{
+	'a': 'а', 'b': 'в', 'c': 'с', 'e': 'е', 'h': 'н', 'k': 'к', 'm': 'м',
+	'o': 'о', 'p': 'р', 't': 'т', 'x': 'х', 'y': 'у', '0': 'о', '3': 'з',
+}
+
+// tokenize разбивает текст на слова в нижнем регистре с ё → е. В словах,
+// где есть кириллица, латинские двойники заменяются кириллицей.
+func tokenize(text string) []string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for i, w := range words {
+		words[i] = normalizeWord(w)
+	}
+	return words
+}
+
+func normalizeWord(w string) string {
+	w = strings.ReplaceAll(w, "ё", "е")
+	hasCyrillic := false
+	for _, r := range w {
+		if unicode.Is(unicode.Cyrillic, r) {
+			hasCyrillic = true
+			break
+		}
+	}
+	if !hasCyrillic {
+		return w
+	}
+	return strings.Map(func(r rune) rune {
+		if c, ok := homoglyphs[r]; ok {
+			return c
+		}
+		return r
+	}, w)
+}
+
+// russianEndings — окончания и суффиксы словоизменения, от длинных к коротким.
+// Упрощённый вариант стеммера Портера для русского: нам нужно не точное
+// выделение основы, а совпадение разных форм одного слова.
+var russianEndings = []string{
+	"ившись", "ывшись", "иями", "ями", "ами", "ией", "иям", "ием", "иях",
+	"ого", "его", "ому", "ему", "ыми", "ими", "ая", "яя", "ое", "ее", "ые", "ие",
+	"ый", "ий", "ой", "ей", "ом", "ем", "ам", "ям", "ах", "ях", "ов", "ев",
+	"ую", "юю", "ть", "ти", "ла", "ло", "ли", "ешь", "ете", "ут", "ют", "ит", "ат", "ят",
+	"ия", "ие", "ию", "ья", "ье", "ью", "ь", "а", "я", "о", "е", "ы", "и", "у", "ю", "й",
+}
+
+// stem отрезает окончание, оставляя основу не короче трёх букв.
+func stem(word string) string {
+	runes := []rune(word)
+	for _, end := range russianEndings {
+		e := []rune(end)
+		if len(runes)-len(e) >= 3 && strings.HasSuffix(word, end) {
+			return string(runes[:len(runes)-len(e)])
+		}
+	}
+	return word
+}
+
+// digitWords — цифры прописью, которыми прячут номер телефона.
+var digitWords = map[string]string{
+	"ноль": "0", "нуль": "0", "один": "1", "два": "2", "три": "3", "четыре": "4",
+	"пять": "5", "шесть": "6", "семь": "7", "восемь": "8", "девять": "9",
+}
+
+// spellDigits заменяет цифры прописью на цифры.
+func spellDigits(text string) string {
+	words := strings.Fields(strings.ToLower(text))
+	for i, w := range words {
+		if d, ok := digitWords[strings.Trim(w, ".,;:-")]; ok {
+			words[i] = d
+		}
+	}
+	return strings.Join(words, " ")
+}
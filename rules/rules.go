@@ -0,0 +1,315 @@
+// Package rules автоматически проверяет текст и цену объявлений:
+// запрещённые слова, спрятанные контакты, заголовки капсом, подозрительные цены.
+package rules
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"poppins/domain"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// Действия правила.
+const (
+	// ActionReject — объявление не принимается.
+	ActionReject = "reject"
+	// ActionFlag — объявление публикуется только после модерации.
+	ActionFlag = "flag"
+	// ActionScore — к рейтингу риска объявления добавляется Score.
+	ActionScore = "score"
+)
+
+// Типы правил.
+const (
+	TypeBannedWords = "banned_words"
+	TypeContacts    = "contacts"
+	TypeCapsTitle   = "caps_title"
+	TypePrice       = "price"
+)
+
+// PriceBounds — допустимый диапазон цены; 0 — без ограничения.
+type PriceBounds struct {
+	Min int64 `json:"min"`
+	Max int64 `json:"max"`
+}
+
+// RuleConfig — описание одного правила в файле конфигурации.
+type RuleConfig struct {
+	Name   string `json:"name"`
+	Type   string `json:"type"`
+	Action string `json:"action"`
+	Score  int    `json:"score"`
+
+	// banned_words: слова и фразы в любой словоформе.
+	Words []string `json:"words,omitempty"`
+
+	// caps_title: минимум букв в заголовке и доля заглавных.
+	MinLetters int     `json:"min_letters,omitempty"`
+	Ratio      float64 `json:"ratio,omitempty"`
+
+	// price: общий диапазон и переопределения по категориям.
+	PriceBounds
+	Categories map[string]PriceBounds `json:"categories,omitempty"`
+}
+
+// Config — набор правил и порог рейтинга риска.
+type Config struct {
+	// FlagScore — при сумме баллов не меньше порога объявление уходит на модерацию;
+	// 0 отключает порог.
+	FlagScore int          `json:"flag_score"`
+	Rules     []RuleConfig `json:"rules"`
+}
+
+// DefaultConfig используется, если файл правил не задан.
+func DefaultConfig() Config {
+	return Config{
+		FlagScore: 10,
+		Rules: []RuleConfig{
+			{Name: "prohibited", Type: TypeBannedWords, Action: ActionReject, Words: []string{
+				"наркотики", "закладка", "спайс", "оружие", "боеприпасы",
+				"поддельный паспорт", "водительские права купить", "обнал",
+			}},
+			{Name: "contacts", Type: TypeContacts, Action: ActionFlag, Score: 5},
+			{Name: "caps_title", Type: TypeCapsTitle, Action: ActionScore, Score: 3, MinLetters: 6, Ratio: 0.7},
+			{Name: "price", Type: TypePrice, Action: ActionFlag, Score: 5,
+				PriceBounds: PriceBounds{Min: 1, Max: 1_000_000_000}},
+		},
+	}
+}
+
+// LoadConfig читает правила из JSON-файла; пустой путь — правила по умолчанию.
+func LoadConfig(path string) (Config, error) {
+	if path == "" {
+		return DefaultConfig(), nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, fmt.Errorf("read rules: %w", err)
+	}
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return Config{}, fmt.Errorf("parse rules %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// Hit — сработавшее правило.
+type Hit struct {
+	Rule   string `json:"rule"`
+	Action string `json:"action"`
+	Detail string `json:"detail"`
+}
+
+// Result — итог проверки объявления.
+type Result struct {
+	Hits   []Hit `json:"hits"`
+	Score  int   `json:"score"`
+	Reject bool  `json:"reject"`
+	Flag   bool  `json:"flag"`
+}
+
+// Summary — краткое описание сработавших правил для модератора.
+func (r Result) Summary() string {
+	parts := make([]string, 0, len(r.Hits))
+	for _, h := range r.Hits {
+		parts = append(parts, h.Rule+": "+h.Detail)
+	}
+	return strings.Join(parts, "; ")
+}
+
+type rule struct {
+	RuleConfig
+	check func(ad *domain.Advertisement) (string, bool)
+}
+
+// Engine применяет набор правил к объявлениям. Безопасен для конкурентного использования.
+type Engine struct {
+	flagScore int
+	rules     []rule
+}
+
+// NewEngine компилирует правила из конфигурации.
+func NewEngine(cfg Config) (*Engine, error) {
+	e := &Engine{flagScore: cfg.FlagScore}
+	for _, rc := range cfg.Rules {
+		switch rc.Action {
+		case ActionReject, ActionFlag, ActionScore:
+		default:
+			return nil, fmt.Errorf("rule %q: unknown action %q", rc.Name, rc.Action)
+		}
+		var check func(*domain.Advertisement) (string, bool)
+		switch rc.Type {
+		case TypeBannedWords:
+			check = bannedWords(rc.Words)
+		case TypeContacts:
+			check = hiddenContacts
+		case TypeCapsTitle:
+			check = capsTitle(rc.MinLetters, rc.Ratio)
+		case TypePrice:
+			check = suspiciousPrice(rc.PriceBounds, rc.Categories)
+		default:
+			return nil, fmt.Errorf("rule %q: unknown type %q", rc.Name, rc.Type)
+		}
+		if rc.Name == "" {
+			rc.Name = rc.Type
+		}
+		e.rules = append(e.rules, rule{RuleConfig: rc, check: check})
+	}
+	return e, nil
+}
+
+// Check проверяет объявление всеми правилами.
+func (e *Engine) Check(ad *domain.Advertisement) Result {
+	return e.apply(ad, nil)
+}
+
+// CheckText проверяет произвольный пользовательский текст (отзыв, ответ
+// продавца) правилами, применимыми к тексту: запрещённые слова и контакты.
+func (e *Engine) CheckText(text string) Result {
+	return e.apply(&domain.Advertisement{Description: text}, func(r rule) bool {
+		return r.Type == TypeBannedWords || r.Type == TypeContacts
+	})
+}
+
+// apply проверяет объявление правилами, прошедшими filter (nil — всеми),
+// и сводит сработавшие в итог.
+func (e *Engine) apply(ad *domain.Advertisement, filter func(rule) bool) Result {
+	var res Result
+	for _, r := range e.rules {
+		if filter != nil && !filter(r) {
+			continue
+		}
+		detail, hit := r.check(ad)
+		if !hit {
+			continue
+		}
+		res.Hits = append(res.Hits, Hit{Rule: r.Name, Action: r.Action, Detail: detail})
+		res.Score += r.Score
+		switch r.Action {
+		case ActionReject:
+			res.Reject = true
+		case ActionFlag:
+			res.Flag = true
+		}
+	}
+	if e.flagScore > 0 && res.Score >= e.flagScore {
+		res.Flag = true
+	}
+	return res
+}
+
+// bannedWords ищет запрещённые слова и фразы в заголовке и описании
+// независимо от словоформы: сравниваются основы слов.
+func bannedWords(words []string) func(*domain.Advertisement) (string, bool) {
+	type phrase struct {
+		text  string
+		stems []string
+	}
+	var phrases []phrase
+	for _, w := range words {
+		var stems []string
+		for _, t := range tokenize(w) {
+			stems = append(stems, stem(t))
+		}
+		if len(stems) > 0 {
+			phrases = append(phrases, phrase{text: w, stems: stems})
+		}
+	}
+
+	return func(ad *domain.Advertisement) (string, bool) {
+		tokens := tokenize(ad.Title + " " + ad.Description)
+		stems := make([]string, len(tokens))
+		for i, t := range tokens {
+			stems[i] = stem(t)
+		}
+		for _, p := range phrases {
+			for i := 0; i+len(p.stems) <= len(stems); i++ {
+				if stemsMatch(stems[i:i+len(p.stems)], p.stems) {
+					return "запрещённое слово «" + p.text + "»", true
+				}
+			}
+		}
+		return "", false
+	}
+}
+
+// stemsMatch сравнивает основы; длинная основа совпадает и с производными
+// словами ("наркот" → "наркотический").
+func stemsMatch(got, want []string) bool {
+	for i := range want {
+		if got[i] == want[i] {
+			continue
+		}
+		if len([]rune(want[i])) >= 5 && strings.HasPrefix(got[i], want[i]) {
+			continue
+		}
+		return false
+	}
+	return true
+}
+
+var (
+	// phonePattern — десять и больше цифр подряд с любыми разделителями.
+	phonePattern = regexp.MustCompile(`(?:\+?\d[\s\-().]*){10,}`)
+	// linkPattern — ссылки, домены (в том числе "site точка ru"), t.me и @username.
+	linkPattern = regexp.MustCompile(
+		`(?i)(https?://|www\.|t\.me/|\B@[a-z0-9_]{4,}|\b[a-z0-9\-]+\s*(\.|\(?точка\)?|\(?dot\)?)\s*(ru|com|net|org|рф|me|io|su|info)\b)`)
+)
+
+// hiddenContacts ищет телефоны и ссылки в заголовке и описании, в том числе
+// записанные прописью или с разделителями.
+func hiddenContacts(ad *domain.Advertisement) (string, bool) {
+	text := ad.Title + " " + ad.Description
+	if phonePattern.MatchString(text) || phonePattern.MatchString(spellDigits(text)) {
+		return "номер телефона в тексте", true
+	}
+	if m := linkPattern.FindString(text); m != "" {
+		return "ссылка или контакт в тексте: " + m, true
+	}
+	return "", false
+}
+
+// capsTitle срабатывает на заголовок, написанный в основном заглавными.
+func capsTitle(minLetters int, ratio float64) func(*domain.Advertisement) (string, bool) {
+	if ratio <= 0 {
+		ratio = 0.7
+	}
+	return func(ad *domain.Advertisement) (string, bool) {
+		letters, upper := 0, 0
+		for _, r := range ad.Title {
+			if unicode.IsLetter(r) {
+				letters++
+				if unicode.IsUpper(r) {
+					upper++
+				}
+			}
+		}
+		if letters < minLetters || letters == 0 {
+			return "", false
+		}
+		if float64(upper)/float64(letters) >= ratio {
+			return "заголовок заглавными буквами", true
+		}
+		return "", false
+	}
+}
+
+// suspiciousPrice срабатывает на цену вне допустимого для категории диапазона.
+func suspiciousPrice(def PriceBounds, byCategory map[string]PriceBounds) func(*domain.Advertisement) (string, bool) {
+	return func(ad *domain.Advertisement) (string, bool) {
+		b := def
+		if cb, ok := byCategory[ad.Category]; ok {
+			b = cb
+		}
+		if b.Min > 0 && ad.Price < b.Min {
+			return fmt.Sprintf("цена %d ниже %d", ad.Price, b.Min), true
+		}
+		if b.Max > 0 && ad.Price > b.Max {
+			return fmt.Sprintf("цена %d выше %d", ad.Price, b.Max), true
+		}
+		return "", false
+	}
+}
@@ -0,0 +1,151 @@
+package rules
+
+import (
+	"poppins/domain"
+	"strings"
+	"testing"
+)
+
+// hitRules — имена сработавших правил через запятую.
+func hitRules(res Result) string {
+	names := make([]string, 0, len(res.Hits))
+	for _, h := range res.Hits {
+		names = append(names, h.Rule)
+	}
+	return strings.Join(names, ",")
+}
+
+func TestCheck(t *testing.T) {
+	cfg := DefaultConfig()
+	for i := range cfg.Rules {
+		if cfg.Rules[i].Type == TypePrice {
+			cfg.Rules[i].Categories = map[string]PriceBounds{
+				"Недвижимость": {Min: 100_000},
+				"Подарки":      {Min: 0, Max: 0},
+			}
+		}
+	}
+	e, err := NewEngine(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ad := func(title, description string, price int64, category string) *domain.Advertisement {
+		return &domain.Advertisement{Title: title, Description: description, Price: price, Category: category}
+	}
+	tests := []struct {
+		name       string
+		ad         *domain.Advertisement
+		wantHits   string
+		wantReject bool
+		wantFlag   bool
+	}{
+		{name: "clean", ad: ad("Диван угловой", "Раскладной, без пятен.", 15000, "Мебель")},
+		{name: "banned word form", ad: ad("Продам", "Много наркотиков недорого", 1000, "Разное"),
+			wantHits: "prohibited", wantReject: true},
+		{name: "banned word with latin homoglyph", ad: ad("Продам нaркотики", "", 1000, "Разное"),
+			wantHits: "prohibited", wantReject: true},
+		{name: "banned phrase in another form", ad: ad("Поддельные паспорта", "", 1000, "Разное"),
+			wantHits: "prohibited", wantReject: true},
+		{name: "banned word in instrumental plural", ad: ad("Работа", "Курьер с закладками", 1000, "Вакансии"),
+			wantHits: "prohibited", wantReject: true},
+		{name: "different word with a close stem", ad: ad("Оружейное масло", "Для чистки", 300, "Спорт")},
+		{name: "phone with separators", ad: ad("Велосипед", "Звоните +7 (912) 345-67-89", 5000, "Спорт"),
+			wantHits: "contacts", wantFlag: true},
+		{name: "phone spelled in words", ad: ad("Велосипед",
+			"Звоните восемь девять один два три четыре пять шесть семь восемь девять", 5000, "Спорт"),
+			wantHits: "contacts", wantFlag: true},
+		{name: "site точка ru", ad: ad("Велосипед", "Подробности на site точка ru", 5000, "Спорт"),
+			wantHits: "contacts", wantFlag: true},
+		{name: "telegram username", ad: ad("Велосипед", "Пишите @seller_bike", 5000, "Спорт"),
+			wantHits: "contacts", wantFlag: true},
+		{name: "caps title only scores", ad: ad("ПРОДАМ ДИВАН СРОЧНО", "", 15000, "Мебель"),
+			wantHits: "caps_title"},
+		{name: "caps below ratio", ad: ad("Продам ДИВАН срочно", "", 15000, "Мебель")},
+		{name: "short caps title", ad: ad("ТВ LG", "", 15000, "Электроника")},
+		{name: "price below default", ad: ad("Диван", "", 0, "Мебель"),
+			wantHits: "price", wantFlag: true},
+		{name: "price above default", ad: ad("Диван", "", 2_000_000_000, "Мебель"),
+			wantHits: "price", wantFlag: true},
+		{name: "category override raises minimum", ad: ad("Квартира", "", 50_000, "Недвижимость"),
+			wantHits: "price", wantFlag: true},
+		{name: "category override allows price", ad: ad("Квартира", "", 5_000_000, "Недвижимость")},
+		{name: "category override without bounds", ad: ad("Котёнок", "", 0, "Подарки")},
+		{name: "score reaches flag threshold", ad: ad("ПРОДАМ ДИВАН СРОЧНО", "Звоните 89123456789", 15000, "Мебель"),
+			wantHits: "contacts,caps_title", wantFlag: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := e.Check(tt.ad)
+			if got := hitRules(res); got != tt.wantHits {
+				t.Errorf("hits = %q, want %q (%s)", got, tt.wantHits, res.Summary())
+			}
+			if res.Reject != tt.wantReject || res.Flag != tt.wantFlag {
+				t.Errorf("reject, flag = %v, %v; want %v, %v", res.Reject, res.Flag, tt.wantReject, tt.wantFlag)
+			}
+		})
+	}
+}
+
+func TestFlagScore(t *testing.T) {
+	e, err := NewEngine(Config{FlagScore: 5, Rules: []RuleConfig{
+		{Name: "caps", Type: TypeCapsTitle, Action: ActionScore, Score: 3, MinLetters: 4},
+		{Name: "cheap", Type: TypePrice, Action: ActionScore, Score: 2, PriceBounds: PriceBounds{Min: 100}},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name      string
+		ad        *domain.Advertisement
+		wantScore int
+		wantFlag  bool
+	}{
+		{"below threshold", &domain.Advertisement{Title: "ДИВАН", Price: 500}, 3, false},
+		{"at threshold", &domain.Advertisement{Title: "ДИВАН", Price: 50}, 5, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := e.Check(tt.ad)
+			if res.Score != tt.wantScore || res.Flag != tt.wantFlag {
+				t.Errorf("score, flag = %d, %v; want %d, %v", res.Score, res.Flag, tt.wantScore, tt.wantFlag)
+			}
+		})
+	}
+}
+
+func TestCheckText(t *testing.T) {
+	e, err := NewEngine(DefaultConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name     string
+		text     string
+		wantHits string
+	}{
+		{"clean review", "Отличный продавец, всё как в описании.", ""},
+		{"caps and price rules do not apply", "ВСЁ ОТЛИЧНО, РЕКОМЕНДУЮ", ""},
+		{"banned word", "Продавец предлагал закладки", "prohibited"},
+		{"contact", "Пишите мне на site точка ru", "contacts"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := hitRules(e.CheckText(tt.text)); got != tt.wantHits {
+				t.Errorf("hits = %q, want %q", got, tt.wantHits)
+			}
+		})
+	}
+}
+
+func TestNewEngineRejectsUnknown(t *testing.T) {
+	tests := []RuleConfig{
+		{Name: "bad action", Type: TypeContacts, Action: "ban"},
+		{Name: "bad type", Type: "emoji", Action: ActionFlag},
+	}
+	for _, rc := range tests {
+		if _, err := NewEngine(Config{Rules: []RuleConfig{rc}}); err == nil {
+			t.Errorf("NewEngine(%q) succeeded, want error", rc.Name)
+		}
+	}
+}
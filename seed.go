@@ -0,0 +1,150 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"log"
+	"math/rand"
+	"os"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+
+	"poppins/config"
+	"poppins/dedup"
+	"poppins/domain"
+	"poppins/imports"
+)
+
+// seedCatalog — категории с правдоподобными заголовками и диапазоном цен.
+var seedCatalog = []struct {
+	Category string
+	Titles   []string
+	MinPrice int64
+	MaxPrice int64
+}{
+	{"Телефоны", []string{"iPhone 12 128 ГБ", "Samsung Galaxy S21", "Xiaomi Redmi Note 10", "Google Pixel 6"}, 8000, 60000},
+	{"Компьютеры", []string{"Ноутбук Lenovo ThinkPad", "MacBook Air M1", "Игровой ПК Ryzen 5", "Монитор Dell 27\""}, 10000, 120000},
+	{"Мебель", []string{"Диван угловой", "Стол обеденный раздвижной", "Шкаф-купе 2 м", "Кресло офисное"}, 1500, 40000},
+	{"Одежда", []string{"Куртка зимняя, размер M", "Джинсы Levi's 501", "Пальто шерстяное", "Кроссовки Nike, 42"}, 500, 15000},
+	{"Детские товары", []string{"Коляска прогулочная", "Автокресло 9–18 кг", "Конструктор LEGO City", "Детский велосипед"}, 700, 25000},
+	{"Спорт", []string{"Велосипед горный", "Гантели разборные 2×10 кг", "Лыжи беговые с ботинками", "Палатка трёхместная"}, 1000, 45000},
+	{"Автозапчасти", []string{"Комплект зимней резины R16", "Аккумулятор 60 Ач", "Фары Ford Focus 3", "Диски литые R17"}, 2000, 50000},
+}
+
+var (
+	seedNames   = []string{"Анна", "Иван", "Мария", "Дмитрий", "Елена", "Сергей", "Ольга", "Алексей", "Наталья", "Павел"}
+	seedStreets = []string{
+		"Москва, ул. Тверская", "Москва, Ленинский пр-т", "Санкт-Петербург, Невский пр-т",
+		"Казань, ул. Баумана", "Екатеринбург, ул. Малышева", "Новосибирск, Красный пр-т",
+	}
+	seedConditions = []string{"В отличном состоянии.", "Б/у, есть следы использования.", "Почти новый, пользовались пару раз.", "Новый, в упаковке."}
+)
+
+// seedCommand выполняет «poppins seed»: создаёт пользователей с объявлениями
+// и сгенерированными фото для локальной разработки.
+func seedCommand(cfg *config.Config, args []string) int {
+	fs := flag.NewFlagSet("seed", flag.ContinueOnError)
+	users := fs.Int("users", 10, "how many users to create")
+	adsPerUser := fs.Int("ads", 5, "how many ads each user gets")
+	seed := fs.Int64("seed", time.Now().UnixNano(), "random seed, for reproducible data")
+	if err := fs.Parse(args); err != nil || fs.NArg() != 0 || *users < 1 || *adsPerUser < 0 {
+		fmt.Fprintln(os.Stderr, "usage: poppins seed [-users 10] [-ads 5] [-seed n]")
+		return 2
+	}
+	client, err := newMinIO(cfg)
+	if err != nil {
+		log.Print(err)
+		return 1
+	}
+	if err := imports.EnsureBucket(context.Background(), client, cfg.MinIOBucket); err != nil {
+		log.Printf("cannot create bucket %q: %v", cfg.MinIOBucket, err)
+		return 1
+	}
+	rnd := rand.New(rand.NewSource(*seed))
+
+	return withDB(cfg, func(repos *cliRepos) error {
+		var createdUsers, createdAds int
+		for i := 0; i < *users; i++ {
+			u := &domain.User{
+				TelegramID:       fmt.Sprintf("%d", 100000000+rnd.Int63n(900000000)),
+				Name:             seedNames[rnd.Intn(len(seedNames))],
+				Phone:            fmt.Sprintf("+79%09d", rnd.Int63n(1000000000)),
+				PreferredContact: domain.ContactTelegram,
+			}
+			if err := repos.Users.Create(u); err != nil {
+				// Случайный telegram_id или телефон мог совпасть с существующим
+				log.Printf("seed: skip user %s: %v", u.TelegramID, err)
+				continue
+			}
+			createdUsers++
+			for j := 0; j < *adsPerUser; j++ {
+				ad := seedAd(rnd, u)
+				if err := uploadSeedPhoto(client, cfg.MinIOBucket, rnd, ad); err != nil {
+					return err
+				}
+				if err := repos.Ads.Create(ad, nil); err != nil {
+					return fmt.Errorf("create ad: %w", err)
+				}
+				createdAds++
+			}
+		}
+		fmt.Printf("created %d users and %d ads (seed %d)\n", createdUsers, createdAds, *seed)
+		return nil
+	})
+}
+
+func seedAd(rnd *rand.Rand, u *domain.User) *domain.Advertisement {
+	c := seedCatalog[rnd.Intn(len(seedCatalog))]
+	title := c.Titles[rnd.Intn(len(c.Titles))]
+	// Цена округлена до сотен, как в настоящих объявлениях
+	price := (c.MinPrice + rnd.Int63n(c.MaxPrice-c.MinPrice)) / 100 * 100
+	ad := &domain.Advertisement{
+		TelegramID:  u.TelegramID,
+		Title:       title,
+		Description: fmt.Sprintf("%s. %s Самовывоз или встреча у метро, торг уместен.", title, seedConditions[rnd.Intn(len(seedConditions))]),
+		Price:       price,
+		Address:     fmt.Sprintf("%s, %d", seedStreets[rnd.Intn(len(seedStreets))], 1+rnd.Intn(120)),
+		Category:    c.Category,
+	}
+	textHash := int64(dedup.TextHash(ad.Title, ad.Description))
+	ad.TextHash = &textHash
+	return ad
+}
+
+// uploadSeedPhoto рисует фото из цветных прямоугольников, загружает его под
+// тем же именем, что и AdHandler.Create, и прописывает в объявление.
+func uploadSeedPhoto(client *minio.Client, bucket string, rnd *rand.Rand, ad *domain.Advertisement) error {
+	img := image.NewRGBA(image.Rect(0, 0, 640, 480))
+	randomColor := func() *image.Uniform {
+		return image.NewUniform(color.RGBA{uint8(rnd.Intn(256)), uint8(rnd.Intn(256)), uint8(rnd.Intn(256)), 255})
+	}
+	draw.Draw(img, img.Bounds(), randomColor(), image.Point{}, draw.Src)
+	for i, n := 0, 3+rnd.Intn(5); i < n; i++ {
+		x, y := rnd.Intn(560), rnd.Intn(400)
+		r := image.Rect(x, y, x+40+rnd.Intn(240), y+40+rnd.Intn(200))
+		draw.Draw(img, r, randomColor(), image.Point{}, draw.Src)
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return err
+	}
+	if h, err := dedup.ImageHash(bytes.NewReader(buf.Bytes())); err == nil {
+		photoHash := int64(h)
+		ad.PhotoHash = &photoHash
+	}
+
+	objectName := fmt.Sprintf("ads/%s_%d.png", ad.TelegramID, time.Now().UnixNano())
+	if _, err := client.PutObject(context.Background(), bucket, objectName,
+		bytes.NewReader(buf.Bytes()), int64(buf.Len()), minio.PutObjectOptions{ContentType: "image/png"}); err != nil {
+		return fmt.Errorf("upload photo: %w", err)
+	}
+	ad.PhotosUrls = "/ads/" + objectName
+	return nil
+}
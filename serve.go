@@ -0,0 +1,307 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+
+	"poppins/auth"
+	"poppins/catalog"
+	"poppins/config"
+	"poppins/dedup"
+	"poppins/domain"
+	"poppins/export"
+	"poppins/handlers"
+	"poppins/idempotency"
+	"poppins/imports"
+	"poppins/migrations"
+	"poppins/moderation"
+	"poppins/notify"
+	"poppins/payments"
+	"poppins/ratelimit"
+	"poppins/repository"
+	"poppins/router"
+	"poppins/rules"
+	"poppins/storage"
+	"poppins/stream"
+	"poppins/webhooks"
+
+	httpSwagger "github.com/swaggo/http-swagger"
+	_ "poppins/docs"
+)
+
+// serve запускает HTTP-сервер вместе с фоновыми обработчиками; перед стартом
+// применяются неприменённые миграции.
+func serve(cfg *config.Config) {
+	// Инициализация MinIO-клиента
+	minioClient, err := newMinIO(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ctx := context.Background()
+
+	// Проверяем и создаём бакет, если нужно
+	exists, err := minioClient.BucketExists(ctx, cfg.MinIOBucket)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if !exists {
+		if err := minioClient.MakeBucket(ctx, cfg.MinIOBucket, minio.MakeBucketOptions{}); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	// Выставляем публичную read-only политику на весь бакет
+	publicReadPolicy := fmt.Sprintf(`{
+  "Version":"2012-10-17",
+  "Statement":[
+    {
+      "Effect":"Allow",
+      "Principal":{"AWS":["*"]},
+      "Action":["s3:GetObject"],
+      "Resource":["arn:aws:s3:::%s/*"]
+    }
+  ]
+}`, cfg.MinIOBucket)
+
+	if err := minioClient.SetBucketPolicy(ctx, cfg.MinIOBucket, publicReadPolicy); err != nil {
+		log.Fatalf("cannot set public policy on bucket %q: %v", cfg.MinIOBucket, err)
+	}
+	log.Printf("Bucket %q is now publicly readable", cfg.MinIOBucket)
+
+	// Подключаемся к базе
+	db, err := openDB(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	// Миграции: экземпляры, стартующие одновременно, применяют их по очереди
+	migrator, err := migrations.New(db)
+	if err != nil {
+		log.Fatal(err)
+	}
+	ran, err := migrator.Up()
+	for _, m := range ran {
+		log.Printf("Applied migration %04d_%s", m.Version, m.Name)
+	}
+	if err != nil {
+		log.Fatal("migrations failed: ", err)
+	}
+
+	// Уведомления пользователей через бота
+	var notifier notify.Notifier = notify.Log{}
+	if cfg.TelegramBotToken != "" {
+		tg, err := notify.NewTelegram(cfg.TelegramBotToken)
+		if err != nil {
+			log.Fatal(err)
+		}
+		notifier = tg
+	} else {
+		log.Println("TELEGRAM_BOT_TOKEN is not set, notifications go to the log")
+	}
+
+	// Автоматические правила проверки объявлений
+	rulesCfg, err := rules.LoadConfig(cfg.ContentRulesFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	rulesEngine, err := rules.NewEngine(rulesCfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if !dedup.ValidMode(cfg.DuplicatePolicy) {
+		log.Fatalf("unknown DUPLICATE_POLICY %q", cfg.DuplicatePolicy)
+	}
+
+	// Платёжный провайдер
+	var provider payments.Provider
+	switch cfg.PaymentsProvider {
+	case "":
+		log.Println("PAYMENTS_PROVIDER is not set, payments are disabled")
+	case "telegram":
+		tp, err := payments.NewTelegram(cfg.TelegramBotToken, cfg.TelegramPaymentsProviderToken, cfg.PaymentsWebhookSecret)
+		if err != nil {
+			log.Fatal(err)
+		}
+		provider = tp
+	case "fake":
+		log.Println("WARNING: PAYMENTS_PROVIDER=fake, orders are paid without real money")
+		fp, err := payments.NewFake(cfg.PaymentsWebhookSecret)
+		if err != nil {
+			log.Fatal(err)
+		}
+		provider = fp
+	default:
+		log.Fatalf("unknown PAYMENTS_PROVIDER %q", cfg.PaymentsProvider)
+	}
+
+	if cfg.ErasureSecret == "" {
+		log.Fatal("ERASURE_SECRET is required")
+	}
+
+	// Репозитории и хендлеры
+	userRepo := repository.NewUserRepo(db, cfg.ErasureSecret)
+	adRepo := repository.NewAdRepo(db, cfg.SearchOrganicPerPromoted)
+	apiKeyRepo := repository.NewAPIKeyRepo(db)
+	moderationRepo := repository.NewModerationRepo(db, cfg.ModerationClaimTTL)
+	reportRepo := repository.NewReportRepo(db)
+	sanctionRepo := repository.NewSanctionRepo(db)
+	statsRepo := repository.NewStatsRepo(db)
+	outboxRepo := repository.NewOutboxRepo(db)
+	uh := handlers.NewUserHandler(userRepo, sanctionRepo)
+	ah := handlers.NewAdHandler(adRepo, minioClient, cfg.MinIOBucket,
+		handlers.NewAdmins(cfg.AdminTelegramIDs), moderation.NewPolicy(cfg.PremoderationCategories), rulesEngine,
+		dedup.Policy{Mode: cfg.DuplicatePolicy, Window: cfg.DuplicateWindow}, sanctionRepo, statsRepo)
+	kh := handlers.NewAPIKeyHandler(apiKeyRepo)
+	mh := handlers.NewModerationHandler(moderationRepo)
+	rh := handlers.NewReportHandler(reportRepo, sanctionRepo, cfg.ReportsHideThreshold)
+	sh := handlers.NewSanctionHandler(sanctionRepo)
+	ch := handlers.NewConversationHandler(repository.NewConversationRepo(db), sanctionRepo)
+	fh := handlers.NewFavoriteHandler(repository.NewFavoriteRepo(db), sanctionRepo)
+	sth := handlers.NewStatsHandler(statsRepo, handlers.NewAdmins(cfg.AdminTelegramIDs))
+	ph := handlers.NewPromotionHandler(repository.NewPromotionRepo(db), adRepo, handlers.NewAdmins(cfg.AdminTelegramIDs))
+	payh := handlers.NewPaymentHandler(repository.NewOrderRepo(db), adRepo, provider,
+		handlers.NewAdmins(cfg.AdminTelegramIDs), cfg.PaymentsCurrency)
+	rvh := handlers.NewReviewHandler(repository.NewReviewRepo(db), sanctionRepo, rulesEngine)
+	oh := handlers.NewOutboxHandler(outboxRepo)
+	webhookRepo := repository.NewWebhookRepo(db)
+	wh := handlers.NewWebhookHandler(webhookRepo)
+
+	// Поток изменений объявлений: события приходят через LISTEN/NOTIFY
+	eventRepo := repository.NewEventRepo(db)
+	broker := stream.NewBroker(eventRepo)
+	if err := broker.Listen(cfg.DBDSN); err != nil {
+		log.Fatal("listen for events: ", err)
+	}
+	streamh := handlers.NewStreamHandler(broker, eventRepo, cfg.StreamReplayLimit)
+	feedh := handlers.NewFeedHandler(adRepo, cfg.PublicBaseURL)
+
+	// Фиды для площадок-агрегаторов
+	taxonomy, err := catalog.LoadTaxonomy(cfg.CatalogTaxonomyFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	cath := handlers.NewCatalogHandler(adRepo, taxonomy, catalog.Shop{
+		Name:     cfg.CatalogShopName,
+		Company:  cfg.CatalogCompany,
+		Currency: cfg.CatalogCurrency,
+		AdURL:    cfg.CatalogAdURL,
+	}, cfg.PublicBaseURL)
+
+	// Выгрузки персональных данных хранятся в закрытом бакете
+	exportStore := export.NewStore(minioClient, cfg.ExportBucket)
+	if err := exportStore.Ensure(ctx); err != nil {
+		log.Fatalf("cannot create bucket %q: %v", cfg.ExportBucket, err)
+	}
+	exportRepo := repository.NewExportRepo(db)
+	exph := handlers.NewExportHandler(exportRepo, exportStore)
+
+	// Импорт объявлений из файлов; файл ждёт обработки в закрытом бакете
+	if err := imports.EnsureBucket(ctx, minioClient, cfg.ImportBucket); err != nil {
+		log.Fatalf("cannot create bucket %q: %v", cfg.ImportBucket, err)
+	}
+	importRepo := repository.NewImportRepo(db)
+	imh := handlers.NewImportHandler(importRepo, minioClient, cfg.ImportBucket, sanctionRepo, cfg.ImportMaxRows)
+
+	// Аутентификация сервисов по API-ключам
+	authenticator := auth.NewAuthenticator(apiKeyRepo, cfg.BootstrapAPIKey, cfg.AuthDisabled)
+	if cfg.AuthDisabled {
+		log.Println("WARNING: AUTH_DISABLED=true, API keys are not required")
+	}
+
+	// Ограничение частоты запросов
+	limits := [][3]string{
+		{ratelimit.Create, cfg.RateLimitCreate, cfg.RateLimitCreateIP},
+		{ratelimit.Edit, cfg.RateLimitEdit, cfg.RateLimitEditIP},
+		{ratelimit.Search, cfg.RateLimitSearch, cfg.RateLimitSearchIP},
+		{ratelimit.Contact, cfg.RateLimitContact, cfg.RateLimitContactIP},
+	}
+	var policies []ratelimit.Policy
+	for _, l := range limits {
+		user, err := ratelimit.ParseLimit(l[1])
+		if err != nil {
+			log.Fatal(err)
+		}
+		ip, err := ratelimit.ParseLimit(l[2])
+		if err != nil {
+			log.Fatal(err)
+		}
+		policies = append(policies, ratelimit.Policy{Name: l[0], User: user, IP: ip})
+	}
+	limiter := ratelimit.NewLimiter(repository.NewRateLimitRepo(db), policies,
+		cfg.RateLimitTrustedIPs, cfg.RateLimitTrustForwarded)
+	go limiter.PurgeLoop(time.Hour)
+
+	// Доставка уведомлений из outbox
+	worker := notify.NewWorker(outboxRepo, notifier, cfg.OutboxMaxAttempts)
+	go worker.Run(cfg.OutboxPollInterval)
+	go worker.PurgeLoop(time.Hour, cfg.OutboxRetention)
+
+	// Доставка событий подписчикам
+	dispatcher := webhooks.NewDispatcher(webhookRepo, cfg.WebhookMaxAttempts, cfg.WebhookTimeout)
+	go dispatcher.Run(2 * time.Second)
+	go dispatcher.PurgeLoop(time.Hour, cfg.EventsRetention)
+
+	// Сборка выгрузок персональных данных
+	exporter := export.NewWorker(exportRepo, userRepo, exportStore, minioClient, cfg.MinIOBucket, cfg.ExportTTL)
+	go exporter.Run(5 * time.Second)
+	go exporter.PurgeLoop(time.Hour)
+
+	// Создание объявлений из загруженных файлов
+	importer := imports.NewWorker(importRepo, adRepo, rulesEngine, moderation.NewPolicy(cfg.PremoderationCategories),
+		dedup.Policy{Mode: cfg.DuplicatePolicy, Window: cfg.DuplicateWindow}, minioClient,
+		cfg.MinIOBucket, cfg.ImportBucket, cfg.ImportMaxRows)
+	go importer.Run(5 * time.Second)
+
+	// Удаление фото и архивов, на которые больше нет ссылок
+	cleaner := storage.NewCleaner(repository.NewStorageRepo(db), minioClient, storageBuckets(cfg))
+	go cleaner.Run(10 * time.Second)
+
+	// Идемпотентность POST-запросов
+	idem := idempotency.New(repository.NewIdempotencyRepo(db, cfg.IdempotencyTTL))
+	go idem.PurgeLoop(time.Hour)
+
+	// Роутер и Swagger
+	r := router.NewRouter(authenticator, limiter, idem, router.Handlers{
+		Users:         uh,
+		Ads:           ah,
+		APIKeys:       kh,
+		Moderation:    mh,
+		Reports:       rh,
+		Sanctions:     sh,
+		Conversations: ch,
+		Favorites:     fh,
+		Stats:         sth,
+		Reviews:       rvh,
+		Promotions:    ph,
+		Payments:      payh,
+		Outbox:        oh,
+		Webhooks:      wh,
+		Stream:        streamh,
+		Feeds:         feedh,
+		Exports:       exph,
+		Imports:       imh,
+		Catalog:       cath,
+	})
+	r.PathPrefix("/swagger/").Handler(httpSwagger.WrapHandler)
+
+	// Старт сервера
+	log.Println("Server started on port", cfg.ServerPort)
+	log.Fatal(http.ListenAndServe(":"+cfg.ServerPort, r))
+}
+
+// storageBuckets — бакет для каждого вида объектов, удаляемых Cleaner.
+func storageBuckets(cfg *config.Config) map[string]string {
+	return map[string]string{
+		domain.StoragePhoto:  cfg.MinIOBucket,
+		domain.StorageExport: cfg.ExportBucket,
+		domain.StorageImport: cfg.ImportBucket,
+	}
+}
@@ -0,0 +1,96 @@
+// Package storage удаляет из хранилища объекты, на которые больше нет ссылок.
+package storage
+
+import (
+	"context"
+	"log"
+	"poppins/repository"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+)
+
+// Cleaner удаляет объекты, поставленные в очередь storage_deletions.
+// Неудачное удаление повторяется, после MaxAttempts попыток объект
+// остаётся в очереди со статусом failed.
+type Cleaner struct {
+	Repo   *repository.StorageRepo
+	Client *minio.Client
+	// Buckets — бакет для каждого вида объектов (domain.StoragePhoto, ...).
+	Buckets     map[string]string
+	MaxAttempts int
+	Batch       int
+	Lease       time.Duration
+}
+
+func NewCleaner(repo *repository.StorageRepo, client *minio.Client, buckets map[string]string) *Cleaner {
+	return &Cleaner{
+		Repo:        repo,
+		Client:      client,
+		Buckets:     buckets,
+		MaxAttempts: 10,
+		Batch:       100,
+		Lease:       5 * time.Minute,
+	}
+}
+
+// Run проверяет очередь каждые every; полная пачка забирается сразу, без ожидания.
+func (c *Cleaner) Run(every time.Duration) {
+	for {
+		batch, err := c.Repo.Claim(c.Batch, c.Lease)
+		if err != nil {
+			log.Printf("storage cleanup: %v", err)
+		}
+		for _, d := range batch {
+			c.remove(d)
+		}
+		if err != nil || len(batch) < c.Batch {
+			time.Sleep(every)
+		}
+	}
+}
+
+// Drain разбирает очередь, пока в ней есть объекты, готовые к удалению, и
+// возвращает число обработанных. Неудачные удаления откладываются и в этот
+// вызов не повторяются.
+func (c *Cleaner) Drain() (int, error) {
+	n := 0
+	for {
+		batch, err := c.Repo.Claim(c.Batch, c.Lease)
+		if err != nil {
+			return n, err
+		}
+		for _, d := range batch {
+			c.remove(d)
+		}
+		n += len(batch)
+		if len(batch) < c.Batch {
+			return n, nil
+		}
+	}
+}
+
+func (c *Cleaner) remove(d *repository.StorageDeletion) {
+	bucket, ok := c.Buckets[d.Kind]
+	if !ok {
+		log.Printf("storage cleanup: unknown kind %q of %s", d.Kind, d.ObjectName)
+		if err := c.Repo.Fail(d.ID, "unknown kind"); err != nil {
+			log.Printf("storage cleanup: update %d: %v", d.ID, err)
+		}
+		return
+	}
+	// Удаление отсутствующего объекта в S3 не считается ошибкой
+	err := c.Client.RemoveObject(context.Background(), bucket, d.ObjectName, minio.RemoveObjectOptions{})
+	switch {
+	case err == nil:
+		err = c.Repo.Done(d.ID)
+	case d.Attempts >= c.MaxAttempts:
+		log.Printf("storage cleanup: %s/%s failed after %d attempts: %v", bucket, d.ObjectName, d.Attempts, err)
+		err = c.Repo.Fail(d.ID, err.Error())
+	default:
+		err = c.Repo.Retry(d.ID, time.Now().Add(time.Duration(d.Attempts)*time.Minute), err.Error())
+	}
+	if err != nil {
+		log.Printf("storage cleanup: update %d: %v", d.ID, err)
+	}
+}
@@ -0,0 +1,149 @@
+// Package stream раздаёт события об объявлениях подключённым клиентам.
+// Каждый экземпляр сервиса слушает канал Postgres LISTEN/NOTIFY, поэтому
+// клиент получает изменения, сделанные через любой экземпляр.
+package stream
+
+import (
+	"log"
+	"poppins/repository"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// bufferSize — сколько событий может ждать медленный клиент; переполнение
+// отключает его, и он продолжает с Last-Event-ID.
+const bufferSize = 256
+
+// Subscription — подписка клиента на события.
+type Subscription struct {
+	C      chan *repository.AdEvent
+	closed bool
+}
+
+type Broker struct {
+	Repo *repository.EventRepo
+
+	mu   sync.Mutex
+	subs map[*Subscription]struct{}
+	// lastID — наибольший разосланный ID; с него догоняем после переподключения.
+	lastID int64
+}
+
+func NewBroker(repo *repository.EventRepo) *Broker {
+	return &Broker{Repo: repo, subs: map[*Subscription]struct{}{}}
+}
+
+// Subscribe регистрирует клиента. Канал закрывается при Unsubscribe или
+// если клиент не успевает читать.
+func (b *Broker) Subscribe() *Subscription {
+	s := &Subscription{C: make(chan *repository.AdEvent, bufferSize)}
+	b.mu.Lock()
+	b.subs[s] = struct{}{}
+	b.mu.Unlock()
+	return s
+}
+
+func (b *Broker) Unsubscribe(s *Subscription) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.drop(s)
+}
+
+// drop вызывается под b.mu.
+func (b *Broker) drop(s *Subscription) {
+	if s.closed {
+		return
+	}
+	s.closed = true
+	delete(b.subs, s)
+	close(s.C)
+}
+
+func (b *Broker) publish(events []*repository.AdEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, ev := range events {
+		b.lastID = max(b.lastID, ev.Event.ID)
+		for s := range b.subs {
+			select {
+			case s.C <- ev:
+			default:
+				b.drop(s)
+			}
+		}
+	}
+}
+
+// Listen слушает канал событий по dsn и рассылает новые события подписчикам.
+// После обрыва соединения догоняет пропущенное по журналу.
+func (b *Broker) Listen(dsn string) error {
+	l := pq.NewListener(dsn, time.Second, time.Minute, func(ev pq.ListenerEventType, err error) {
+		if err != nil {
+			log.Printf("stream listener: %v", err)
+		}
+	})
+	if err := l.Listen(repository.EventsChannel); err != nil {
+		return err
+	}
+	last, err := b.Repo.LastID()
+	if err != nil {
+		return err
+	}
+	b.mu.Lock()
+	b.lastID = max(b.lastID, last)
+	b.mu.Unlock()
+	go b.loop(l)
+	return nil
+}
+
+func (b *Broker) loop(l *pq.Listener) {
+	ping := time.NewTicker(time.Minute)
+	defer ping.Stop()
+	for {
+		select {
+		case n := <-l.Notify:
+			if n == nil {
+				// Соединение восстановлено: уведомления за время обрыва потеряны
+				b.catchUp()
+				continue
+			}
+			id, err := strconv.ParseInt(n.Extra, 10, 64)
+			if err != nil {
+				log.Printf("stream: bad notification %q", n.Extra)
+				continue
+			}
+			events, err := b.Repo.AdEventsByID([]int64{id})
+			if err != nil {
+				log.Printf("stream: load event %d: %v", id, err)
+				continue
+			}
+			b.publish(events)
+		case <-ping.C:
+			if err := l.Ping(); err != nil {
+				log.Printf("stream listener ping: %v", err)
+			}
+		}
+	}
+}
+
+// catchUp рассылает события, появившиеся после последнего разосланного.
+func (b *Broker) catchUp() {
+	b.mu.Lock()
+	after := b.lastID
+	b.mu.Unlock()
+	for {
+		events, err := b.Repo.AdEventsAfter(after, 500)
+		if err != nil {
+			log.Printf("stream: catch up: %v", err)
+			return
+		}
+		if len(events) == 0 {
+			return
+		}
+		b.publish(events)
+		after = events[len(events)-1].Event.ID
+	}
+}
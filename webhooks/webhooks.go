@@ -0,0 +1,188 @@
+// Package webhooks доставляет доменные события подписчикам по HTTP.
+//
+// Каждое событие отправляется POST-запросом с JSON-телом domain.Event и
+// заголовками:
+//
+//	Webhook-Id         — ID события, одинаковый во всех повторах
+//	Webhook-Event      — тип события
+//	Webhook-Version    — версия схемы data
+//	Webhook-Timestamp  — время отправки, Unix-секунды
+//	Webhook-Signature  — v1=<hex HMAC-SHA256 от "<timestamp>.<тело>" с секретом подписки>
+//
+// Подписчик отвечает 2xx; любой другой ответ или таймаут повторяется с
+// экспоненциальной задержкой.
+package webhooks
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	mrand "math/rand"
+	"net/http"
+	"poppins/domain"
+	"poppins/repository"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// maxResponseBody — сколько байт ответа подписчика сохраняется в журнале.
+const maxResponseBody = 1024
+
+// GenerateSecret создаёт секрет для подписи событий.
+func GenerateSecret() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(buf), nil
+}
+
+// Sign возвращает значение заголовка Webhook-Signature.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "v1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Dispatcher забирает доставки из очереди и отправляет их подписчикам.
+// Несколько экземпляров могут работать параллельно.
+type Dispatcher struct {
+	Repo   *repository.WebhookRepo
+	Client *http.Client
+	// MaxAttempts — после стольких неудач доставка становится failed.
+	MaxAttempts int
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+	Batch       int
+	// Lease — сколько доставка закреплена за обработчиком; больше таймаута запроса.
+	Lease time.Duration
+}
+
+func NewDispatcher(repo *repository.WebhookRepo, maxAttempts int, timeout time.Duration) *Dispatcher {
+	return &Dispatcher{
+		Repo:        repo,
+		Client:      &http.Client{Timeout: timeout},
+		MaxAttempts: maxAttempts,
+		BaseBackoff: 30 * time.Second,
+		MaxBackoff:  12 * time.Hour,
+		Batch:       20,
+		Lease:       timeout + time.Minute,
+	}
+}
+
+// Run опрашивает очередь каждые every; полная пачка забирается сразу, без ожидания.
+func (d *Dispatcher) Run(every time.Duration) {
+	for {
+		n, err := d.DeliverBatch()
+		if err != nil {
+			log.Printf("webhooks: %v", err)
+		}
+		if err != nil || n < d.Batch {
+			time.Sleep(every)
+		}
+	}
+}
+
+// DeliverBatch параллельно отправляет одну пачку доставок и возвращает её размер.
+func (d *Dispatcher) DeliverBatch() (int, error) {
+	jobs, err := d.Repo.ClaimDeliveries(d.Batch, d.Lease)
+	if err != nil {
+		return 0, err
+	}
+	var wg sync.WaitGroup
+	for _, j := range jobs {
+		wg.Add(1)
+		go func(j *repository.DeliveryJob) {
+			defer wg.Done()
+			d.deliver(j)
+		}(j)
+	}
+	wg.Wait()
+	return len(jobs), nil
+}
+
+func (d *Dispatcher) deliver(j *repository.DeliveryJob) {
+	a := d.send(j)
+	status, next := domain.WebhookDelivered, time.Now()
+	switch {
+	case a.Error == "" && a.StatusCode >= 200 && a.StatusCode < 300:
+	case j.Delivery.Attempts >= d.MaxAttempts:
+		status = domain.WebhookFailed
+		log.Printf("webhooks: delivery %d to %s failed after %d attempts", j.Delivery.ID, j.URL, j.Delivery.Attempts)
+	default:
+		status, next = domain.WebhookPending, next.Add(d.backoff(j.Delivery.Attempts))
+	}
+	if err := d.Repo.RecordAttempt(j.Delivery.ID, a, status, next); err != nil {
+		log.Printf("webhooks: record delivery %d: %v", j.Delivery.ID, err)
+	}
+}
+
+// send выполняет одну попытку и описывает её результат.
+func (d *Dispatcher) send(j *repository.DeliveryJob) *domain.WebhookAttempt {
+	a := &domain.WebhookAttempt{StartedAt: time.Now()}
+	defer func() { a.DurationMs = time.Since(a.StartedAt).Milliseconds() }()
+
+	body, err := json.Marshal(j.Event)
+	if err != nil {
+		a.Error = "marshal event: " + err.Error()
+		return a
+	}
+	req, err := http.NewRequest(http.MethodPost, j.URL, bytes.NewReader(body))
+	if err != nil {
+		a.Error = err.Error()
+		return a
+	}
+	ts := a.StartedAt.Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "poppins-webhooks/1")
+	req.Header.Set("Webhook-Id", strconv.FormatInt(j.Event.ID, 10))
+	req.Header.Set("Webhook-Event", j.Event.Type)
+	req.Header.Set("Webhook-Version", strconv.Itoa(j.Event.Version))
+	req.Header.Set("Webhook-Timestamp", strconv.FormatInt(ts, 10))
+	req.Header.Set("Webhook-Signature", Sign(j.Secret, ts, body))
+
+	resp, err := d.Client.Do(req)
+	if err != nil {
+		a.Error = err.Error()
+		return a
+	}
+	defer resp.Body.Close()
+	a.StatusCode = resp.StatusCode
+	snippet, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponseBody))
+	a.ResponseBody = string(bytes.ToValidUTF8(snippet, nil))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		a.Error = fmt.Sprintf("unexpected status %d", resp.StatusCode)
+	}
+	return a
+}
+
+// backoff — задержка после attempt неудачных попыток, со случайным разбросом
+// ±20%, чтобы повторы к одному подписчику не приходили пачкой.
+func (d *Dispatcher) backoff(attempt int) time.Duration {
+	b := d.BaseBackoff
+	for i := 1; i < attempt && b < d.MaxBackoff; i++ {
+		b *= 2
+	}
+	b = min(b, d.MaxBackoff)
+	return b + time.Duration((mrand.Float64()*0.4-0.2)*float64(b))
+}
+
+// PurgeLoop периодически удаляет события старше retention вместе с журналом доставок.
+func (d *Dispatcher) PurgeLoop(every, retention time.Duration) {
+	for range time.Tick(every) {
+		if n, err := d.Repo.PurgeEvents(time.Now().Add(-retention)); err != nil {
+			log.Printf("purge events: %v", err)
+		} else if n > 0 {
+			log.Printf("purged %d events", n)
+		}
+	}
+}